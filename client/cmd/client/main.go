@@ -78,35 +78,36 @@ func main() {
 	}
 
 	log.Info("Starting streaming transcription client")
-	log.Info("Config: server_url=%s, api_bind_address=%s, debug=%v",
-		cfg.Server.URL, cfg.Client.APIBindAddress, cfg.Client.Debug)
+	log.Info("Config: server_url=%s, transport=%s, api_bind_address=%s, debug=%v",
+		cfg.Server.URL, cfg.Server.Transport, cfg.Client.APIBindAddress, cfg.Client.Debug)
 
-	// Create WebRTC client
-	webrtcClient := webrtc.New(cfg.Server.URL+"/api/v1/stream/signal", cfg, log, handleDataChannelMessage)
+	// Create the streaming client (WebRTC or plain WebSocket, per server.transport)
+	streamClient := webrtc.NewTransport(cfg, log, handleDataChannelMessage)
+	globalStreamClient = streamClient // Set global for re-delivery requests
 
 	// Connect to server
 	log.Info("Connecting to server...")
-	if err := webrtcClient.Connect(); err != nil {
+	if err := streamClient.Connect(); err != nil {
 		log.Fatal("Failed to connect to server: %v", err)
 	}
 
 	// Wait for connection to establish
-	log.Info("Waiting for DataChannel to open...")
+	log.Info("Waiting for connection to open...")
 	for i := 0; i < 100; i++ {
-		if webrtcClient.IsConnected() {
+		if streamClient.IsConnected() {
 			break
 		}
 		time.Sleep(100 * time.Millisecond)
 	}
 
-	if !webrtcClient.IsConnected() {
-		log.Fatal("Failed to establish DataChannel connection within timeout")
+	if !streamClient.IsConnected() {
+		log.Fatal("Failed to establish connection within timeout")
 	}
 
-	log.Info("DataChannel connected! Sending test ping...")
+	log.Info("Connected! Sending test ping...")
 
 	// Send a test ping
-	if err := webrtcClient.SendPing(); err != nil {
+	if err := streamClient.SendPing(); err != nil {
 		log.Error("Failed to send ping: %v", err)
 	} else {
 		log.Info("Ping sent successfully")
@@ -125,9 +126,9 @@ func main() {
 	go func() {
 		defer audioWg.Done()
 		for chunk := range capturer.Chunks() {
-			// Send raw PCM data via WebRTC
+			// Send raw PCM data to the server
 			// SendAudioChunk will handle the JSON marshaling
-			if err := webrtcClient.SendAudioChunk(chunk.Data, chunk.SampleRate, chunk.Channels); err != nil {
+			if err := streamClient.SendAudioChunk(chunk.Data, chunk.SampleRate, chunk.Channels); err != nil {
 				log.Error("Failed to send audio chunk: %v", err)
 			} else {
 				log.Debug("Sent audio chunk: seq=%d, size=%d bytes", chunk.SequenceID, len(chunk.Data))
@@ -148,10 +149,14 @@ func main() {
 			sessionChunks = []string{}
 			sessionStart = time.Now()
 			sessionRecording = true
+			sessionHasSeq = false
+			previousSession = sessionID
+			sessionID = uint64(time.Now().UnixNano())
+			currentSession := sessionID
 			sessionMu.Unlock()
 
 			// Send control start message to server
-			if err := webrtcClient.SendControlStart(); err != nil {
+			if err := streamClient.SendControlStart(currentSession); err != nil {
 				log.Error("Failed to send control start: %v", err)
 				return err
 			}
@@ -192,7 +197,7 @@ func main() {
 			}
 
 			// Send control stop message to server
-			if err := webrtcClient.SendControlStop(); err != nil {
+			if err := streamClient.SendControlStop(); err != nil {
 				log.Error("Failed to send control stop: %v", err)
 				return err
 			}
@@ -232,8 +237,8 @@ func main() {
 	log.Info("Waiting for audio goroutine to finish...")
 	audioWg.Wait()
 
-	if err := webrtcClient.Close(); err != nil {
-		log.Error("Error closing WebRTC client: %v", err)
+	if err := streamClient.Close(); err != nil {
+		log.Error("Error closing streaming client: %v", err)
 	}
 
 	log.Info("Client stopped")
@@ -248,14 +253,39 @@ var globalDebugLog *debuglog.Logger
 // Global API server for broadcasting transcriptions (set in main)
 var globalAPIServer *api.Server
 
+// Global streaming client for requesting re-delivery of missed transcripts (set in main)
+var globalStreamClient webrtc.Transport
+
 // Session state for tracking complete transcriptions
 var (
 	sessionMu        sync.Mutex
 	sessionChunks    []string
 	sessionStart     time.Time
 	sessionRecording bool
+	sessionID        uint64 // Sent in control.start; transcripts of older sessions are ignored
+	sessionLastSeq   uint64 // Sequence ID of the last final transcript
+	sessionHasSeq    bool   // Whether sessionLastSeq is set
+
+	// The server finishes transcribing the chunks a session had in flight when the
+	// next one started, so its last finals can still arrive
+	previousSession uint64
 )
 
+// fromCurrentSession returns false for transcripts of an earlier session
+// (sequence IDs restart with every session, so they must not be mixed)
+func fromCurrentSession(transcript protocol.TranscriptData) bool {
+	sessionMu.Lock()
+	defer sessionMu.Unlock()
+	return transcript.SessionID == 0 || transcript.SessionID == sessionID
+}
+
+// fromPreviousSession returns true for trailing finals of the session before the current one
+func fromPreviousSession(transcript protocol.TranscriptData) bool {
+	sessionMu.Lock()
+	defer sessionMu.Unlock()
+	return previousSession != 0 && transcript.SessionID == previousSession
+}
+
 // handleDataChannelMessage handles messages received from the server
 func handleDataChannelMessage(msg *protocol.Message) {
 	messageLog := globalLog.With("message")
@@ -271,11 +301,14 @@ func handleDataChannelMessage(msg *protocol.Message) {
 			messageLog.Error("Failed to unmarshal partial transcript: %v", err)
 			return
 		}
+		if !fromCurrentSession(transcript) {
+			return
+		}
 		fmt.Printf("📝 [partial] %s\n", transcript.Text)
 
 		// Broadcast to WebSocket clients
 		if globalAPIServer != nil {
-			globalAPIServer.BroadcastTranscription(transcript.Text, false)
+			globalAPIServer.BroadcastTranscription(transcript)
 		}
 
 	case protocol.MessageTypeTranscriptFinal:
@@ -284,11 +317,34 @@ func handleDataChannelMessage(msg *protocol.Message) {
 			messageLog.Error("Failed to unmarshal final transcript: %v", err)
 			return
 		}
-		fmt.Printf("✅ %s\n", transcript.Text)
+		current := fromCurrentSession(transcript)
+		if !current && !fromPreviousSession(transcript) {
+			messageLog.Debug("Ignoring transcript of earlier session %d: seq=%d", transcript.SessionID, transcript.SequenceID)
+			return
+		}
+
+		// Every chunk gets a final, so a jump in sequence IDs means finals were missed
+		// (the previous session's sequence is no longer tracked)
+		if current && !transcript.Redelivered {
+			requestMissedTranscripts(messageLog, transcript.SequenceID)
+		}
+
+		// Empty finals only mark a chunk without speech
+		if transcript.Text == "" {
+			return
+		}
+
+		if transcript.Redelivered {
+			fmt.Printf("🔁 [redelivered #%d] %s\n", transcript.SequenceID, transcript.Text)
+		} else if transcript.LowConfidence {
+			fmt.Printf("⚠️  [low confidence %.2f] %s\n", transcript.Confidence, transcript.Text)
+		} else {
+			fmt.Printf("✅ %s\n", transcript.Text)
+		}
 
 		// Broadcast to WebSocket clients
 		if globalAPIServer != nil {
-			globalAPIServer.BroadcastTranscription(transcript.Text, true)
+			globalAPIServer.BroadcastTranscription(transcript)
 		}
 
 		// Log chunk to debug log
@@ -296,14 +352,93 @@ func handleDataChannelMessage(msg *protocol.Message) {
 			messageLog.Error("Failed to log chunk to debug log: %v", err)
 		}
 
-		// Track session chunks
+		// Track session chunks (the previous session's were logged when it stopped)
 		sessionMu.Lock()
-		if sessionRecording {
+		if sessionRecording && current {
 			sessionChunks = append(sessionChunks, transcript.Text)
 		}
 		sessionMu.Unlock()
 
+	case protocol.MessageTypeError:
+		var errorData protocol.ErrorData
+		if err := json.Unmarshal(msg.Data, &errorData); err != nil {
+			messageLog.Error("Failed to unmarshal error: %v", err)
+			return
+		}
+		messageLog.Warn("Server error %s: %s (sequence IDs: %v)", errorData.Code, errorData.Message, errorData.SequenceIDs)
+		fmt.Printf("⚠️  Server error [%s]: %s\n", errorData.Code, errorData.Message)
+
+		// Surface through /status and the /transcriptions WebSocket
+		if globalAPIServer != nil {
+			globalAPIServer.ReportError(errorData)
+		}
+
+	case protocol.MessageTypeControlStarted:
+		var started protocol.ControlStartedData
+		if err := json.Unmarshal(msg.Data, &started); err != nil {
+			messageLog.Error("Failed to unmarshal session settings: %v", err)
+			return
+		}
+		messageLog.Info("Session %d settings: VAD=%.0f, Silence=%dms, Min=%dms, Max=%dms, Density=%.1f%%, language=%q, threads=%d, temperature=%.2f",
+			started.SessionID,
+			started.VADEnergyThreshold,
+			started.SilenceThresholdMs,
+			started.MinChunkDurationMs,
+			started.MaxChunkDurationMs,
+			started.SpeechDensityThreshold*100,
+			started.Language,
+			started.Threads,
+			started.Temperature)
+		if len(started.Adjusted) > 0 {
+			messageLog.Warn("Server clamped %v to its limits", started.Adjusted)
+		}
+
+	case protocol.MessageTypeAudioStats:
+		var stats protocol.AudioStatsData
+		if err := json.Unmarshal(msg.Data, &stats); err != nil {
+			messageLog.Error("Failed to unmarshal audio stats: %v", err)
+			return
+		}
+		if stats.Missing > 0 || stats.Duplicates > 0 || stats.Late > 0 {
+			messageLog.Warn("Server audio stats: %d chunks received, %d missing in %d gaps, %d duplicates, %d late",
+				stats.Received, stats.Missing, stats.Gaps, stats.Duplicates, stats.Late)
+		} else {
+			messageLog.Debug("Server audio stats: %d chunks received, none lost", stats.Received)
+		}
+
 	default:
 		messageLog.Debug("Received message type: %s", string(msg.Type))
 	}
 }
+
+// requestMissedTranscripts checks a final's sequence ID against the last one seen and
+// asks the server to re-deliver any finals in between
+func requestMissedTranscripts(messageLog *logger.ContextLogger, seq uint64) {
+	sessionMu.Lock()
+	currentSession := sessionID
+	expected := uint64(0)
+	if sessionHasSeq {
+		expected = sessionLastSeq + 1
+	}
+	if sessionHasSeq && seq < expected {
+		messageLog.Warn("Transcript out of order: seq=%d after seq=%d", seq, sessionLastSeq)
+	}
+	if seq >= expected {
+		sessionLastSeq = seq
+		sessionHasSeq = true
+	}
+	sessionMu.Unlock()
+
+	if seq <= expected || globalStreamClient == nil {
+		return
+	}
+
+	var missing []uint64
+	for id := expected; id < seq; id++ {
+		missing = append(missing, id)
+	}
+	messageLog.Warn("Missed transcripts %d-%d, requesting re-delivery", expected, seq-1)
+	if err := globalStreamClient.SendTranscriptResend(currentSession, missing); err != nil {
+		messageLog.Error("Failed to request re-delivery: %v", err)
+	}
+}
@@ -5,7 +5,9 @@ import (
 	"encoding/json"
 	"fmt"
 	"io"
+	"net"
 	"net/http"
+	"os"
 	"sync"
 	"time"
 
@@ -13,6 +15,7 @@ import (
 	"github.com/lucianHymer/streaming-transcription/client/internal/audio"
 	"github.com/lucianHymer/streaming-transcription/client/internal/config"
 	"github.com/lucianHymer/streaming-transcription/shared/logger"
+	"github.com/lucianHymer/streaming-transcription/shared/protocol"
 )
 
 // Server handles the HTTP control API
@@ -31,6 +34,12 @@ type Server struct {
 	wsClientsMu sync.RWMutex
 	wsUpgrader  websocket.Upgrader
 
+	// Last error reported by the server
+	lastError   *protocol.ErrorData
+	lastErrorAt time.Time
+	errorCount  int
+	errorMu     sync.RWMutex
+
 	// Calibration support
 	cfg        *config.Config
 	configPath string
@@ -93,8 +102,47 @@ func (s *Server) Start() error {
 		IdleTimeout:  60 * time.Second,
 	}
 
-	s.logger.Info("Starting control API on %s", s.bindAddr)
-	return s.server.ListenAndServe()
+	listener, err := s.listen()
+	if err != nil {
+		return err
+	}
+	return s.server.Serve(listener)
+}
+
+// listen opens the control API listener: the Unix domain socket if one is
+// configured, otherwise TCP on the bind address
+func (s *Server) listen() (net.Listener, error) {
+	path := s.cfg.Client.APISocket
+	if path == "" {
+		s.logger.Info("Starting control API on %s", s.bindAddr)
+		return net.Listen("tcp", s.bindAddr)
+	}
+
+	perm, err := s.cfg.APISocketPerm()
+	if err != nil {
+		return nil, err
+	}
+
+	// A socket left behind by a client that did not shut down cleanly blocks the listen;
+	// one that still answers belongs to a running client
+	if info, err := os.Lstat(path); err == nil && info.Mode()&os.ModeSocket != 0 {
+		if conn, err := net.Dial("unix", path); err == nil {
+			conn.Close()
+			return nil, fmt.Errorf("control socket %s is in use by another client", path)
+		}
+		os.Remove(path)
+	}
+	listener, err := listenUnix(path, perm)
+	if err != nil {
+		return nil, fmt.Errorf("failed to listen on control socket: %w", err)
+	}
+	// Also applies the bits a umask cannot grant
+	if err := os.Chmod(path, perm); err != nil {
+		listener.Close()
+		return nil, fmt.Errorf("failed to set control socket permissions: %w", err)
+	}
+	s.logger.Info("Starting control API on unix socket %s (mode %04o)", path, perm)
+	return listener, nil
 }
 
 // Stop gracefully stops the server
@@ -140,6 +188,11 @@ func (s *Server) handleStart(w http.ResponseWriter, r *http.Request) {
 	s.isRunning = true
 	s.isRunningMu.Unlock()
 
+	// Errors from a previous session no longer apply
+	s.errorMu.Lock()
+	s.lastError = nil
+	s.errorMu.Unlock()
+
 	if s.onStart != nil {
 		if err := s.onStart(); err != nil {
 			s.isRunningMu.Lock()
@@ -207,6 +260,18 @@ func (s *Server) handleStatus(w http.ResponseWriter, r *http.Request) {
 		"timestamp": time.Now().Unix(),
 	}
 
+	s.errorMu.RLock()
+	response["error_count"] = s.errorCount
+	if s.lastError != nil {
+		response["last_error"] = map[string]interface{}{
+			"code":         s.lastError.Code,
+			"message":      s.lastError.Message,
+			"sequence_ids": s.lastError.SequenceIDs,
+			"timestamp":    s.lastErrorAt.Unix(),
+		}
+	}
+	s.errorMu.RUnlock()
+
 	w.Header().Set("Content-Type", "application/json")
 	json.NewEncoder(w).Encode(response)
 }
@@ -243,10 +308,18 @@ func (s *Server) handleTranscriptions(w http.ResponseWriter, r *http.Request) {
 }
 
 // BroadcastTranscription sends a transcription chunk to all connected WebSocket clients
-func (s *Server) BroadcastTranscription(text string, isFinal bool) {
+func (s *Server) BroadcastTranscription(transcript protocol.TranscriptData) {
+	text, isFinal := transcript.Text, transcript.IsFinal
+
 	message := map[string]interface{}{
-		"chunk": text,
-		"final": isFinal,
+		"type":           "transcript",
+		"chunk":          text,
+		"final":          isFinal,
+		"confidence":     transcript.Confidence,
+		"low_confidence": transcript.LowConfidence,
+		"sequence_id":    transcript.SequenceID,
+		"session_id":     transcript.SessionID,
+		"redelivered":    transcript.Redelivered,
 	}
 
 	data, err := json.Marshal(message)
@@ -255,11 +328,42 @@ func (s *Server) BroadcastTranscription(text string, isFinal bool) {
 		return
 	}
 
+	s.logger.Debug("Broadcasting transcription: %s (final=%v)", text, isFinal)
+	s.broadcast(data)
+}
+
+// ReportError records an error from the server for /status and sends it to all WebSocket clients
+func (s *Server) ReportError(errorData protocol.ErrorData) {
+	s.errorMu.Lock()
+	s.lastError = &errorData
+	s.lastErrorAt = time.Now()
+	s.errorCount++
+	s.errorMu.Unlock()
+
+	message := map[string]interface{}{
+		"type":         "error",
+		"code":         errorData.Code,
+		"message":      errorData.Message,
+		"sequence_ids": errorData.SequenceIDs,
+	}
+
+	data, err := json.Marshal(message)
+	if err != nil {
+		s.logger.Error("Failed to marshal error: %v", err)
+		return
+	}
+
+	s.logger.Debug("Broadcasting error: %s", errorData.Code)
+	s.broadcast(data)
+}
+
+// broadcast sends a message to all connected WebSocket clients
+func (s *Server) broadcast(data []byte) {
 	s.wsClientsMu.RLock()
 	clientCount := len(s.wsClients)
 	s.wsClientsMu.RUnlock()
 
-	s.logger.Debug("Broadcasting to %d WebSocket clients: %s (final=%v)", clientCount, text, isFinal)
+	s.logger.Debug("Broadcasting to %d WebSocket clients", clientCount)
 
 	s.wsClientsMu.RLock()
 	defer s.wsClientsMu.RUnlock()
@@ -268,7 +372,7 @@ func (s *Server) BroadcastTranscription(text string, isFinal bool) {
 		if err := conn.WriteMessage(websocket.TextMessage, data); err != nil {
 			s.logger.Error("Failed to send to WebSocket client: %v", err)
 		} else {
-			s.logger.Debug("Sent chunk to WebSocket client successfully")
+			s.logger.Debug("Sent message to WebSocket client successfully")
 		}
 	}
 }
@@ -506,7 +610,20 @@ func (s *Server) analyzeAudio(audioData []byte) (*AudioStatistics, error) {
 		return nil, fmt.Errorf("failed to marshal request: %w", err)
 	}
 
-	resp, err := http.Post(url, "application/json", bytes.NewBuffer(jsonData))
+	req, err := http.NewRequest(http.MethodPost, url, bytes.NewBuffer(jsonData))
+	if err != nil {
+		return nil, fmt.Errorf("failed to create request: %w", err)
+	}
+	req.Header.Set("Content-Type", "application/json")
+	if s.cfg.Server.AuthToken != "" {
+		req.Header.Set("Authorization", "Bearer "+s.cfg.Server.AuthToken)
+	}
+
+	client, err := s.cfg.ServerHTTPClient()
+	if err != nil {
+		return nil, err
+	}
+	resp, err := client.Do(req)
 	if err != nil {
 		return nil, fmt.Errorf("failed to send request: %w", err)
 	}
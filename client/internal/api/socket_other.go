@@ -0,0 +1,14 @@
+//go:build !unix
+
+package api
+
+import (
+	"net"
+	"os"
+)
+
+// listenUnix creates the control socket; without a umask, its permissions are
+// only set by the chmod that follows
+func listenUnix(path string, perm os.FileMode) (net.Listener, error) {
+	return net.Listen("unix", path)
+}
@@ -0,0 +1,18 @@
+//go:build unix
+
+package api
+
+import (
+	"net"
+	"os"
+	"syscall"
+)
+
+// listenUnix creates the control socket with perm from the start, narrowing the
+// umask around the listen so the socket is never reachable with wider permissions
+// The umask is process-wide, so files created meanwhile get at most perm as well
+func listenUnix(path string, perm os.FileMode) (net.Listener, error) {
+	previous := syscall.Umask(int(^perm & os.ModePerm))
+	defer syscall.Umask(previous)
+	return net.Listen("unix", path)
+}
@@ -224,7 +224,20 @@ func (w *Wizard) analyzeAudio(audioData []byte) (*AudioStatistics, error) {
 		return nil, fmt.Errorf("failed to marshal request: %w", err)
 	}
 
-	resp, err := http.Post(url, "application/json", bytes.NewBuffer(jsonData))
+	req, err := http.NewRequest(http.MethodPost, url, bytes.NewBuffer(jsonData))
+	if err != nil {
+		return nil, fmt.Errorf("failed to create request: %w", err)
+	}
+	req.Header.Set("Content-Type", "application/json")
+	if w.cfg.Server.AuthToken != "" {
+		req.Header.Set("Authorization", "Bearer "+w.cfg.Server.AuthToken)
+	}
+
+	client, err := w.cfg.ServerHTTPClient()
+	if err != nil {
+		return nil, err
+	}
+	resp, err := client.Do(req)
 	if err != nil {
 		return nil, fmt.Errorf("failed to send request: %w", err)
 	}
@@ -3,6 +3,7 @@ package config
 import (
 	"fmt"
 	"os"
+	"strconv"
 
 	"gopkg.in/yaml.v3"
 )
@@ -14,17 +15,28 @@ type Config struct {
 		Debug           bool   `yaml:"debug"`
 		DebugLogPath    string `yaml:"debug_log_path"`
 		DebugLogMaxSize int    `yaml:"debug_log_max_size"`
+
+		// Serve the control API on a Unix domain socket instead of api_bind_address
+		APISocket     string `yaml:"api_socket"`      // Socket path (empty = TCP on api_bind_address)
+		APISocketMode string `yaml:"api_socket_mode"` // Socket file permissions, octal (default: 0600)
 	} `yaml:"client"`
 
 	Server struct {
-		URL string `yaml:"url"`
+		URL       string `yaml:"url"`
+		Transport string `yaml:"transport"`  // webrtc, websocket (default: webrtc)
+		AuthToken string `yaml:"auth_token"` // API key or signed token the server's auth section accepts (empty = none)
+		CAFile    string `yaml:"ca_file"`    // Trust only this CA (PEM) for a wss:// server (empty = system roots)
 	} `yaml:"server"`
 
 	Audio struct {
-		DeviceName string `yaml:"device_name"` // Empty = default device
+		DeviceName  string `yaml:"device_name"`  // Empty = default device
+		Encoding    string `yaml:"encoding"`     // pcm16, opus (default: pcm16)
+		OpusBitrate int    `yaml:"opus_bitrate"` // Opus bitrate in bits per second (default: 24000)
+		Transport   string `yaml:"transport"`    // datachannel, track (default: datachannel)
 	} `yaml:"audio"`
 
 	Transcription struct {
+		// VAD settings (zero values use the server defaults)
 		VAD struct {
 			EnergyThreshold        float64 `yaml:"energy_threshold"`
 			SilenceThresholdMs     int     `yaml:"silence_threshold_ms"`
@@ -32,6 +44,16 @@ type Config struct {
 			MaxChunkDurationMs     int     `yaml:"max_chunk_duration_ms"`
 			SpeechDensityThreshold float64 `yaml:"speech_density_threshold"`
 		} `yaml:"vad"`
+
+		// Whisper settings (empty/zero values use the server defaults)
+		Whisper struct {
+			Language      string   `yaml:"language"`       // Language code, "auto", or empty for server default
+			Translate     bool     `yaml:"translate"`      // Translate to English
+			InitialPrompt string   `yaml:"initial_prompt"` // Prompt to bias vocabulary/style
+			Vocabulary    []string `yaml:"vocabulary"`     // Domain terms appended to the prompt
+			Threads       int      `yaml:"threads"`
+			Temperature   float64  `yaml:"temperature"`
+		} `yaml:"whisper"`
 	} `yaml:"transcription"`
 
 	// Internal field to track config file path for reloading
@@ -66,22 +88,37 @@ func Load(path string) (*Config, error) {
 	if cfg.Server.URL == "" {
 		cfg.Server.URL = "ws://localhost:8080"
 	}
+	if cfg.Server.Transport == "" {
+		cfg.Server.Transport = "webrtc"
+	}
+	if cfg.Server.Transport != "webrtc" && cfg.Server.Transport != "websocket" {
+		return nil, fmt.Errorf("invalid server transport %q (expected webrtc or websocket)", cfg.Server.Transport)
+	}
+	if err := cfg.validateTLS(); err != nil {
+		return nil, err
+	}
+	if cfg.Client.APISocketMode == "" {
+		cfg.Client.APISocketMode = "0600"
+	}
+	if _, err := cfg.APISocketPerm(); err != nil {
+		return nil, err
+	}
 
-	// Transcription defaults
-	if cfg.Transcription.VAD.EnergyThreshold == 0 {
-		cfg.Transcription.VAD.EnergyThreshold = 500.0 // Default threshold
+	// Audio defaults
+	if cfg.Audio.Encoding == "" {
+		cfg.Audio.Encoding = "pcm16"
 	}
-	if cfg.Transcription.VAD.SilenceThresholdMs == 0 {
-		cfg.Transcription.VAD.SilenceThresholdMs = 1000 // 1 second
+	if cfg.Audio.Encoding != "pcm16" && cfg.Audio.Encoding != "opus" {
+		return nil, fmt.Errorf("invalid audio encoding %q (expected pcm16 or opus)", cfg.Audio.Encoding)
 	}
-	if cfg.Transcription.VAD.MinChunkDurationMs == 0 {
-		cfg.Transcription.VAD.MinChunkDurationMs = 500 // 500ms
+	if cfg.Audio.OpusBitrate == 0 {
+		cfg.Audio.OpusBitrate = 24000
 	}
-	if cfg.Transcription.VAD.MaxChunkDurationMs == 0 {
-		cfg.Transcription.VAD.MaxChunkDurationMs = 30000 // 30 seconds
+	if cfg.Audio.Transport == "" {
+		cfg.Audio.Transport = "datachannel"
 	}
-	if cfg.Transcription.VAD.SpeechDensityThreshold == 0 {
-		cfg.Transcription.VAD.SpeechDensityThreshold = 0.6 // 60% speech density for short utterances
+	if cfg.Audio.Transport != "datachannel" && cfg.Audio.Transport != "track" {
+		return nil, fmt.Errorf("invalid audio transport %q (expected datachannel or track)", cfg.Audio.Transport)
 	}
 
 	return &cfg, nil
@@ -112,6 +149,15 @@ func (c *Config) Reload() error {
 	return nil
 }
 
+// APISocketPerm returns the file permissions of the control API socket
+func (c *Config) APISocketPerm() (os.FileMode, error) {
+	mode, err := strconv.ParseUint(c.Client.APISocketMode, 8, 32)
+	if err != nil || mode > 0777 {
+		return 0, fmt.Errorf("invalid client.api_socket_mode %q (expected octal permissions like 0600)", c.Client.APISocketMode)
+	}
+	return os.FileMode(mode), nil
+}
+
 // Default returns a default configuration
 func Default() *Config {
 	cfg := &Config{}
@@ -119,11 +165,10 @@ func Default() *Config {
 	cfg.Client.Debug = true
 	cfg.Client.DebugLogPath = "~/.config/richardtate/debug.log"
 	cfg.Client.DebugLogMaxSize = 8388608
+	cfg.Client.APISocketMode = "0600"
 	cfg.Server.URL = "ws://localhost:8080"
-	cfg.Transcription.VAD.EnergyThreshold = 500.0
-	cfg.Transcription.VAD.SilenceThresholdMs = 1000
-	cfg.Transcription.VAD.MinChunkDurationMs = 500
-	cfg.Transcription.VAD.MaxChunkDurationMs = 30000
-	cfg.Transcription.VAD.SpeechDensityThreshold = 0.6
+	cfg.Audio.Encoding = "pcm16"
+	cfg.Audio.OpusBitrate = 24000
+	cfg.Audio.Transport = "datachannel"
 	return cfg
 }
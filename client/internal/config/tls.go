@@ -0,0 +1,61 @@
+package config
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"fmt"
+	"net/http"
+	"os"
+	"strings"
+	"time"
+)
+
+// ServerTLSConfig returns the TLS settings for connecting to the server
+// With server.ca_file set, only certificates issued by that CA are trusted;
+// otherwise it returns nil and the system roots apply
+func (c *Config) ServerTLSConfig() (*tls.Config, error) {
+	if c.Server.CAFile == "" {
+		return nil, nil
+	}
+
+	pem, err := os.ReadFile(c.Server.CAFile)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read server CA file: %w", err)
+	}
+	pool := x509.NewCertPool()
+	if !pool.AppendCertsFromPEM(pem) {
+		return nil, fmt.Errorf("no PEM certificates in server CA file %s", c.Server.CAFile)
+	}
+	return &tls.Config{
+		RootCAs:    pool,
+		MinVersion: tls.VersionTLS12,
+	}, nil
+}
+
+// ServerHTTPClient returns an HTTP client for the server's REST endpoints,
+// trusting the same CA as the streaming connection
+func (c *Config) ServerHTTPClient() (*http.Client, error) {
+	tlsConfig, err := c.ServerTLSConfig()
+	if err != nil {
+		return nil, err
+	}
+	if tlsConfig == nil {
+		return http.DefaultClient, nil
+	}
+
+	transport := http.DefaultTransport.(*http.Transport).Clone()
+	transport.TLSClientConfig = tlsConfig
+	return &http.Client{Transport: transport, Timeout: time.Minute}, nil
+}
+
+// validateTLS checks the server CA file and that it is used with a wss:// URL
+func (c *Config) validateTLS() error {
+	if c.Server.CAFile == "" {
+		return nil
+	}
+	if !strings.HasPrefix(c.Server.URL, "wss://") {
+		return fmt.Errorf("server.ca_file is set but server.url %q is not a wss:// URL", c.Server.URL)
+	}
+	_, err := c.ServerTLSConfig()
+	return err
+}
@@ -0,0 +1,74 @@
+package webrtc
+
+import (
+	"sync"
+	"time"
+
+	"github.com/lucianHymer/streaming-transcription/shared/logger"
+)
+
+type bufferedChunk struct {
+	data       []byte
+	sampleRate int
+	channels   int
+	encoding   string
+	sequenceID uint64
+	timestamp  int64
+}
+
+// chunkBuffer holds audio chunks captured while reconnecting
+// When full, the oldest chunk is dropped
+type chunkBuffer struct {
+	mu      sync.Mutex
+	chunks  []bufferedChunk
+	maxSize int
+	dropped uint64
+	logger  *logger.ContextLogger
+}
+
+// newChunkBuffer creates a buffer holding up to maxSize chunks
+func newChunkBuffer(maxSize int, log *logger.ContextLogger) *chunkBuffer {
+	return &chunkBuffer{
+		maxSize: maxSize,
+		logger:  log,
+	}
+}
+
+// Add buffers a chunk, copying data since it might be reused by the caller
+func (b *chunkBuffer) Add(data []byte, sampleRate, channels int, encoding string, sequenceID uint64) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	// Check if buffer is full
+	if len(b.chunks) >= b.maxSize {
+		// Drop oldest chunk
+		b.chunks = b.chunks[1:]
+		b.dropped++
+		b.logger.Warn("Chunk buffer full, dropped chunk (total dropped: %d)", b.dropped)
+	}
+
+	dataCopy := make([]byte, len(data))
+	copy(dataCopy, data)
+
+	b.chunks = append(b.chunks, bufferedChunk{
+		data:       dataCopy,
+		sampleRate: sampleRate,
+		channels:   channels,
+		encoding:   encoding,
+		sequenceID: sequenceID,
+		timestamp:  time.Now().UnixMilli(),
+	})
+	b.logger.Debug("Buffered chunk seq=%d (buffer size: %d/%d)", sequenceID, len(b.chunks), b.maxSize)
+}
+
+// Drain empties the buffer, returning its chunks and how many were dropped
+func (b *chunkBuffer) Drain() ([]bufferedChunk, uint64) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	chunks := b.chunks
+	dropped := b.dropped
+	b.chunks = nil
+	b.dropped = 0
+	return chunks, dropped
+}
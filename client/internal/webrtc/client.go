@@ -3,89 +3,56 @@ package webrtc
 import (
 	"encoding/json"
 	"fmt"
-	"sync"
-	"time"
+	"sync/atomic"
 
 	"github.com/gorilla/websocket"
 	"github.com/lucianHymer/streaming-transcription/client/internal/config"
+	"github.com/lucianHymer/streaming-transcription/shared/audiocodec"
 	"github.com/lucianHymer/streaming-transcription/shared/logger"
 	"github.com/lucianHymer/streaming-transcription/shared/protocol"
 	"github.com/pion/webrtc/v4"
+	"github.com/pion/webrtc/v4/pkg/media"
 )
 
 // Client handles WebRTC connection to the server
 type Client struct {
-	serverURL    string
-	config       *config.Config
-	logger       *logger.ContextLogger
-	pc           *webrtc.PeerConnection
-	dataChannel  *webrtc.DataChannel
-	wsConn       *websocket.Conn
-	onMessage    func(msg *protocol.Message)
-	connected    bool
-	connectedMu  sync.RWMutex
-	sequenceID   uint64
-	sequenceIDMu sync.Mutex
-
-	// Reconnection state
-	reconnecting         bool
-	reconnectingMu       sync.RWMutex
-	reconnectAttempts    int
-	maxReconnectAttempts int
-	reconnectBaseDelay   time.Duration
-	stopReconnect        chan struct{}
-
-	// Audio chunk buffering during reconnection
-	chunkBuffer   []bufferedChunk
-	chunkBufferMu sync.Mutex
-	maxBufferSize int
-	droppedChunks uint64
-
-	// Connection state callback
-	onConnectionStateChange func(connected bool, reconnecting bool)
-
-	// Prevent multiple reconnection attempts
-	reconnectOnce sync.Once
-}
-
-type bufferedChunk struct {
-	data       []byte
-	sampleRate int
-	channels   int
-	sequenceID uint64
-	timestamp  int64
+	*stream
+	pc          *webrtc.PeerConnection
+	dataChannel *webrtc.DataChannel
+	wsConn      *websocket.Conn
+	trackAudio  atomic.Bool // Audio goes out on the media track (chosen in the answer)
+	serverHello atomic.Bool // Server answers control.hello (announced in the answer)
+	audioTrack  *webrtc.TrackLocalStaticSample
 }
 
 // New creates a new WebRTC client
 func New(serverURL string, cfg *config.Config, log *logger.Logger, onMessage func(msg *protocol.Message)) *Client {
-	return &Client{
-		serverURL:            serverURL,
-		config:               cfg,
-		logger:               log.With("webrtc"),
-		onMessage:            onMessage,
-		maxReconnectAttempts: 10,
-		reconnectBaseDelay:   time.Second,
-		maxBufferSize:        100, // Buffer up to 100 chunks (20 seconds at 200ms/chunk)
-		stopReconnect:        make(chan struct{}),
+	c := &Client{}
+	c.stream = newStream(c, serverURL, cfg, log.With("webrtc"), onMessage)
+
+	// Opus encoding of captured audio, for the DataChannel or the media track
+	if cfg.Audio.Encoding == protocol.AudioEncodingOpus || cfg.Audio.Transport == protocol.TransportTrack {
+		c.encoder = newOpusEncoder(cfg, c.logger)
 	}
-}
 
-// SetConnectionStateCallback sets a callback for connection state changes
-func (c *Client) SetConnectionStateCallback(callback func(connected bool, reconnecting bool)) {
-	c.onConnectionStateChange = callback
+	return c
 }
 
-// Connect establishes WebRTC connection to the server
-func (c *Client) Connect() error {
-	c.logger.Info("Connecting to server at %s", c.serverURL)
-
+// connect starts the WebRTC connection to the server; it comes up once the
+// answer and ICE candidates are exchanged over the signaling WebSocket
+func (c *Client) connect() error {
 	// Connect WebSocket for signaling
-	wsConn, _, err := websocket.DefaultDialer.Dial(c.serverURL, nil)
+	wsConn, _, err := dial(c.serverURL, c.config, nil)
 	if err != nil {
 		return fmt.Errorf("failed to connect WebSocket: %w", err)
 	}
 	c.wsConn = wsConn
 
+	// Until the answer says otherwise, assume a server that only understands JSON
+	c.binaryAudio.Store(false)
+	c.trackAudio.Store(false)
+	c.serverHello.Store(false)
+
 	// Create peer connection
 	config := webrtc.Configuration{
 		ICEServers: []webrtc.ICEServer{
@@ -104,62 +71,15 @@ func (c *Client) Connect() error {
 	pc.OnConnectionStateChange(func(state webrtc.PeerConnectionState) {
 		c.logger.Info("Connection state: %s", state.String())
 
-		c.connectedMu.Lock()
-		previouslyConnected := c.connected
-
-		if state == webrtc.PeerConnectionStateConnected {
-			c.connected = true
-			c.connectedMu.Unlock()
-
-			// Reset reconnection state on successful connection
-			c.reconnectingMu.Lock()
-			if c.reconnecting {
-				c.logger.Info("Reconnection successful! Flushing buffered chunks...")
-				c.reconnecting = false
-				c.reconnectAttempts = 0
-				c.reconnectingMu.Unlock()
-
-				// Flush any buffered chunks
-				go c.flushBuffer()
-			} else {
-				c.reconnectingMu.Unlock()
-			}
-
-			// Notify callback
-			if c.onConnectionStateChange != nil {
-				c.onConnectionStateChange(true, false)
-			}
-
-		} else if state == webrtc.PeerConnectionStateFailed ||
-			state == webrtc.PeerConnectionStateDisconnected {
-			c.connected = false
-			c.connectedMu.Unlock()
-
-			// Trigger reconnection if we were previously connected
-			if previouslyConnected {
-				c.logger.Warn("Connection lost, attempting reconnection...")
-				go c.attemptReconnect()
-			}
-
-			// Notify callback
-			c.reconnectingMu.RLock()
-			reconnecting := c.reconnecting
-			c.reconnectingMu.RUnlock()
-
-			if c.onConnectionStateChange != nil {
-				c.onConnectionStateChange(false, reconnecting)
-			}
-
-		} else if state == webrtc.PeerConnectionStateClosed {
-			c.connected = false
-			c.connectedMu.Unlock()
-
+		switch state {
+		case webrtc.PeerConnectionStateConnected:
+			c.connectionUp()
+		case webrtc.PeerConnectionStateFailed, webrtc.PeerConnectionStateDisconnected:
+			c.connectionLost()
+		case webrtc.PeerConnectionStateClosed:
 			// Don't reconnect on intentional close
-			if c.onConnectionStateChange != nil {
-				c.onConnectionStateChange(false, false)
-			}
-		} else {
-			c.connectedMu.Unlock()
+			c.setConnected(false)
+			c.notify(false, false)
 		}
 	})
 
@@ -205,16 +125,16 @@ func (c *Client) Connect() error {
 	// Set up DataChannel handlers
 	dataChannel.OnOpen(func() {
 		c.logger.Info("DataChannel opened")
-		c.connectedMu.Lock()
-		c.connected = true
-		c.connectedMu.Unlock()
+		c.setConnected(true)
+
+		if c.serverHello.Load() {
+			go c.sendHello()
+		}
 	})
 
 	dataChannel.OnClose(func() {
 		c.logger.Info("DataChannel closed")
-		c.connectedMu.Lock()
-		c.connected = false
-		c.connectedMu.Unlock()
+		c.setConnected(false)
 	})
 
 	dataChannel.OnMessage(func(msg webrtc.DataChannelMessage) {
@@ -225,17 +145,31 @@ func (c *Client) Connect() error {
 		c.logger.Error("DataChannel error: %v", err)
 
 		// Mark as disconnected and trigger reconnection
-		c.connectedMu.Lock()
-		wasConnected := c.connected
-		c.connected = false
-		c.connectedMu.Unlock()
-
-		if wasConnected {
-			c.logger.Warn("DataChannel error, attempting reconnection...")
-			go c.attemptReconnect()
-		}
+		c.connectionLost()
 	})
 
+	// Offer an Opus audio track when configured; the server may still choose the DataChannel
+	transport := protocol.TransportDataChannel
+	if c.config.Audio.Transport == protocol.TransportTrack && c.encoder != nil {
+		track, err := webrtc.NewTrackLocalStaticSample(webrtc.RTPCodecCapability{
+			MimeType:  webrtc.MimeTypeOpus,
+			ClockRate: 48000, // RTP clock of Opus regardless of the encoded rate
+			Channels:  2,
+		}, "audio", "richardtate")
+		if err != nil {
+			pc.Close()
+			wsConn.Close()
+			return fmt.Errorf("failed to create audio track: %w", err)
+		}
+		if _, err := pc.AddTrack(track); err != nil {
+			pc.Close()
+			wsConn.Close()
+			return fmt.Errorf("failed to add audio track: %w", err)
+		}
+		c.audioTrack = track
+		transport = protocol.TransportTrack
+	}
+
 	// Create offer
 	offer, err := pc.CreateOffer(nil)
 	if err != nil {
@@ -260,8 +194,10 @@ func (c *Client) Connect() error {
 	}
 
 	offerMsg := protocol.SignalingMessage{
-		Type: "offer",
-		Data: json.RawMessage(offerJSON),
+		Type:        "offer",
+		Data:        json.RawMessage(offerJSON),
+		Transport:   transport,
+		ResumeToken: c.resume.Get(),
 	}
 
 	if err := c.wsConn.WriteJSON(offerMsg); err != nil {
@@ -305,6 +241,17 @@ func (c *Client) handleSignaling() {
 			}
 
 			c.logger.Debug("Set remote description (answer)")
+			c.resume.Update(msg.ResumeToken, c.logger)
+			c.serverHello.Store(c.setFeatures(msg.Features))
+
+			if c.audioTrack != nil {
+				c.trackAudio.Store(msg.Transport == protocol.TransportTrack)
+				if msg.Transport == protocol.TransportTrack {
+					c.logger.Info("Sending audio on the Opus media track")
+				} else {
+					c.logger.Warn("Server did not accept the media track, sending audio over the DataChannel")
+				}
+			}
 
 		case "ice":
 			// Server sent ICE candidate
@@ -327,335 +274,51 @@ func (c *Client) handleSignaling() {
 	}
 }
 
-// handleMessage handles incoming DataChannel messages
-func (c *Client) handleMessage(data []byte) {
-	var msg protocol.Message
-	if err := json.Unmarshal(data, &msg); err != nil {
-		c.logger.Error("Failed to unmarshal message: %v", err)
-		return
-	}
-
-	c.logger.Debug("Received message type: %s", msg.Type)
-
-	if c.onMessage != nil {
-		c.onMessage(&msg)
-	}
-}
-
-// SendMessage sends a message over the DataChannel
-func (c *Client) SendMessage(msg *protocol.Message) error {
-	c.connectedMu.RLock()
-	connected := c.connected
-	c.connectedMu.RUnlock()
-
-	if !connected || c.dataChannel == nil {
+// send writes one message to the DataChannel, which carries text and binary alike
+func (c *Client) send(messageType int, data []byte) error {
+	if c.dataChannel == nil {
 		return fmt.Errorf("data channel not ready")
 	}
-
-	data, err := json.Marshal(msg)
-	if err != nil {
-		return fmt.Errorf("failed to marshal message: %w", err)
-	}
-
 	return c.dataChannel.Send(data)
 }
 
-// SendPing sends a ping message to test the connection
-func (c *Client) SendPing() error {
-	msg := &protocol.Message{
-		Type:      protocol.MessageTypeControlPing,
-		Timestamp: time.Now().UnixMilli(),
-	}
-	return c.SendMessage(msg)
+// trackActive returns whether audio goes out on the media track
+func (c *Client) trackActive() bool {
+	return c.trackAudio.Load()
 }
 
-// SendControlStart sends a start command with VAD settings to the server to begin transcription
-func (c *Client) SendControlStart() error {
-	// Create control start data with VAD settings from config
-	controlData := protocol.ControlStartData{
-		VADEnergyThreshold:     c.config.Transcription.VAD.EnergyThreshold,
-		SilenceThresholdMs:     c.config.Transcription.VAD.SilenceThresholdMs,
-		MinChunkDurationMs:     c.config.Transcription.VAD.MinChunkDurationMs,
-		MaxChunkDurationMs:     c.config.Transcription.VAD.MaxChunkDurationMs,
-		SpeechDensityThreshold: c.config.Transcription.VAD.SpeechDensityThreshold,
-	}
-
-	// Marshal to JSON
-	data, err := json.Marshal(controlData)
+// writeTrack encodes PCM with Opus and writes one RTP sample per 20ms frame to the audio track
+func (c *Client) writeTrack(data []byte) error {
+	c.encoderMu.Lock()
+	packets, err := c.encoder.EncodeFrames(data)
+	c.encoderMu.Unlock()
 	if err != nil {
-		return fmt.Errorf("failed to marshal control data: %w", err)
-	}
-
-	msg := &protocol.Message{
-		Type:      protocol.MessageTypeControlStart,
-		Timestamp: time.Now().UnixMilli(),
-		Data:      json.RawMessage(data),
-	}
-	return c.SendMessage(msg)
-}
-
-// SendControlStop sends a stop command to the server to end transcription
-func (c *Client) SendControlStop() error {
-	msg := &protocol.Message{
-		Type:      protocol.MessageTypeControlStop,
-		Timestamp: time.Now().UnixMilli(),
-	}
-	return c.SendMessage(msg)
-}
-
-// SendAudioChunk sends an audio chunk to the server
-// If disconnected and reconnecting, chunks are buffered automatically
-func (c *Client) SendAudioChunk(data []byte, sampleRate, channels int) error {
-	c.sequenceIDMu.Lock()
-	seqID := c.sequenceID
-	c.sequenceID++
-	c.sequenceIDMu.Unlock()
-
-	// Check if we're connected
-	c.connectedMu.RLock()
-	connected := c.connected
-	c.connectedMu.RUnlock()
-
-	c.reconnectingMu.RLock()
-	reconnecting := c.reconnecting
-	c.reconnectingMu.RUnlock()
-
-	// If disconnected and reconnecting, buffer the chunk
-	if !connected && reconnecting {
-		c.bufferChunk(data, sampleRate, channels, seqID)
-		return nil // Return nil since buffering succeeded
-	}
-
-	// If disconnected and NOT reconnecting, return error
-	if !connected {
-		return fmt.Errorf("not connected and not reconnecting")
-	}
-
-	// Connected - send immediately
-	audioData := protocol.AudioChunkData{
-		SampleRate: sampleRate,
-		Channels:   channels,
-		Data:       data,
-		SequenceID: seqID,
-	}
-
-	audioJSON, err := json.Marshal(audioData)
-	if err != nil {
-		return fmt.Errorf("failed to marshal audio data: %w", err)
-	}
-
-	msg := &protocol.Message{
-		Type:      protocol.MessageTypeAudioChunk,
-		Timestamp: time.Now().UnixMilli(),
-		Data:      json.RawMessage(audioJSON),
-	}
-
-	return c.SendMessage(msg)
-}
-
-// IsConnected returns whether the DataChannel is connected
-func (c *Client) IsConnected() bool {
-	c.connectedMu.RLock()
-	defer c.connectedMu.RUnlock()
-	return c.connected
-}
-
-// attemptReconnect handles automatic reconnection with exponential backoff
-func (c *Client) attemptReconnect() {
-	// Prevent multiple concurrent reconnection attempts
-	c.reconnectingMu.Lock()
-	if c.reconnecting {
-		c.reconnectingMu.Unlock()
-		return
-	}
-	c.reconnecting = true
-	c.reconnectingMu.Unlock()
-
-	c.logger.Info("Starting reconnection attempts...")
-
-	// Notify callback
-	if c.onConnectionStateChange != nil {
-		c.onConnectionStateChange(false, true)
-	}
-
-	for {
-		c.reconnectingMu.Lock()
-		attempts := c.reconnectAttempts
-		c.reconnectAttempts++
-		c.reconnectingMu.Unlock()
-
-		if attempts >= c.maxReconnectAttempts {
-			c.logger.Error("Max reconnection attempts (%d) reached, giving up", c.maxReconnectAttempts)
-			c.reconnectingMu.Lock()
-			c.reconnecting = false
-			c.reconnectingMu.Unlock()
-
-			if c.onConnectionStateChange != nil {
-				c.onConnectionStateChange(false, false)
-			}
-			return
-		}
-
-		// Exponential backoff: 1s, 2s, 4s, 8s, 16s, 30s (max)
-		delay := c.reconnectBaseDelay * time.Duration(1<<uint(attempts))
-		if delay > 30*time.Second {
-			delay = 30 * time.Second
-		}
-
-		c.logger.Info("Reconnection attempt %d/%d in %v...", attempts+1, c.maxReconnectAttempts, delay)
-
-		select {
-		case <-time.After(delay):
-			// Try to reconnect
-		case <-c.stopReconnect:
-			c.logger.Info("Reconnection stopped by user")
-			c.reconnectingMu.Lock()
-			c.reconnecting = false
-			c.reconnectingMu.Unlock()
-			return
-		}
-
-		// Close old connection
-		if c.pc != nil {
-			c.pc.Close()
-		}
-		if c.wsConn != nil {
-			c.wsConn.Close()
-		}
-
-		// Attempt to reconnect
-		err := c.Connect()
-		if err != nil {
-			c.logger.Warn("Reconnection attempt %d failed: %v", attempts+1, err)
-			continue
-		}
-
-		// Wait for DataChannel to open (give it 10 seconds)
-		c.logger.Info("Waiting for DataChannel to open...")
-		for i := 0; i < 100; i++ {
-			time.Sleep(100 * time.Millisecond)
-			c.connectedMu.RLock()
-			connected := c.connected
-			c.connectedMu.RUnlock()
-
-			if connected {
-				c.logger.Info("Reconnection successful on attempt %d!", attempts+1)
-				return // Success! OnConnectionStateChange will handle the rest
-			}
-		}
-
-		c.logger.Warn("Reconnection attempt %d timed out waiting for DataChannel", attempts+1)
-	}
-}
-
-// bufferChunk buffers an audio chunk during reconnection
-func (c *Client) bufferChunk(data []byte, sampleRate, channels int, sequenceID uint64) {
-	c.chunkBufferMu.Lock()
-	defer c.chunkBufferMu.Unlock()
-
-	// Check if buffer is full
-	if len(c.chunkBuffer) >= c.maxBufferSize {
-		// Drop oldest chunk
-		c.chunkBuffer = c.chunkBuffer[1:]
-		c.droppedChunks++
-		c.logger.Warn("Chunk buffer full, dropped chunk (total dropped: %d)", c.droppedChunks)
-	}
-
-	// Make a copy of the data since it might be reused by the caller
-	dataCopy := make([]byte, len(data))
-	copy(dataCopy, data)
-
-	chunk := bufferedChunk{
-		data:       dataCopy,
-		sampleRate: sampleRate,
-		channels:   channels,
-		sequenceID: sequenceID,
-		timestamp:  time.Now().UnixMilli(),
-	}
-
-	c.chunkBuffer = append(c.chunkBuffer, chunk)
-	c.logger.Debug("Buffered chunk seq=%d (buffer size: %d/%d)", sequenceID, len(c.chunkBuffer), c.maxBufferSize)
-}
-
-// flushBuffer sends all buffered chunks after reconnection
-func (c *Client) flushBuffer() {
-	c.chunkBufferMu.Lock()
-	chunks := make([]bufferedChunk, len(c.chunkBuffer))
-	copy(chunks, c.chunkBuffer)
-	c.chunkBuffer = nil // Clear buffer
-	dropped := c.droppedChunks
-	c.droppedChunks = 0
-	c.chunkBufferMu.Unlock()
-
-	if len(chunks) == 0 {
-		c.logger.Info("No buffered chunks to flush")
-		return
+		return fmt.Errorf("failed to encode audio: %w", err)
 	}
 
-	c.logger.Info("Flushing %d buffered chunks (%d were dropped during disconnect)", len(chunks), dropped)
-
-	for i, chunk := range chunks {
-		audioData := protocol.AudioChunkData{
-			SampleRate: chunk.sampleRate,
-			Channels:   chunk.channels,
-			Data:       chunk.data,
-			SequenceID: chunk.sequenceID,
-		}
-
-		audioJSON, err := json.Marshal(audioData)
-		if err != nil {
-			c.logger.Error("Failed to marshal buffered chunk %d: %v", i, err)
-			continue
-		}
-
-		msg := &protocol.Message{
-			Type:      protocol.MessageTypeAudioChunk,
-			Timestamp: chunk.timestamp,
-			Data:      json.RawMessage(audioJSON),
+	for _, packet := range packets {
+		if err := c.audioTrack.WriteSample(media.Sample{Data: packet, Duration: audiocodec.FrameDuration}); err != nil {
+			return fmt.Errorf("failed to write audio track: %w", err)
 		}
-
-		// Try to send, but don't fail if it doesn't work
-		err = c.SendMessage(msg)
-		if err != nil {
-			c.logger.Warn("Failed to send buffered chunk seq=%d: %v", chunk.sequenceID, err)
-			// Continue trying to send the rest
-		} else {
-			c.logger.Debug("Flushed buffered chunk seq=%d", chunk.sequenceID)
-		}
-
-		// Small delay to avoid overwhelming the connection
-		time.Sleep(10 * time.Millisecond)
 	}
-
-	c.logger.Info("Finished flushing buffered chunks")
+	return nil
 }
 
-// Close closes the WebRTC connection
-func (c *Client) Close() error {
-	c.logger.Info("Closing WebRTC connection")
-
-	// Stop any reconnection attempts
-	close(c.stopReconnect)
-
-	c.reconnectingMu.Lock()
-	c.reconnecting = false
-	c.reconnectingMu.Unlock()
-
-	c.connectedMu.Lock()
-	c.connected = false
-	c.connectedMu.Unlock()
-
-	if c.dataChannel != nil {
-		c.dataChannel.Close()
-	}
-
+// hangUp closes the current peer connection and signaling WebSocket before a reconnect
+func (c *Client) hangUp() {
 	if c.pc != nil {
 		c.pc.Close()
 	}
-
 	if c.wsConn != nil {
 		c.wsConn.Close()
 	}
+}
 
+// close closes the WebRTC connection
+func (c *Client) close() error {
+	if c.dataChannel != nil {
+		c.dataChannel.Close()
+	}
+	c.hangUp()
 	return nil
 }
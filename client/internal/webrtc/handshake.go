@@ -0,0 +1,79 @@
+package webrtc
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/lucianHymer/streaming-transcription/client/internal/config"
+	"github.com/lucianHymer/streaming-transcription/shared/logger"
+	"github.com/lucianHymer/streaming-transcription/shared/protocol"
+)
+
+// clientFeatures are the protocol features this client understands
+var clientFeatures = []string{
+	protocol.FeatureBinaryAudio,
+	protocol.FeaturePartials,
+	protocol.FeatureTimestamps,
+	protocol.FeatureSessionResume,
+	protocol.FeatureAudioStats,
+}
+
+// newHelloMessage creates the client's half of the handshake
+// Only sent to servers announcing FeatureHello; older ones reject unknown messages
+func newHelloMessage(opus bool) (*protocol.Message, error) {
+	hello := protocol.HelloData{
+		ProtocolVersion: protocol.ProtocolVersion,
+		AudioEncodings:  []string{protocol.AudioEncodingPCM16},
+		Features:        clientFeatures,
+	}
+	if opus {
+		hello.AudioEncodings = append(hello.AudioEncodings, protocol.AudioEncodingOpus)
+	}
+
+	data, err := json.Marshal(hello)
+	if err != nil {
+		return nil, fmt.Errorf("failed to marshal hello: %w", err)
+	}
+
+	return &protocol.Message{
+		Type:      protocol.MessageTypeControlHello,
+		Timestamp: time.Now().UnixMilli(),
+		Data:      json.RawMessage(data),
+	}, nil
+}
+
+// handleWelcome logs the server's half of the handshake and warns about configured
+// settings the server will reject in control.start
+func handleWelcome(msg *protocol.Message, cfg *config.Config, log *logger.ContextLogger) {
+	var welcome protocol.WelcomeData
+	if err := json.Unmarshal(msg.Data, &welcome); err != nil {
+		log.Error("Failed to unmarshal welcome: %v", err)
+		return
+	}
+
+	log.Info("Server speaks protocol v%d: model %s, encodings %v, features %v",
+		welcome.ProtocolVersion, welcome.Model, welcome.AudioEncodings, welcome.Features)
+
+	whisper := cfg.Transcription.Whisper
+	if whisper.Language != "" && whisper.Language != "auto" && !protocol.HasFeature(welcome.Languages, whisper.Language) {
+		log.Warn("Server does not offer language %q (available: %v)", whisper.Language, welcome.Languages)
+	}
+	if whisper.Translate && !welcome.Multilingual {
+		log.Warn("Server model is English-only and cannot translate")
+	}
+	if whisper.Threads > welcome.Limits.MaxThreads {
+		log.Warn("Configured threads %d exceed the server limit %d", whisper.Threads, welcome.Limits.MaxThreads)
+	}
+	if whisper.Temperature > welcome.Limits.MaxTemperature {
+		log.Warn("Configured temperature %.2f exceeds the server limit %.2f", whisper.Temperature, welcome.Limits.MaxTemperature)
+	}
+	// Lower bound: the server also adds its default prompt when only vocabulary is set
+	if prompt := len(whisper.InitialPrompt) + len(strings.Join(whisper.Vocabulary, ", ")); prompt > welcome.Limits.MaxPromptChars {
+		log.Warn("Configured prompt and vocabulary (%d characters) exceed the server limit %d", prompt, welcome.Limits.MaxPromptChars)
+	}
+	if cfg.Audio.Encoding == protocol.AudioEncodingOpus && !protocol.HasFeature(welcome.AudioEncodings, protocol.AudioEncodingOpus) {
+		log.Warn("Server cannot decode Opus audio, sending PCM")
+	}
+}
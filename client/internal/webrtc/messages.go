@@ -0,0 +1,85 @@
+package webrtc
+
+import (
+	"encoding/json"
+	"fmt"
+	"time"
+
+	"github.com/lucianHymer/streaming-transcription/client/internal/config"
+	"github.com/lucianHymer/streaming-transcription/shared/protocol"
+)
+
+// Message constructors shared by the WebRTC and WebSocket transports
+
+// newPingMessage creates a ping message to test the connection
+func newPingMessage() *protocol.Message {
+	return &protocol.Message{
+		Type:      protocol.MessageTypeControlPing,
+		Timestamp: time.Now().UnixMilli(),
+	}
+}
+
+// newControlStartMessage creates a start command with the VAD and Whisper settings from config
+func newControlStartMessage(cfg *config.Config, sessionID uint64) (*protocol.Message, error) {
+	controlData := protocol.ControlStartData{
+		VADEnergyThreshold:     cfg.Transcription.VAD.EnergyThreshold,
+		SilenceThresholdMs:     cfg.Transcription.VAD.SilenceThresholdMs,
+		MinChunkDurationMs:     cfg.Transcription.VAD.MinChunkDurationMs,
+		MaxChunkDurationMs:     cfg.Transcription.VAD.MaxChunkDurationMs,
+		SpeechDensityThreshold: cfg.Transcription.VAD.SpeechDensityThreshold,
+		Language:               cfg.Transcription.Whisper.Language,
+		Translate:              cfg.Transcription.Whisper.Translate,
+		InitialPrompt:          cfg.Transcription.Whisper.InitialPrompt,
+		Vocabulary:             cfg.Transcription.Whisper.Vocabulary,
+		Threads:                cfg.Transcription.Whisper.Threads,
+		Temperature:            cfg.Transcription.Whisper.Temperature,
+		SessionID:              sessionID,
+	}
+
+	data, err := json.Marshal(controlData)
+	if err != nil {
+		return nil, fmt.Errorf("failed to marshal control data: %w", err)
+	}
+
+	return &protocol.Message{
+		Type:      protocol.MessageTypeControlStart,
+		Timestamp: time.Now().UnixMilli(),
+		Data:      json.RawMessage(data),
+	}, nil
+}
+
+// newControlStopMessage creates a stop command to end transcription
+func newControlStopMessage() *protocol.Message {
+	return &protocol.Message{
+		Type:      protocol.MessageTypeControlStop,
+		Timestamp: time.Now().UnixMilli(),
+	}
+}
+
+// newTranscriptResendMessage asks the server to send the given final transcripts again
+func newTranscriptResendMessage(sessionID uint64, sequenceIDs []uint64) (*protocol.Message, error) {
+	data, err := json.Marshal(protocol.TranscriptResendData{SequenceIDs: sequenceIDs, SessionID: sessionID})
+	if err != nil {
+		return nil, fmt.Errorf("failed to marshal resend data: %w", err)
+	}
+
+	return &protocol.Message{
+		Type:      protocol.MessageTypeTranscriptResend,
+		Timestamp: time.Now().UnixMilli(),
+		Data:      json.RawMessage(data),
+	}, nil
+}
+
+// newAudioChunkMessage wraps a chunk in a JSON message for servers without binary audio frames
+func newAudioChunkMessage(audioData protocol.AudioChunkData, timestamp int64) (*protocol.Message, error) {
+	audioJSON, err := json.Marshal(audioData)
+	if err != nil {
+		return nil, fmt.Errorf("failed to marshal audio data: %w", err)
+	}
+
+	return &protocol.Message{
+		Type:      protocol.MessageTypeAudioChunk,
+		Timestamp: timestamp,
+		Data:      json.RawMessage(audioJSON),
+	}, nil
+}
@@ -0,0 +1,506 @@
+package webrtc
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/gorilla/websocket"
+	"github.com/lucianHymer/streaming-transcription/client/internal/config"
+	"github.com/lucianHymer/streaming-transcription/shared/audiocodec"
+	"github.com/lucianHymer/streaming-transcription/shared/logger"
+	"github.com/lucianHymer/streaming-transcription/shared/protocol"
+)
+
+// maxBufferedChunks is how many chunks are buffered while reconnecting (20 seconds at 200ms/chunk)
+const maxBufferedChunks = 100
+
+// reconnectTimeout is how long a reconnection attempt waits for the new connection to come up
+const reconnectTimeout = 10 * time.Second
+
+// link is the connection a stream sends over: a WebRTC DataChannel or a plain WebSocket
+// The link reports its connection coming up or going down back to the stream
+type link interface {
+	// connect opens a new connection, presenting the stream's resume token
+	connect() error
+	// hangUp closes the current connection before a reconnect
+	hangUp()
+	// send writes one message; messageType is websocket.TextMessage or BinaryMessage
+	send(messageType int, data []byte) error
+	// close closes the connection for good
+	close() error
+}
+
+// trackLink is a link that can also carry live audio on an Opus media track
+type trackLink interface {
+	// trackActive returns whether the server accepted the media track
+	trackActive() bool
+	// writeTrack encodes PCM and sends it on the media track
+	writeTrack(data []byte) error
+}
+
+// stream holds the state shared by all transports: the session handshake, audio
+// encoding, buffering while disconnected and reconnection with backoff
+type stream struct {
+	link         link
+	serverURL    string
+	config       *config.Config
+	logger       *logger.ContextLogger
+	onMessage    func(msg *protocol.Message)
+	connected    bool
+	connectedMu  sync.RWMutex
+	sequenceID   uint64
+	sequenceIDMu sync.Mutex
+	binaryAudio  atomic.Bool // Server accepts binary audio frames (announced when connecting)
+	opusAudio    atomic.Bool // Server decodes Opus audio (announced when connecting)
+	closed       atomic.Bool
+	resume       resumeToken // Presented when connecting to resume the session after a reconnect
+
+	// Opus encoding of captured audio (nil unless the transport needs it)
+	encoder   *audiocodec.OpusEncoder
+	encoderMu sync.Mutex
+
+	// Reconnection state
+	reconnecting         bool
+	reconnectingMu       sync.RWMutex
+	reconnectAttempts    int
+	maxReconnectAttempts int
+	reconnectBaseDelay   time.Duration
+	stopReconnect        chan struct{}
+
+	// Audio chunk buffering during reconnection
+	chunkBuffer *chunkBuffer
+	sendMu      sync.Mutex // Orders audio sends, so buffered chunks go out before live ones
+
+	// Connection state callback
+	onConnectionStateChange func(connected bool, reconnecting bool)
+}
+
+// newStream creates the shared state of a transport sending over l
+func newStream(l link, serverURL string, cfg *config.Config, log *logger.ContextLogger, onMessage func(msg *protocol.Message)) *stream {
+	return &stream{
+		link:                 l,
+		serverURL:            serverURL,
+		config:               cfg,
+		logger:               log,
+		onMessage:            onMessage,
+		maxReconnectAttempts: 10,
+		reconnectBaseDelay:   time.Second,
+		stopReconnect:        make(chan struct{}),
+		chunkBuffer:          newChunkBuffer(maxBufferedChunks, log),
+	}
+}
+
+// newOpusEncoder creates the encoder for captured audio, or nil if Opus is unavailable
+func newOpusEncoder(cfg *config.Config, log *logger.ContextLogger) *audiocodec.OpusEncoder {
+	encoder, err := audiocodec.NewOpusEncoder(cfg.Audio.OpusBitrate)
+	if err != nil {
+		log.Warn("Opus encoding unavailable, sending PCM: %v", err)
+		return nil
+	}
+	return encoder
+}
+
+// SetConnectionStateCallback sets a callback for connection state changes
+func (s *stream) SetConnectionStateCallback(callback func(connected bool, reconnecting bool)) {
+	s.onConnectionStateChange = callback
+}
+
+// notify reports a connection state change to the callback
+func (s *stream) notify(connected, reconnecting bool) {
+	if s.onConnectionStateChange != nil {
+		s.onConnectionStateChange(connected, reconnecting)
+	}
+}
+
+// Connect connects to the server
+func (s *stream) Connect() error {
+	s.logger.Info("Connecting to server at %s", s.serverURL)
+	return s.link.connect()
+}
+
+// setFeatures records the features the server announced, returning whether it answers control.hello
+// Opus support outlives the connection, so chunks buffered while reconnecting stay compressed
+func (s *stream) setFeatures(features []string) bool {
+	binaryAudio, opusAudio, serverHello := false, false, false
+	for _, feature := range features {
+		switch strings.TrimSpace(feature) {
+		case protocol.FeatureBinaryAudio:
+			binaryAudio = true
+		case protocol.FeatureOpusAudio:
+			opusAudio = true
+		case protocol.FeatureHello:
+			serverHello = true
+		}
+	}
+	s.binaryAudio.Store(binaryAudio)
+	s.opusAudio.Store(opusAudio)
+
+	if binaryAudio {
+		s.logger.Info("Server accepts binary audio frames")
+	}
+	if s.encoder != nil {
+		if opusAudio {
+			s.logger.Info("Sending Opus audio")
+		} else {
+			s.logger.Warn("Server cannot decode Opus audio, sending PCM")
+		}
+	}
+	return serverHello
+}
+
+// setConnected records whether messages can be sent
+func (s *stream) setConnected(connected bool) {
+	s.connectedMu.Lock()
+	s.connected = connected
+	s.connectedMu.Unlock()
+}
+
+// connectionUp marks the connection established, ending any reconnection
+func (s *stream) connectionUp() {
+	s.setConnected(true)
+
+	// Reset reconnection state on successful connection
+	s.reconnectingMu.Lock()
+	if s.reconnecting {
+		s.logger.Info("Reconnection successful! Flushing buffered chunks...")
+		s.reconnecting = false
+		s.reconnectAttempts = 0
+		s.reconnectingMu.Unlock()
+
+		// Flush any buffered chunks
+		go s.flushBuffer()
+	} else {
+		s.reconnectingMu.Unlock()
+	}
+
+	s.notify(true, false)
+}
+
+// connectionLost marks the connection down, and starts reconnecting if it was up
+func (s *stream) connectionLost() {
+	s.connectedMu.Lock()
+	wasConnected := s.connected
+	s.connected = false
+	s.connectedMu.Unlock()
+
+	if wasConnected {
+		s.logger.Warn("Connection lost, attempting reconnection...")
+		go s.attemptReconnect()
+		return
+	}
+
+	s.reconnectingMu.RLock()
+	reconnecting := s.reconnecting
+	s.reconnectingMu.RUnlock()
+	s.notify(false, reconnecting)
+}
+
+// handleMessage handles an incoming message from the server
+func (s *stream) handleMessage(data []byte) {
+	var msg protocol.Message
+	if err := json.Unmarshal(data, &msg); err != nil {
+		s.logger.Error("Failed to unmarshal message: %v", err)
+		return
+	}
+
+	s.logger.Debug("Received message type: %s", msg.Type)
+
+	if msg.Type == protocol.MessageTypeControlWelcome {
+		handleWelcome(&msg, s.config, s.logger)
+		return
+	}
+
+	if s.onMessage != nil {
+		s.onMessage(&msg)
+	}
+}
+
+// SendMessage sends a JSON message to the server
+func (s *stream) SendMessage(msg *protocol.Message) error {
+	data, err := json.Marshal(msg)
+	if err != nil {
+		return fmt.Errorf("failed to marshal message: %w", err)
+	}
+	if !s.IsConnected() {
+		return fmt.Errorf("not connected")
+	}
+	return s.link.send(websocket.TextMessage, data)
+}
+
+// sendHello starts the protocol handshake
+func (s *stream) sendHello() {
+	msg, err := newHelloMessage(s.encoder != nil)
+	if err == nil {
+		err = s.SendMessage(msg)
+	}
+	if err != nil {
+		s.logger.Warn("Failed to send hello: %v", err)
+	}
+}
+
+// SendPing sends a ping message to test the connection
+func (s *stream) SendPing() error {
+	return s.SendMessage(newPingMessage())
+}
+
+// SendControlStart sends a start command with VAD and Whisper settings to the server to begin transcription
+// The server tags the session's transcripts with sessionID
+func (s *stream) SendControlStart(sessionID uint64) error {
+	// The server starts every session with a fresh decoder
+	if s.encoder != nil {
+		s.encoderMu.Lock()
+		err := s.encoder.Reset()
+		s.encoderMu.Unlock()
+		if err != nil {
+			return fmt.Errorf("failed to reset opus encoder: %w", err)
+		}
+	}
+
+	msg, err := newControlStartMessage(s.config, sessionID)
+	if err != nil {
+		return err
+	}
+	return s.SendMessage(msg)
+}
+
+// SendControlStop sends a stop command to the server to end transcription
+func (s *stream) SendControlStop() error {
+	return s.SendMessage(newControlStopMessage())
+}
+
+// SendTranscriptResend asks the server to send the given final transcripts again
+func (s *stream) SendTranscriptResend(sessionID uint64, sequenceIDs []uint64) error {
+	msg, err := newTranscriptResendMessage(sessionID, sequenceIDs)
+	if err != nil {
+		return err
+	}
+	return s.SendMessage(msg)
+}
+
+// SendAudioChunk sends an audio chunk to the server
+// If disconnected and reconnecting, chunks are buffered automatically
+func (s *stream) SendAudioChunk(data []byte, sampleRate, channels int) error {
+	s.sequenceIDMu.Lock()
+	seqID := s.sequenceID
+	s.sequenceID++
+	s.sequenceIDMu.Unlock()
+
+	// With the media track negotiated, audio goes out as RTP; chunks buffered
+	// while reconnecting still go as messages, and are sent first so the
+	// server receives the audio in order
+	if track, ok := s.link.(trackLink); ok && track.trackActive() && s.IsConnected() {
+		s.sendMu.Lock()
+		defer s.sendMu.Unlock()
+		s.sendBuffered()
+		return track.writeTrack(data)
+	}
+
+	// Encode before buffering so the reconnection buffer holds compressed audio
+	data, encoding := s.encodeAudio(data)
+
+	connected := s.IsConnected()
+
+	s.reconnectingMu.RLock()
+	reconnecting := s.reconnecting
+	s.reconnectingMu.RUnlock()
+
+	// If disconnected and reconnecting, buffer the chunk
+	if !connected && reconnecting {
+		s.chunkBuffer.Add(data, sampleRate, channels, encoding, seqID)
+		return nil // Return nil since buffering succeeded
+	}
+
+	// If disconnected and NOT reconnecting, return error
+	if !connected {
+		return fmt.Errorf("not connected and not reconnecting")
+	}
+
+	// Connected - send immediately, after any chunks still buffered from the disconnect
+	s.sendMu.Lock()
+	defer s.sendMu.Unlock()
+	s.sendBuffered()
+	return s.sendAudio(protocol.AudioChunkData{
+		SampleRate: sampleRate,
+		Channels:   channels,
+		Data:       data,
+		SequenceID: seqID,
+		Encoding:   encoding,
+	}, time.Now().UnixMilli())
+}
+
+// encodeAudio compresses 16-bit PCM with Opus when configured and the server can decode it
+// Chunks the encoder fails on are sent as PCM
+func (s *stream) encodeAudio(data []byte) ([]byte, string) {
+	if s.encoder == nil || !s.opusAudio.Load() {
+		return data, protocol.AudioEncodingPCM16
+	}
+
+	s.encoderMu.Lock()
+	defer s.encoderMu.Unlock()
+
+	payload, err := s.encoder.EncodeChunk(data)
+	if err != nil {
+		s.logger.Warn("Opus encoding failed, sending PCM: %v", err)
+		return data, protocol.AudioEncodingPCM16
+	}
+	return payload, protocol.AudioEncodingOpus
+}
+
+// sendAudio sends a chunk as a binary audio frame, or as JSON to servers without binary support
+func (s *stream) sendAudio(audioData protocol.AudioChunkData, timestamp int64) error {
+	if s.binaryAudio.Load() {
+		if !s.IsConnected() {
+			return fmt.Errorf("not connected")
+		}
+		return s.link.send(websocket.BinaryMessage, protocol.EncodeAudioFrame(audioData, timestamp))
+	}
+
+	msg, err := newAudioChunkMessage(audioData, timestamp)
+	if err != nil {
+		return err
+	}
+	return s.SendMessage(msg)
+}
+
+// IsConnected returns whether messages can be sent to the server
+func (s *stream) IsConnected() bool {
+	s.connectedMu.RLock()
+	defer s.connectedMu.RUnlock()
+	return s.connected
+}
+
+// attemptReconnect handles automatic reconnection with exponential backoff
+func (s *stream) attemptReconnect() {
+	// Prevent multiple concurrent reconnection attempts
+	s.reconnectingMu.Lock()
+	if s.reconnecting {
+		s.reconnectingMu.Unlock()
+		return
+	}
+	s.reconnecting = true
+	s.reconnectingMu.Unlock()
+
+	s.logger.Info("Starting reconnection attempts...")
+	s.notify(false, true)
+
+	for {
+		s.reconnectingMu.Lock()
+		attempts := s.reconnectAttempts
+		s.reconnectAttempts++
+		s.reconnectingMu.Unlock()
+
+		if attempts >= s.maxReconnectAttempts {
+			s.logger.Error("Max reconnection attempts (%d) reached, giving up", s.maxReconnectAttempts)
+			s.reconnectingMu.Lock()
+			s.reconnecting = false
+			s.reconnectingMu.Unlock()
+
+			s.notify(false, false)
+			return
+		}
+
+		// Exponential backoff: 1s, 2s, 4s, 8s, 16s, 30s (max)
+		delay := s.reconnectBaseDelay * time.Duration(1<<uint(attempts))
+		if delay > 30*time.Second {
+			delay = 30 * time.Second
+		}
+
+		s.logger.Info("Reconnection attempt %d/%d in %v...", attempts+1, s.maxReconnectAttempts, delay)
+
+		select {
+		case <-time.After(delay):
+			// Try to reconnect
+		case <-s.stopReconnect:
+			s.logger.Info("Reconnection stopped by user")
+			s.reconnectingMu.Lock()
+			s.reconnecting = false
+			s.reconnectingMu.Unlock()
+			return
+		}
+
+		s.link.hangUp()
+		if err := s.link.connect(); err != nil {
+			s.logger.Warn("Reconnection attempt %d failed: %v", attempts+1, err)
+			continue
+		}
+
+		// The link may come up asynchronously (WebRTC negotiates after connect returns)
+		// connectionUp clears the reconnecting state and flushes the buffer
+		if s.waitConnected(reconnectTimeout) {
+			s.logger.Info("Reconnection successful on attempt %d!", attempts+1)
+			return
+		}
+
+		s.logger.Warn("Reconnection attempt %d timed out waiting for the connection", attempts+1)
+	}
+}
+
+// waitConnected waits up to timeout for the connection to come up
+func (s *stream) waitConnected(timeout time.Duration) bool {
+	deadline := time.Now().Add(timeout)
+	for !s.IsConnected() {
+		if time.Now().After(deadline) {
+			return false
+		}
+		time.Sleep(100 * time.Millisecond)
+	}
+	return true
+}
+
+// flushBuffer sends all buffered chunks after reconnection
+func (s *stream) flushBuffer() {
+	s.sendMu.Lock()
+	defer s.sendMu.Unlock()
+	s.sendBuffered()
+}
+
+// sendBuffered sends the chunks buffered while reconnecting, oldest first (caller holds sendMu)
+// Holding sendMu keeps live chunks from overtaking them, which the server would drop as late
+func (s *stream) sendBuffered() {
+	chunks, dropped := s.chunkBuffer.Drain()
+	if len(chunks) == 0 {
+		return
+	}
+
+	s.logger.Info("Flushing %d buffered chunks (%d were dropped during disconnect)", len(chunks), dropped)
+
+	for _, chunk := range chunks {
+		audioData := protocol.AudioChunkData{
+			SampleRate: chunk.sampleRate,
+			Channels:   chunk.channels,
+			Data:       chunk.data,
+			SequenceID: chunk.sequenceID,
+			Encoding:   chunk.encoding,
+		}
+
+		// Try to send, but don't fail if it doesn't work
+		if err := s.sendAudio(audioData, chunk.timestamp); err != nil {
+			s.logger.Warn("Failed to send buffered chunk seq=%d: %v", chunk.sequenceID, err)
+		} else {
+			s.logger.Debug("Flushed buffered chunk seq=%d", chunk.sequenceID)
+		}
+	}
+
+	s.logger.Info("Finished flushing buffered chunks")
+}
+
+// Close closes the connection and stops reconnecting
+func (s *stream) Close() error {
+	s.logger.Info("Closing connection to server")
+
+	s.closed.Store(true)
+
+	// Stop any reconnection attempts
+	close(s.stopReconnect)
+
+	s.reconnectingMu.Lock()
+	s.reconnecting = false
+	s.reconnectingMu.Unlock()
+
+	s.setConnected(false)
+	return s.link.close()
+}
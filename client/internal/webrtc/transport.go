@@ -0,0 +1,95 @@
+package webrtc
+
+import (
+	"fmt"
+	"net/http"
+	"sync"
+
+	"github.com/gorilla/websocket"
+
+	"github.com/lucianHymer/streaming-transcription/client/internal/config"
+	"github.com/lucianHymer/streaming-transcription/shared/logger"
+	"github.com/lucianHymer/streaming-transcription/shared/protocol"
+)
+
+// Transport streams audio and control messages to the server
+// Client (WebRTC) and WebSocketClient (plain WebSocket) implement it
+type Transport interface {
+	Connect() error
+	IsConnected() bool
+	SetConnectionStateCallback(callback func(connected bool, reconnecting bool))
+	SendPing() error
+	SendControlStart(sessionID uint64) error
+	SendControlStop() error
+	SendTranscriptResend(sessionID uint64, sequenceIDs []uint64) error
+	SendAudioChunk(data []byte, sampleRate, channels int) error
+	Close() error
+}
+
+// NewTransport creates the transport selected by server.transport
+func NewTransport(cfg *config.Config, log *logger.Logger, onMessage func(msg *protocol.Message)) Transport {
+	if cfg.Server.Transport == "websocket" {
+		return NewWebSocket(cfg.Server.URL+"/api/v1/stream/ws", cfg, log, onMessage)
+	}
+	return New(cfg.Server.URL+"/api/v1/stream/signal", cfg, log, onMessage)
+}
+
+// dial opens a WebSocket to the server, presenting the configured auth token and
+// trusting the configured CA
+func dial(serverURL string, cfg *config.Config, header http.Header) (*websocket.Conn, *http.Response, error) {
+	if header == nil {
+		header = http.Header{}
+	}
+	if cfg.Server.AuthToken != "" {
+		header.Set("Authorization", "Bearer "+cfg.Server.AuthToken)
+	}
+
+	tlsConfig, err := cfg.ServerTLSConfig()
+	if err != nil {
+		return nil, nil, err
+	}
+	dialer := *websocket.DefaultDialer
+	dialer.TLSClientConfig = tlsConfig
+
+	conn, resp, err := dialer.Dial(serverURL, header)
+	if err != nil && resp != nil {
+		switch resp.StatusCode {
+		case http.StatusUnauthorized:
+			return nil, resp, fmt.Errorf("server rejected the credentials (check server.auth_token): %w", err)
+		case http.StatusTooManyRequests:
+			return nil, resp, fmt.Errorf("server refused the connection, too many connections for this user: %w", err)
+		}
+	}
+	return conn, resp, err
+}
+
+// resumeToken holds the token the server issued for resuming the session after a reconnect
+type resumeToken struct {
+	mu    sync.Mutex
+	token string
+}
+
+// Get returns the token to present when connecting (empty before the first connection)
+func (r *resumeToken) Get() string {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return r.token
+}
+
+// Update stores the token issued by the server, logging whether the previous session was resumed
+func (r *resumeToken) Update(token string, log *logger.ContextLogger) {
+	if token == "" {
+		return // Server without session resumption
+	}
+
+	r.mu.Lock()
+	previous := r.token
+	r.token = token
+	r.mu.Unlock()
+
+	if previous == token {
+		log.Info("Resumed the transcription session after reconnecting")
+	} else if previous != "" {
+		log.Warn("Server could not resume the transcription session, audio and transcripts in flight were lost")
+	}
+}
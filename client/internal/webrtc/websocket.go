@@ -0,0 +1,127 @@
+package webrtc
+
+import (
+	"fmt"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/gorilla/websocket"
+	"github.com/lucianHymer/streaming-transcription/client/internal/config"
+	"github.com/lucianHymer/streaming-transcription/shared/logger"
+	"github.com/lucianHymer/streaming-transcription/shared/protocol"
+)
+
+// wsWriteTimeout bounds a write to the server so a stalled socket cannot block capture
+const wsWriteTimeout = 10 * time.Second
+
+// WebSocketClient streams to the server over a plain WebSocket, for networks where
+// WebRTC is blocked or unavailable
+// It carries the same messages as the DataChannel and reconnects the same way
+type WebSocketClient struct {
+	*stream
+	conn    *websocket.Conn
+	writeMu sync.Mutex // Guards conn; gorilla allows one concurrent writer
+}
+
+// NewWebSocket creates a new WebSocket client
+func NewWebSocket(serverURL string, cfg *config.Config, log *logger.Logger, onMessage func(msg *protocol.Message)) *WebSocketClient {
+	c := &WebSocketClient{}
+	c.stream = newStream(c, serverURL, cfg, log.With("websocket"), onMessage)
+
+	if cfg.Audio.Transport == protocol.TransportTrack {
+		c.logger.Warn("Media track transport needs WebRTC, sending audio over the WebSocket")
+	}
+	if cfg.Audio.Encoding == protocol.AudioEncodingOpus {
+		c.encoder = newOpusEncoder(cfg, c.logger)
+	}
+
+	return c
+}
+
+// connect opens the WebSocket to the server
+func (c *WebSocketClient) connect() error {
+	header := http.Header{}
+	if token := c.resume.Get(); token != "" {
+		header.Set(protocol.ResumeTokenHeader, token)
+	}
+
+	conn, resp, err := dial(c.serverURL, c.config, header)
+	if err != nil {
+		return fmt.Errorf("failed to connect WebSocket: %w", err)
+	}
+	c.resume.Update(resp.Header.Get(protocol.ResumeTokenHeader), c.logger)
+
+	// There is no signaling answer, so features come with the upgrade response
+	serverHello := c.setFeatures(strings.Split(resp.Header.Get(protocol.FeaturesHeader), ","))
+
+	c.writeMu.Lock()
+	c.conn = conn
+	c.writeMu.Unlock()
+	c.setConnected(true)
+
+	go c.readMessages(conn)
+
+	if serverHello {
+		c.sendHello()
+	}
+	c.connectionUp()
+
+	c.logger.Info("WebSocket connected")
+	return nil
+}
+
+// readMessages handles messages from the server until the connection drops
+func (c *WebSocketClient) readMessages(conn *websocket.Conn) {
+	for {
+		_, data, err := conn.ReadMessage()
+		if err != nil {
+			c.logger.Debug("WebSocket closed: %v", err)
+			break
+		}
+		c.handleMessage(data)
+	}
+
+	// Ignore connections already replaced by a reconnect or closed on purpose
+	c.writeMu.Lock()
+	current := c.conn == conn
+	c.writeMu.Unlock()
+	if !current || c.closed.Load() {
+		return
+	}
+	c.connectionLost()
+}
+
+// send writes one WebSocket message
+func (c *WebSocketClient) send(messageType int, data []byte) error {
+	c.writeMu.Lock()
+	defer c.writeMu.Unlock()
+
+	if c.conn == nil {
+		return fmt.Errorf("websocket not connected")
+	}
+	c.conn.SetWriteDeadline(time.Now().Add(wsWriteTimeout))
+	return c.conn.WriteMessage(messageType, data)
+}
+
+// hangUp closes the current WebSocket before a reconnect
+func (c *WebSocketClient) hangUp() {
+	c.writeMu.Lock()
+	defer c.writeMu.Unlock()
+	if c.conn != nil {
+		c.conn.Close()
+	}
+}
+
+// close closes the WebSocket, telling the server it was on purpose
+func (c *WebSocketClient) close() error {
+	c.writeMu.Lock()
+	defer c.writeMu.Unlock()
+	if c.conn != nil {
+		c.conn.WriteControl(websocket.CloseMessage,
+			websocket.FormatCloseMessage(websocket.CloseNormalClosure, ""), time.Now().Add(time.Second))
+		return c.conn.Close()
+	}
+	return nil
+}
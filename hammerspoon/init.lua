@@ -15,6 +15,17 @@ local config = {
     calibrateHotkey = {mods = {"ctrl", "alt"}, key = "c"},
 }
 
+-- Server error codes worth interrupting the user for (see shared/protocol/messages.go)
+-- Others, like resend_unavailable or not_started, are only logged
+local alertErrorCodes = {
+    invalid_settings = true,
+    pipeline_failed = true,
+    audio_format_unsupported = true,
+    model_busy = true,
+    inference_failed = true,
+    result_dropped = true,
+}
+
 -- State
 local state = {
     recording = false,
@@ -101,7 +112,24 @@ local function connectWebSocket()
 
         if event == "received" then
             local success, data = pcall(hs.json.decode, message)
-            if success and data.chunk then
+            if success and data.type == "error" then
+                -- Server-side failure (see error codes in shared/protocol/messages.go)
+                print("❌ Server error [" .. tostring(data.code) .. "]: " .. tostring(data.message))
+                if alertErrorCodes[data.code] then
+                    hs.alert.show("Transcription error: " .. tostring(data.message))
+                end
+            elseif success and data.chunk and data.final == false then
+                -- Interim hypothesis while still speaking; the final chunk supersedes it
+                print("💭 Partial: " .. data.chunk)
+            elseif success and data.chunk and data.redelivered then
+                -- A final that was missed earlier; typing it now would put it out of
+                -- order at the cursor, so only show it
+                print("🔁 Redelivered chunk #" .. tostring(data.sequence_id) .. ": " .. data.chunk)
+                hs.alert.show("Recovered missed text: " .. data.chunk)
+            elseif success and data.chunk then
+                if data.low_confidence then
+                    print(string.format("⚠️ Low confidence (%.2f): %s", data.confidence or 0, data.chunk))
+                end
                 print("📝 Received chunk: " .. data.chunk)
                 -- Insert text directly at cursor position with trailing space
                 hs.eventtap.keyStrokes(data.chunk .. " ")
@@ -3,12 +3,16 @@ package main
 import (
 	"errors"
 	"flag"
+	"fmt"
 	"os"
 	"os/signal"
 	"path/filepath"
+	"runtime"
 	"syscall"
+	"time"
 
 	"github.com/lucianHymer/streaming-transcription/server/internal/api"
+	"github.com/lucianHymer/streaming-transcription/server/internal/auth"
 	"github.com/lucianHymer/streaming-transcription/server/internal/config"
 	"github.com/lucianHymer/streaming-transcription/server/internal/transcription"
 	webrtcmgr "github.com/lucianHymer/streaming-transcription/server/internal/webrtc"
@@ -28,6 +32,9 @@ func getDefaultConfigPath() string {
 func main() {
 	defaultConfigPath := getDefaultConfigPath()
 	configPath := flag.String("config", defaultConfigPath, "Path to configuration file")
+	replayPattern := flag.String("replay", "", "Replay debug WAV chunks matching this glob with and without rolling context, then exit")
+	issueToken := flag.String("issue-token", "", "Print an auth token for this user, signed with auth.token_secret, then exit")
+	tokenTTL := flag.Duration("token-ttl", 30*24*time.Hour, "How long a token from -issue-token is valid")
 	flag.Parse()
 
 	// Load configuration
@@ -41,6 +48,15 @@ func main() {
 		}
 	}
 
+	if *issueToken != "" {
+		if cfg.Auth.TokenSecret == "" {
+			fmt.Fprintln(os.Stderr, "auth.token_secret is not set in", *configPath)
+			os.Exit(1)
+		}
+		fmt.Println(auth.IssueToken([]byte(cfg.Auth.TokenSecret), *issueToken, time.Now().Add(*tokenTTL)))
+		return
+	}
+
 	// Initialize logger
 	logLevel := logger.LevelInfo
 	if cfg.Server.LogLevel != "" {
@@ -72,42 +88,123 @@ func main() {
 		})
 	}
 
+	// Serve health checks while the model loads (not in replay mode)
+	inferenceTimeout := time.Duration(cfg.Health.InferenceTimeoutMs) * time.Millisecond
+	apiServer := api.New(log, api.Config{
+		BindAddress:      cfg.Server.BindAddress,
+		RNNoiseModelPath: cfg.NoiseSuppression.ModelPath,
+		InferenceTimeout: inferenceTimeout,
+		AdminToken:       cfg.Admin.Token,
+		Auth:             authConfig(cfg),
+		AllowedOrigins:   cfg.Auth.AllowedOrigins,
+		TLSCertFile:      cfg.TLS.CertFile,
+		TLSKeyFile:       cfg.TLS.KeyFile,
+	})
+	errChan := make(chan error, 1)
+	if *replayPattern == "" {
+		go func() {
+			if err := apiServer.Start(); err != nil {
+				errChan <- err
+			}
+		}()
+	}
+
 	// CRITICAL: Load Whisper model ONCE and share across all pipelines
 	// This prevents loading 1.6GB model for each connection
 	log.Info("Loading shared Whisper model (this may take a moment)...")
-	sharedWhisperModel, err := transcription.LoadSharedWhisperModel(cfg.Transcription.ModelPath, log)
+	sharedWhisperModel, err := transcription.LoadSharedWhisperModel(cfg.Transcription.ModelPath, transcription.SchedulerConfig{
+		Workers:       cfg.Transcription.MaxConcurrent,
+		MaxQueueDepth: cfg.Transcription.MaxQueueDepth,
+	}, log)
 	if err != nil {
 		log.Fatal("Failed to load Whisper model: %v", err)
 	}
-	log.Info("Whisper model loaded successfully (shared across all connections)")
+	log.Info("Whisper model loaded successfully (shared across all connections, %d concurrent transcriptions, %d threads each)", cfg.Transcription.MaxConcurrent, cfg.Transcription.Threads)
+	apiServer.ModelLoaded(sharedWhisperModel.Info())
+	if perWorker := config.ThreadsPerWorker(cfg.Transcription.MaxConcurrent); cfg.Transcription.Threads > perWorker {
+		log.Warn("threads=%d with max_concurrent=%d oversubscribes %d CPUs (%d threads per transcription fit)",
+			cfg.Transcription.Threads, cfg.Transcription.MaxConcurrent, runtime.NumCPU(), perWorker)
+	}
 
 	// Create WebRTC manager config with shared model
-	// Note: VAD settings now come from each client, not server config
 	managerConfig := webrtcmgr.ManagerConfig{
 		SharedWhisperModel: sharedWhisperModel,
 		WhisperConfig: transcription.WhisperConfig{
-			Language: cfg.Transcription.Language,
-			Threads:  uint(cfg.Transcription.Threads),
-			Logger:   log,
+			Language:       cfg.Transcription.Language,
+			Threads:        uint(cfg.Transcription.Threads),
+			InitialPrompt:  cfg.Transcription.InitialPrompt,
+			Temperature:    float32(cfg.Transcription.Temperature),
+			ContextTokens:  cfg.Transcription.ContextTokens,
+			ShortUtterance: time.Duration(cfg.Transcription.ShortUtteranceMs) * time.Millisecond,
+			Logger:         log,
 		},
-		RNNoiseModelPath: cfg.NoiseSuppression.ModelPath,
-		EnableDebugWAV:   cfg.Transcription.EnableDebugWAV,
+		WhisperLimits: transcription.WhisperLimits{
+			MaxThreads:       uint(cfg.SessionLimits.MaxThreads),
+			MaxTemperature:   float32(cfg.SessionLimits.MaxTemperature),
+			MaxPromptChars:   cfg.SessionLimits.MaxPromptChars,
+			AllowedLanguages: cfg.SessionLimits.AllowedLanguages,
+		},
+		VADDefaults: vadSettings(cfg.VAD.VADSettings),
+		VADLimits: transcription.VADLimits{
+			Min:    vadSettings(cfg.VAD.Min),
+			Max:    vadSettings(cfg.VAD.Max),
+			Reject: cfg.VAD.OutOfRange == "reject",
+		},
+		RNNoiseModelPath:  cfg.NoiseSuppression.ModelPath,
+		EnableDebugWAV:    cfg.Transcription.EnableDebugWAV,
+		MinConfidence:     cfg.Transcription.MinConfidence,
+		DropLowConfidence: cfg.Transcription.LowConfidenceAction == "drop",
+		Filter: transcription.FilterConfig{
+			Enabled:            cfg.HallucinationFilter.Enabled,
+			StripNonSpeechTags: cfg.HallucinationFilter.StripNonSpeechTags,
+			StockPhrases:       cfg.HallucinationFilter.StockPhrases,
+			MaxRepeats:         cfg.HallucinationFilter.MaxRepeats,
+			MaxWordsPerSecond:  cfg.HallucinationFilter.MaxWordsPerSecond,
+		},
+		Outbox: transcription.OutboxConfig{
+			MemorySize:  cfg.Delivery.MemoryQueueSize,
+			SpillDir:    cfg.Delivery.SpillDir,
+			HistorySize: cfg.Delivery.HistorySize,
+		},
+		ResumeGracePeriod: time.Duration(cfg.Delivery.ResumeGraceMs) * time.Millisecond,
+	}
+	// Replay mode: compare transcription of saved chunks and exit
+	if *replayPattern != "" {
+		if err := runReplay(*replayPattern, sharedWhisperModel, managerConfig.WhisperConfig); err != nil {
+			log.Fatal("Replay failed: %v", err)
+		}
+		return
+	}
+
+	if cfg.Transcription.PartialIntervalMs > 0 {
+		managerConfig.PartialInterval = time.Duration(cfg.Transcription.PartialIntervalMs) * time.Millisecond
+		managerConfig.PartialMax = time.Duration(cfg.Transcription.PartialMaxMs) * time.Millisecond
+		log.Info("Partial transcripts enabled every %dms of new speech (chunks up to %dms)",
+			cfg.Transcription.PartialIntervalMs, cfg.Transcription.PartialMaxMs)
+	} else {
+		log.Info("Partial transcripts disabled")
 	}
 
 	// Create WebRTC manager (no global pipeline - each peer creates their own)
 	webrtcManager := webrtcmgr.New(log, iceServers, managerConfig)
 	log.Info("WebRTC manager initialized with %d ICE servers", len(iceServers))
 
-	// Create API server
-	apiServer := api.New(cfg.Server.BindAddress, log, webrtcManager, cfg.NoiseSuppression.ModelPath)
+	apiServer.Attach(webrtcManager)
 
-	// Start server in a goroutine
-	errChan := make(chan error, 1)
-	go func() {
-		if err := apiServer.Start(); err != nil {
-			errChan <- err
+	// Check the model and noise suppression work before accepting clients
+	if cfg.Health.SelfTest {
+		log.Info("Running startup self-test...")
+		result := transcription.SelfTest(sharedWhisperModel, managerConfig.WhisperConfig, cfg.NoiseSuppression.ModelPath, inferenceTimeout)
+		if result.Passed {
+			log.Info("Self-test passed in %dms (%.2fx real time, noise suppression active: %v)",
+				result.Duration.Milliseconds(), result.RealtimeFactor, result.NoiseSuppressionActive)
+		} else {
+			log.Error("Self-test failed, not accepting clients: %s", result.Error)
 		}
-	}()
+		apiServer.SelfTestDone(&result)
+	} else {
+		apiServer.SelfTestDone(nil)
+	}
 
 	// Wait for interrupt signal
 	sigChan := make(chan os.Signal, 1)
@@ -121,7 +218,32 @@ func main() {
 		if err := apiServer.Stop(); err != nil {
 			log.Error("Error stopping server: %v", err)
 		}
+		sharedWhisperModel.Close()
 	}
 
 	log.Info("Server stopped")
 }
+
+// vadSettings converts VAD settings from the config file
+func vadSettings(vad config.VADSettings) transcription.VADSettings {
+	return transcription.VADSettings{
+		EnergyThreshold:        vad.EnergyThreshold,
+		SilenceThreshold:       time.Duration(vad.SilenceThresholdMs) * time.Millisecond,
+		MinChunkDuration:       time.Duration(vad.MinChunkDurationMs) * time.Millisecond,
+		MaxChunkDuration:       time.Duration(vad.MaxChunkDurationMs) * time.Millisecond,
+		SpeechDensityThreshold: vad.SpeechDensityThreshold,
+	}
+}
+
+// authConfig converts the streaming client credentials from the config file
+func authConfig(cfg *config.Config) auth.Config {
+	keys := make([]auth.APIKey, 0, len(cfg.Auth.APIKeys))
+	for _, key := range cfg.Auth.APIKeys {
+		keys = append(keys, auth.APIKey{User: key.User, Key: key.Key})
+	}
+	return auth.Config{
+		APIKeys:         keys,
+		TokenSecret:     cfg.Auth.TokenSecret,
+		MaxPeersPerUser: cfg.Auth.MaxPeersPerUser,
+	}
+}
@@ -0,0 +1,82 @@
+package main
+
+import (
+	"fmt"
+	"path/filepath"
+
+	"github.com/lucianHymer/streaming-transcription/server/internal/transcription"
+)
+
+// defaultReplayContextTokens is used when the config leaves rolling context disabled
+const defaultReplayContextTokens = 128
+
+// runReplay transcribes saved debug WAV chunks in order, once cold and once with
+// rolling context, and prints both so the effect of the context prompt can be compared
+func runReplay(pattern string, model *transcription.SharedWhisperModel, whisperConfig transcription.WhisperConfig) error {
+	files, err := filepath.Glob(pattern)
+	if err != nil {
+		return fmt.Errorf("invalid replay pattern: %w", err)
+	}
+	if len(files) == 0 {
+		return fmt.Errorf("no files match %q", pattern)
+	}
+
+	coldConfig := whisperConfig
+	coldConfig.ContextTokens = 0
+	cold, err := transcription.NewWhisperTranscriberShared(model, coldConfig)
+	if err != nil {
+		return err
+	}
+	defer cold.Close()
+
+	rollingConfig := whisperConfig
+	if rollingConfig.ContextTokens <= 0 {
+		rollingConfig.ContextTokens = defaultReplayContextTokens
+	}
+	rolling, err := transcription.NewWhisperTranscriberShared(model, rollingConfig)
+	if err != nil {
+		return err
+	}
+	defer rolling.Close()
+
+	fmt.Printf("Replaying %d chunks (rolling context: %d tokens)\n\n", len(files), rollingConfig.ContextTokens)
+
+	changed := 0
+	for _, file := range files {
+		samples, err := transcription.LoadWAV(file)
+		if err != nil {
+			fmt.Printf("⚠️  %s: %v\n\n", file, err)
+			continue
+		}
+
+		floatSamples := make([]float32, len(samples))
+		for i, sample := range samples {
+			floatSamples[i] = float32(sample) / 32768.0
+		}
+
+		coldOutput, err := cold.Transcribe(floatSamples)
+		if err != nil {
+			fmt.Printf("⚠️  %s: %v\n\n", file, err)
+			continue
+		}
+		rollingOutput, err := rolling.Transcribe(floatSamples)
+		if err != nil {
+			fmt.Printf("⚠️  %s: %v\n\n", file, err)
+			continue
+		}
+		rolling.AppendContext(rollingOutput.Text)
+
+		marker := " "
+		if coldOutput.Text != rollingOutput.Text {
+			marker = "*"
+			changed++
+		}
+
+		fmt.Printf("%s %s (%.1fs)\n", marker, filepath.Base(file), float64(len(samples))/16000.0)
+		fmt.Printf("    cold    [%.2f] %s\n", coldOutput.Confidence, coldOutput.Text)
+		fmt.Printf("    context [%.2f] %s\n\n", rollingOutput.Confidence, rollingOutput.Text)
+	}
+
+	fmt.Printf("%d of %d chunks changed with rolling context\n", changed, len(files))
+	return nil
+}
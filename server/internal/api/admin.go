@@ -0,0 +1,206 @@
+package api
+
+import (
+	"crypto/subtle"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/lucianHymer/streaming-transcription/server/internal/transcription"
+	"github.com/lucianHymer/streaming-transcription/server/internal/webrtc"
+	"github.com/lucianHymer/streaming-transcription/shared/protocol"
+)
+
+const (
+	// defaultTranscriptLimit is the number of recent transcripts returned when no limit is given
+	defaultTranscriptLimit = 20
+	// maxTranscriptLimit caps the limit a request may ask for
+	maxTranscriptLimit = 500
+)
+
+// registerAdmin adds the admin API routes, all behind the admin token
+func (s *Server) registerAdmin(mux *http.ServeMux) {
+	mux.HandleFunc("GET /api/v1/admin/peers", s.requireAdmin(s.handleAdminPeers))
+	mux.HandleFunc("GET /api/v1/admin/peers/{id}", s.requireAdmin(s.handleAdminPeer))
+	mux.HandleFunc("GET /api/v1/admin/peers/{id}/transcripts", s.requireAdmin(s.handleAdminTranscripts))
+	mux.HandleFunc("POST /api/v1/admin/peers/{id}/stop", s.requireAdmin(s.handleAdminStop))
+	mux.HandleFunc("POST /api/v1/admin/peers/{id}/disconnect", s.requireAdmin(s.handleAdminDisconnect))
+}
+
+// requireAdmin refuses requests without the admin bearer token, and requests
+// made before the WebRTC manager is attached
+func (s *Server) requireAdmin(next func(w http.ResponseWriter, r *http.Request, manager *webrtc.Manager)) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		token, ok := strings.CutPrefix(r.Header.Get("Authorization"), "Bearer ")
+		if !ok || subtle.ConstantTimeCompare([]byte(token), []byte(s.adminToken)) != 1 {
+			s.logger.Warn("Rejected admin request %s %s from %s", r.Method, r.URL.Path, r.RemoteAddr)
+			w.Header().Set("WWW-Authenticate", `Bearer realm="admin"`)
+			http.Error(w, "Unauthorized", http.StatusUnauthorized)
+			return
+		}
+
+		s.health.mu.RLock()
+		manager := s.webrtcManager
+		s.health.mu.RUnlock()
+		if manager == nil {
+			w.Header().Set("Retry-After", "5")
+			http.Error(w, "Server is starting up", http.StatusServiceUnavailable)
+			return
+		}
+		next(w, r, manager)
+	}
+}
+
+// handleAdminPeers lists the connected peers
+func (s *Server) handleAdminPeers(w http.ResponseWriter, r *http.Request, manager *webrtc.Manager) {
+	peers := manager.Peers()
+	list := make([]map[string]interface{}, 0, len(peers))
+	for _, peer := range peers {
+		list = append(list, peerJSON(peer))
+	}
+	writeJSON(w, http.StatusOK, map[string]interface{}{
+		"peers":     list,
+		"timestamp": time.Now().Unix(),
+	})
+}
+
+// handleAdminPeer reports one peer and its session
+func (s *Server) handleAdminPeer(w http.ResponseWriter, r *http.Request, manager *webrtc.Manager) {
+	peer, ok := manager.Peer(r.PathValue("id"))
+	if !ok {
+		http.Error(w, "Peer not found", http.StatusNotFound)
+		return
+	}
+	writeJSON(w, http.StatusOK, peerJSON(peer))
+}
+
+// handleAdminTranscripts returns the most recent final transcripts of a peer's session
+func (s *Server) handleAdminTranscripts(w http.ResponseWriter, r *http.Request, manager *webrtc.Manager) {
+	limit := defaultTranscriptLimit
+	if value := r.URL.Query().Get("limit"); value != "" {
+		n, err := strconv.Atoi(value)
+		if err != nil || n <= 0 {
+			http.Error(w, "limit must be a positive integer", http.StatusBadRequest)
+			return
+		}
+		limit = min(n, maxTranscriptLimit)
+	}
+
+	id := r.PathValue("id")
+	if _, ok := manager.Peer(id); !ok {
+		http.Error(w, "Peer not found", http.StatusNotFound)
+		return
+	}
+
+	transcripts := []map[string]interface{}{}
+	response := map[string]interface{}{"peer_id": id}
+	if pipeline := manager.GetPeerPipeline(id); pipeline != nil {
+		response["session_id"] = pipeline.SessionID()
+		for _, result := range pipeline.RecentTranscripts(limit) {
+			transcripts = append(transcripts, map[string]interface{}{
+				"chunk_index":    result.ChunkIndex,
+				"text":           result.Text,
+				"timestamp":      result.Timestamp,
+				"confidence":     result.Confidence,
+				"low_confidence": result.LowConfidence,
+			})
+		}
+	}
+	response["transcripts"] = transcripts
+	writeJSON(w, http.StatusOK, response)
+}
+
+// handleAdminStop stops a peer's transcription session, as if the client had sent control.stop
+// The peer stays connected and can start a new session
+func (s *Server) handleAdminStop(w http.ResponseWriter, r *http.Request, manager *webrtc.Manager) {
+	id := r.PathValue("id")
+	peer, ok := manager.GetPeerConnection(id)
+	if !ok {
+		http.Error(w, "Peer not found", http.StatusNotFound)
+		return
+	}
+	pipeline := manager.GetPeerPipeline(id)
+	if !manager.StopPeerSession(id) {
+		http.Error(w, "Peer has no active session", http.StatusConflict)
+		return
+	}
+
+	s.logger.Info("Admin stopped the session of peer %s", id)
+	s.sendErrorUnthrottled(id, peer, protocol.ErrorCodeSessionTerminated, "session stopped by an administrator", nil)
+	s.sendAudioStats(id, peer, pipeline)
+	writeJSON(w, http.StatusOK, map[string]interface{}{
+		"peer_id":    id,
+		"session_id": pipeline.SessionID(),
+		"status":     "stopped",
+	})
+}
+
+// handleAdminDisconnect closes a peer's session and connection; the session cannot be resumed
+func (s *Server) handleAdminDisconnect(w http.ResponseWriter, r *http.Request, manager *webrtc.Manager) {
+	id := r.PathValue("id")
+	peer, ok := manager.GetPeerConnection(id)
+	if !ok {
+		http.Error(w, "Peer not found", http.StatusNotFound)
+		return
+	}
+
+	// Tell the client why before the connection goes away, so it does not reconnect blindly
+	s.sendErrorUnthrottled(id, peer, protocol.ErrorCodeSessionTerminated, "disconnected by an administrator", nil)
+	if !manager.DisconnectPeer(id) {
+		http.Error(w, "Peer not found", http.StatusNotFound)
+		return
+	}
+
+	s.logger.Info("Admin disconnected peer %s", id)
+	writeJSON(w, http.StatusOK, map[string]interface{}{
+		"peer_id": id,
+		"status":  "disconnected",
+	})
+}
+
+// peerJSON converts a peer snapshot to its admin API representation
+func peerJSON(peer webrtc.PeerInfo) map[string]interface{} {
+	response := map[string]interface{}{
+		"id":              peer.ID,
+		"user":            peer.User,
+		"kind":            peer.Kind,
+		"remote_addr":     peer.RemoteAddr,
+		"connected_at":    peer.ConnectedAt.Unix(),
+		"connected_s":     int64(time.Since(peer.ConnectedAt).Seconds()),
+		"audio_transport": peer.AudioTransport,
+		"audio_bytes":     peer.AudioBytes,
+		"audio_chunks":    peer.AudioChunks,
+	}
+	if peer.Hello != nil {
+		response["hello"] = peer.Hello
+	}
+	if peer.Settings != nil {
+		response["settings"] = peer.Settings
+	}
+	if peer.Pipeline != nil {
+		response["session"] = sessionJSON(peer.SessionID, *peer.Pipeline)
+	}
+	return response
+}
+
+// sessionJSON converts a session's pipeline stats to their admin API representation
+func sessionJSON(sessionID uint64, stats transcription.PipelineStats) map[string]interface{} {
+	return map[string]interface{}{
+		"id":               sessionID,
+		"active":           stats.Active,
+		"chunks_cut":       stats.ChunkerStats.ChunksCut,
+		"speech_ms":        stats.ChunkerStats.TotalSpeech.Milliseconds(),
+		"buffered_ms":      stats.ChunkerStats.BufferDuration.Milliseconds(),
+		"transcribed":      stats.Transcribed,
+		"filtered":         stats.FilterStats.Dropped,
+		"results_queued":   stats.OutboxStats.Queued,
+		"results_spilled":  stats.OutboxStats.Spilled,
+		"results_dropped":  stats.OutboxStats.Dropped,
+		"chunks_received":  stats.SequenceStats.Received,
+		"chunks_missing":   stats.SequenceStats.Missing,
+		"chunks_duplicate": stats.SequenceStats.Duplicates,
+		"chunks_late":      stats.SequenceStats.Late,
+		"sequence_gaps":    stats.SequenceStats.Gaps,
+	}
+}
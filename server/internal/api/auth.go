@@ -0,0 +1,89 @@
+package api
+
+import (
+	"errors"
+	"net/http"
+	"net/url"
+	"strings"
+
+	"github.com/gorilla/websocket"
+	"github.com/lucianHymer/streaming-transcription/server/internal/auth"
+	"github.com/lucianHymer/streaming-transcription/server/internal/metrics"
+)
+
+// authenticate checks a streaming client's credentials and quota before its
+// connection is accepted, answering the request itself when they fail
+// The returned function releases the client's quota slot when the connection closes
+func (s *Server) authenticate(w http.ResponseWriter, r *http.Request) (auth.Identity, func(), bool) {
+	identity, err := s.auth.Authenticate(r)
+	if err != nil {
+		s.rejectAuth(w, r, err)
+		return auth.Identity{}, nil, false
+	}
+
+	release, err := s.auth.Acquire(identity)
+	if err != nil {
+		metrics.AuthRejected.With("quota").Inc()
+		s.logger.Warn("Refused connection from user %s (%s): %v", identity.User, r.RemoteAddr, err)
+		http.Error(w, "Too many connections", http.StatusTooManyRequests)
+		return auth.Identity{}, nil, false
+	}
+	return identity, release, true
+}
+
+// requireAuth refuses requests without valid credentials
+func (s *Server) requireAuth(next http.HandlerFunc) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if _, err := s.auth.Authenticate(r); err != nil {
+			s.rejectAuth(w, r, err)
+			return
+		}
+		next(w, r)
+	}
+}
+
+// rejectAuth answers a request whose credentials failed with 401
+func (s *Server) rejectAuth(w http.ResponseWriter, r *http.Request, err error) {
+	reason := "invalid"
+	switch {
+	case errors.Is(err, auth.ErrMissingCredentials):
+		reason = "missing"
+	case errors.Is(err, auth.ErrTokenExpired):
+		reason = "expired"
+	}
+	metrics.AuthRejected.With(reason).Inc()
+	s.logger.Warn("Rejected %s %s from %s: %v", r.Method, r.URL.Path, r.RemoteAddr, err)
+
+	w.Header().Set("WWW-Authenticate", `Bearer realm="transcription"`)
+	http.Error(w, "Unauthorized: "+err.Error(), http.StatusUnauthorized)
+}
+
+// checkOrigin accepts WebSocket upgrades from the configured origins
+// Without a list only same-origin browser requests are accepted; clients that
+// send no Origin (like ours) are always accepted
+func (s *Server) checkOrigin(r *http.Request) bool {
+	origin := r.Header.Get("Origin")
+	if origin == "" {
+		return true
+	}
+	if len(s.allowedOrigins) == 0 {
+		u, err := url.Parse(origin)
+		return err == nil && strings.EqualFold(u.Host, r.Host)
+	}
+	for _, allowed := range s.allowedOrigins {
+		if allowed == "*" || strings.EqualFold(allowed, origin) {
+			return true
+		}
+	}
+	s.logger.Warn("Rejected WebSocket from origin %s (%s)", origin, r.RemoteAddr)
+	return false
+}
+
+// newUpgrader creates the WebSocket upgrader for streaming clients
+func (s *Server) newUpgrader() websocket.Upgrader {
+	return websocket.Upgrader{
+		ReadBufferSize:  1024,
+		WriteBufferSize: 1024,
+		CheckOrigin:     s.checkOrigin,
+	}
+}
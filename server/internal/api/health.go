@@ -0,0 +1,212 @@
+package api
+
+import (
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/lucianHymer/streaming-transcription/server/internal/transcription"
+	"github.com/lucianHymer/streaming-transcription/server/internal/webrtc"
+)
+
+// Startup stages reported by the readiness endpoint
+const (
+	stageLoadingModel   = "loading_model"
+	stageSelfTest       = "self_test"
+	stageSelfTestFailed = "self_test_failed"
+	stageReady          = "ready"
+)
+
+// health tracks server startup for the liveness and readiness endpoints
+type health struct {
+	mu       sync.RWMutex
+	stage    string
+	model    *transcription.ModelInfo
+	selfTest *transcription.SelfTestResult
+	started  time.Time
+
+	inferenceTimeout time.Duration // A job running longer than this means inference is wedged (0 = never)
+}
+
+// ModelLoaded records the loaded Whisper model
+func (s *Server) ModelLoaded(info transcription.ModelInfo) {
+	s.health.mu.Lock()
+	defer s.health.mu.Unlock()
+	s.health.model = &info
+}
+
+// Attach hands the server its WebRTC manager once the model is loaded
+// Streaming endpoints still refuse clients until SelfTestDone
+func (s *Server) Attach(webrtcMgr *webrtc.Manager) {
+	registerMetrics(webrtcMgr)
+
+	s.health.mu.Lock()
+	defer s.health.mu.Unlock()
+	s.webrtcManager = webrtcMgr
+	s.health.stage = stageSelfTest
+}
+
+// SelfTestDone records the startup self-test (nil if it was skipped) and, if it
+// passed, starts accepting streaming clients
+func (s *Server) SelfTestDone(result *transcription.SelfTestResult) {
+	s.health.mu.Lock()
+	defer s.health.mu.Unlock()
+
+	s.health.selfTest = result
+	if result != nil && !result.Passed {
+		s.health.stage = stageSelfTestFailed
+		return
+	}
+	s.health.stage = stageReady
+}
+
+// ready reports whether streaming clients are accepted
+func (s *Server) ready() bool {
+	s.health.mu.RLock()
+	defer s.health.mu.RUnlock()
+	return s.health.stage == stageReady
+}
+
+// requireReady refuses requests until the server is ready to transcribe
+func (s *Server) requireReady(next http.HandlerFunc) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if !s.ready() {
+			w.Header().Set("Retry-After", "5")
+			http.Error(w, "Server is starting up", http.StatusServiceUnavailable)
+			return
+		}
+		next(w, r)
+	}
+}
+
+// wedged reports whether a transcription has been running for longer than the inference timeout
+func (h *health) wedged(stats transcription.SchedulerStats) bool {
+	return h.inferenceTimeout > 0 && stats.LongestJob > h.inferenceTimeout
+}
+
+// handleLive reports whether the process is working: it fails only when inference
+// is wedged, which a restart fixes (a loading model is alive but not ready)
+func (s *Server) handleLive(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	s.health.mu.RLock()
+	manager := s.webrtcManager
+	s.health.mu.RUnlock()
+
+	response := map[string]interface{}{
+		"status":    "alive",
+		"timestamp": time.Now().Unix(),
+	}
+	code := http.StatusOK
+	if manager != nil {
+		stats := manager.SchedulerStats()
+		response["longest_job_ms"] = stats.LongestJob.Milliseconds()
+		if s.health.wedged(stats) {
+			response["status"] = "wedged"
+			code = http.StatusServiceUnavailable
+		}
+	}
+
+	writeJSON(w, code, response)
+}
+
+// handleReady reports whether the server can take new sessions, with the model,
+// noise suppression, load and self-test details behind the answer
+func (s *Server) handleReady(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	s.health.mu.RLock()
+	stage := s.health.stage
+	model := s.health.model
+	selfTest := s.health.selfTest
+	manager := s.webrtcManager
+	s.health.mu.RUnlock()
+
+	status := stage
+	noiseSuppression := map[string]interface{}{
+		"build":  "passthrough",
+		"active": false,
+	}
+	if transcription.RNNoiseAvailable {
+		noiseSuppression["build"] = "rnnoise"
+		noiseSuppression["active"] = selfTest == nil || selfTest.NoiseSuppressionActive
+	}
+
+	response := map[string]interface{}{
+		"uptime_s":          int64(time.Since(s.health.started).Seconds()),
+		"noise_suppression": noiseSuppression,
+		"timestamp":         time.Now().Unix(),
+	}
+
+	if model != nil {
+		response["model"] = map[string]interface{}{
+			"path":         model.Path,
+			"type":         model.Type,
+			"size_bytes":   model.SizeBytes,
+			"multilingual": model.Multilingual,
+			"instances":    model.Instances,
+		}
+	}
+
+	if manager != nil {
+		stats := manager.SchedulerStats()
+		peers := manager.Stats()
+		response["load"] = map[string]interface{}{
+			"peers":           peers.Peers,
+			"pipelines":       peers.Pipelines,
+			"detached":        peers.Detached,
+			"workers":         stats.Workers,
+			"busy":            stats.Busy,
+			"queue_depth":     stats.QueueDepth,
+			"max_queue_depth": stats.MaxQueue,
+			"clients":         stats.Clients,
+			"started":         stats.Started,
+			"completed":       stats.Completed,
+			"rejected":        stats.Rejected,
+			"evicted":         stats.Evicted,
+			"avg_wait_ms":     stats.AvgWait.Milliseconds(),
+			"max_wait_ms":     stats.MaxWait.Milliseconds(),
+			"last_wait_ms":    stats.LastWait.Milliseconds(),
+			"longest_job_ms":  stats.LongestJob.Milliseconds(),
+		}
+
+		// Ready servers still turn clients away when inference is stuck or the queue is full
+		if stage == stageReady {
+			if s.health.wedged(stats) {
+				status = "wedged"
+			} else if stats.MaxQueue > 0 && stats.QueueDepth >= stats.MaxQueue {
+				status = "overloaded"
+			}
+		}
+	}
+
+	if selfTest != nil {
+		test := map[string]interface{}{
+			"passed":          selfTest.Passed,
+			"duration_ms":     selfTest.Duration.Milliseconds(),
+			"realtime_factor": selfTest.RealtimeFactor,
+			"text":            selfTest.Text,
+			"completed_at":    selfTest.CompletedAt.Unix(),
+		}
+		if selfTest.Error != "" {
+			test["error"] = selfTest.Error
+		}
+		response["self_test"] = test
+	} else if stage == stageReady {
+		response["self_test"] = map[string]interface{}{"skipped": true}
+	}
+
+	response["status"] = status
+	response["ready"] = status == stageReady
+	code := http.StatusOK
+	if status != stageReady {
+		code = http.StatusServiceUnavailable
+	}
+	writeJSON(w, code, response)
+}
@@ -1,47 +1,95 @@
 package api
 
 import (
+	"crypto/tls"
 	"encoding/json"
+	"errors"
 	"math"
 	"net/http"
+	"strings"
+	"sync"
 	"time"
 
 	"github.com/google/uuid"
 	"github.com/gorilla/websocket"
+	"github.com/lucianHymer/streaming-transcription/server/internal/auth"
+	"github.com/lucianHymer/streaming-transcription/server/internal/metrics"
 	"github.com/lucianHymer/streaming-transcription/server/internal/transcription"
 	"github.com/lucianHymer/streaming-transcription/server/internal/webrtc"
+	"github.com/lucianHymer/streaming-transcription/shared/audiocodec"
 	"github.com/lucianHymer/streaming-transcription/shared/logger"
 	"github.com/lucianHymer/streaming-transcription/shared/protocol"
 )
 
-var upgrader = websocket.Upgrader{
-	ReadBufferSize:  1024,
-	WriteBufferSize: 1024,
-	CheckOrigin: func(r *http.Request) bool {
-		// Allow all origins for now (can be restricted later)
-		return true
-	},
+// Config holds API server settings
+type Config struct {
+	BindAddress      string
+	RNNoiseModelPath string        // For calibration RNNoise processing
+	InferenceTimeout time.Duration // A transcription running longer than this means inference is wedged (0 = never)
+	AdminToken       string        // Bearer token for the admin API (empty = admin API disabled)
+	Auth             auth.Config   // Credentials streaming clients must present
+	AllowedOrigins   []string      // Browser origins allowed to open WebSockets (empty = same origin only)
+	TLSCertFile      string        // Serve HTTPS/WSS with this certificate (empty = plain HTTP)
+	TLSKeyFile       string
 }
 
 // Server handles HTTP and WebSocket requests
 type Server struct {
 	bindAddr         string
+	adminToken       string
+	tlsCertFile      string
+	tlsKeyFile       string
+	auth             *auth.Authenticator
+	allowedOrigins   []string
+	upgrader         websocket.Upgrader
 	baseLogger       *logger.Logger        // For creating new components
 	logger           *logger.ContextLogger // For API server logging
 	server           *http.Server
-	webrtcManager    *webrtc.Manager
-	rnnoiseModelPath string // For calibration RNNoise processing
+	webrtcManager    *webrtc.Manager // Set by Attach once the model is loaded (guarded by health.mu until then)
+	rnnoiseModelPath string          // For calibration RNNoise processing
+	health           health
+
+	// Last time each error code was sent to each peer (throttles per-chunk errors)
+	errorsSent   map[string]map[string]time.Time
+	errorsSentMu sync.Mutex
 }
 
+// errorThrottle is the minimum interval between repeats of the same error to a peer
+// (errors tied to specific sequence IDs are never throttled)
+const errorThrottle = 5 * time.Second
+
+// streamWriteTimeout bounds a write to a WebSocket streaming client
+const streamWriteTimeout = 10 * time.Second
+
+// errStreamNotOpen is returned for messages to a WebSocket peer before its upgrade completes
+var errStreamNotOpen = errors.New("WebSocket stream not open yet")
+
+// stopGracePeriod is how long after control.stop audio still in flight is dropped silently
+const stopGracePeriod = 2 * time.Second
+
 // New creates a new API server
-func New(bindAddr string, log *logger.Logger, webrtcMgr *webrtc.Manager, rnnoiseModelPath string) *Server {
-	return &Server{
-		bindAddr:         bindAddr,
+// It can start before the model is loaded; streaming clients are refused until
+// Attach and SelfTestDone, and the readiness endpoint reports the progress
+func New(log *logger.Logger, config Config) *Server {
+	s := &Server{
+		bindAddr:         config.BindAddress,
+		adminToken:       config.AdminToken,
+		tlsCertFile:      config.TLSCertFile,
+		tlsKeyFile:       config.TLSKeyFile,
+		auth:             auth.New(config.Auth),
+		allowedOrigins:   config.AllowedOrigins,
 		baseLogger:       log,
 		logger:           log.With("api"),
-		webrtcManager:    webrtcMgr,
-		rnnoiseModelPath: rnnoiseModelPath,
+		rnnoiseModelPath: config.RNNoiseModelPath,
+		errorsSent:       make(map[string]map[string]time.Time),
+		health: health{
+			stage:            stageLoadingModel,
+			started:          time.Now(),
+			inferenceTimeout: config.InferenceTimeout,
+		},
 	}
+	s.upgrader = s.newUpgrader()
+	return s
 }
 
 // Start starts the HTTP server
@@ -49,9 +97,21 @@ func (s *Server) Start() error {
 	mux := http.NewServeMux()
 
 	// Register handlers
-	mux.HandleFunc("/health", s.handleHealth)
-	mux.HandleFunc("/api/v1/stream/signal", s.handleSignaling)
-	mux.HandleFunc("/api/v1/analyze-audio", s.handleAnalyzeAudio)
+	mux.HandleFunc("/health", s.handleReady)
+	mux.HandleFunc("/health/live", s.handleLive)
+	mux.HandleFunc("/health/ready", s.handleReady)
+	mux.Handle("/metrics", metrics.Default.Handler())
+	mux.HandleFunc("/api/v1/stream/signal", s.requireReady(s.handleSignaling))
+	mux.HandleFunc("/api/v1/stream/ws", s.requireReady(s.handleStreamWebSocket))
+	mux.HandleFunc("/api/v1/analyze-audio", s.requireAuth(s.handleAnalyzeAudio))
+	if !s.auth.Enabled() {
+		s.logger.Warn("Authentication disabled: anyone who can reach %s can stream audio (configure auth in server.yaml)", s.bindAddr)
+	}
+	if s.adminToken != "" {
+		s.registerAdmin(mux)
+	} else {
+		s.logger.Info("Admin API disabled (no admin.token configured)")
+	}
 
 	s.server = &http.Server{
 		Addr:         s.bindAddr,
@@ -61,6 +121,19 @@ func (s *Server) Start() error {
 		IdleTimeout:  60 * time.Second,
 	}
 
+	if s.tlsCertFile != "" {
+		certs, err := newCertReloader(s.tlsCertFile, s.tlsKeyFile, s.logger)
+		if err != nil {
+			return err
+		}
+		s.server.TLSConfig = &tls.Config{
+			MinVersion:     tls.VersionTLS12,
+			GetCertificate: certs.GetCertificate,
+		}
+		s.logger.Info("Starting HTTPS server on %s (certificate %s)", s.bindAddr, s.tlsCertFile)
+		return s.server.ListenAndServeTLS("", "")
+	}
+
 	s.logger.Info("Starting HTTP server on %s", s.bindAddr)
 	return s.server.ListenAndServe()
 }
@@ -73,26 +146,42 @@ func (s *Server) Stop() error {
 	return nil
 }
 
-// handleHealth handles health check requests
-func (s *Server) handleHealth(w http.ResponseWriter, r *http.Request) {
-	if r.Method != http.MethodGet {
-		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
-		return
-	}
+// registerMetrics registers gauges of the manager's current state
+func registerMetrics(manager *webrtc.Manager) {
+	metrics.Default.GaugeFunc("transcription_active_peers", "Connected peers (WebRTC and WebSocket).",
+		func() float64 { return float64(manager.Stats().Peers) })
+	metrics.Default.GaugeFunc("transcription_active_pipelines", "Running transcription pipelines, including sessions waiting to be resumed.",
+		func() float64 { return float64(manager.Stats().Pipelines) })
+	metrics.Default.GaugeFunc("transcription_detached_sessions", "Sessions of disconnected peers waiting to be resumed.",
+		func() float64 { return float64(manager.Stats().Detached) })
+	metrics.Default.GaugeFunc("transcription_queue_depth", "Transcription jobs waiting for a Whisper worker.",
+		func() float64 { return float64(manager.SchedulerStats().QueueDepth) })
+	metrics.Default.GaugeFunc("transcription_workers_busy", "Whisper workers currently transcribing.",
+		func() float64 { return float64(manager.SchedulerStats().Busy) })
+	metrics.Default.CounterFunc("transcription_jobs_rejected_total", "Transcription jobs rejected because the queue was full.",
+		func() float64 { return float64(manager.SchedulerStats().Rejected) })
+}
 
-	response := map[string]interface{}{
-		"status":    "ok",
-		"timestamp": time.Now().Unix(),
+// serverFeatures returns the features announced to clients in the signaling answer
+func serverFeatures() []string {
+	features := []string{protocol.FeatureBinaryAudio, protocol.FeatureHello}
+	if audiocodec.Available {
+		features = append(features, protocol.FeatureOpusAudio)
 	}
-
-	w.Header().Set("Content-Type", "application/json")
-	json.NewEncoder(w).Encode(response)
+	return features
 }
 
 // handleSignaling handles WebRTC signaling over WebSocket
 func (s *Server) handleSignaling(w http.ResponseWriter, r *http.Request) {
+	// Credentials are checked before the upgrade, so rejected clients get a plain HTTP error
+	identity, release, ok := s.authenticate(w, r)
+	if !ok {
+		return
+	}
+	defer release()
+
 	// Upgrade to WebSocket
-	conn, err := upgrader.Upgrade(w, r, nil)
+	conn, err := s.upgrader.Upgrade(w, r, nil)
 	if err != nil {
 		s.logger.Error("Failed to upgrade to WebSocket: %v", err)
 		return
@@ -101,7 +190,7 @@ func (s *Server) handleSignaling(w http.ResponseWriter, r *http.Request) {
 
 	// Generate peer ID
 	peerID := uuid.New().String()
-	s.logger.Info("New signaling connection from peer %s", peerID)
+	s.logger.Info("New signaling connection from peer %s (user %s)", peerID, identity.User)
 
 	// Declare peer variable first for closure
 	var peer *webrtc.PeerConnection
@@ -109,12 +198,23 @@ func (s *Server) handleSignaling(w http.ResponseWriter, r *http.Request) {
 	// Create peer connection
 	peer, err = s.webrtcManager.CreatePeerConnection(peerID, func(msg *protocol.Message) {
 		s.handleDataChannelMessage(peerID, peer, msg)
+	}, func(chunk protocol.AudioChunkData, timestamp int64) {
+		s.handleAudioChunk(peerID, peer, chunk, timestamp)
 	})
 	if err != nil {
 		s.logger.Error("Failed to create peer connection: %v", err)
 		return
 	}
 	defer s.webrtcManager.RemovePeerConnection(peerID)
+	defer s.forgetErrors(peerID)
+	peer.SetRemoteAddr(r.RemoteAddr)
+	peer.SetUser(identity.User)
+
+	// The peer lives as long as the signaling socket; an admin disconnect removes the peer first
+	go func() {
+		<-peer.Done()
+		conn.Close()
+	}()
 
 	// Set up ICE candidate handler
 	peer.GatherICECandidates(func(candidateJSON string) {
@@ -139,6 +239,19 @@ func (s *Server) handleSignaling(w http.ResponseWriter, r *http.Request) {
 
 		switch msg.Type {
 		case "offer":
+			// A reconnecting client presents the token of its previous connection
+			if pipeline := s.webrtcManager.ResumeSession(peerID, msg.ResumeToken); pipeline != nil {
+				go s.sendTranscriptionResults(peerID, peer, pipeline)
+			} else if msg.ResumeToken != "" {
+				s.logger.Info("Peer %s could not resume its session (expired or unknown token)", peerID)
+			}
+
+			// Choose the audio transport before the answer, so the track is read as soon as it arrives
+			transport := peer.NegotiateTransport(msg.Transport)
+			if msg.Transport != "" && msg.Transport != transport {
+				s.logger.Warn("Peer %s requested %s transport, using %s", peerID, msg.Transport, transport)
+			}
+
 			// Client sent an offer, create an answer
 			answer, err := peer.CreateAnswer(string(msg.Data))
 			if err != nil {
@@ -147,8 +260,11 @@ func (s *Server) handleSignaling(w http.ResponseWriter, r *http.Request) {
 			}
 
 			response := protocol.SignalingMessage{
-				Type: "answer",
-				Data: json.RawMessage(answer),
+				Type:        "answer",
+				Data:        json.RawMessage(answer),
+				Features:    serverFeatures(),
+				Transport:   transport,
+				ResumeToken: peer.ResumeToken(),
 			}
 			if err := conn.WriteJSON(response); err != nil {
 				s.logger.Error("Failed to send answer: %v", err)
@@ -168,6 +284,91 @@ func (s *Server) handleSignaling(w http.ResponseWriter, r *http.Request) {
 	s.logger.Info("Signaling connection closed for peer %s", peerID)
 }
 
+// handleStreamWebSocket streams over a plain WebSocket for clients that cannot use WebRTC
+// It carries the same messages as the DataChannel (JSON text, binary audio frames)
+// and shares the peer and pipeline lifecycle of WebRTC peers
+func (s *Server) handleStreamWebSocket(w http.ResponseWriter, r *http.Request) {
+	identity, release, ok := s.authenticate(w, r)
+	if !ok {
+		return
+	}
+	defer release()
+
+	peerID := uuid.New().String()
+	s.logger.Info("New WebSocket stream from peer %s (user %s, %s)", peerID, identity.User, r.RemoteAddr)
+
+	// The peer exists before the upgrade so its resume token can go in the response;
+	// sends fail until the socket is set (e.g. an admin stop in the meantime)
+	// Results and errors are sent from other goroutines, and the socket allows one writer
+	var conn *websocket.Conn
+	var writeMu sync.Mutex
+	send := func(data []byte) error {
+		writeMu.Lock()
+		defer writeMu.Unlock()
+		if conn == nil {
+			return errStreamNotOpen
+		}
+		conn.SetWriteDeadline(time.Now().Add(streamWriteTimeout))
+		return conn.WriteMessage(websocket.TextMessage, data)
+	}
+
+	var peer *webrtc.PeerConnection
+	peer, err := s.webrtcManager.CreateWebSocketPeer(peerID, send, func(msg *protocol.Message) {
+		s.handleDataChannelMessage(peerID, peer, msg)
+	}, func(chunk protocol.AudioChunkData, timestamp int64) {
+		s.handleAudioChunk(peerID, peer, chunk, timestamp)
+	})
+	if err != nil {
+		s.logger.Error("Failed to create WebSocket peer: %v", err)
+		http.Error(w, "Failed to create peer", http.StatusInternalServerError)
+		return
+	}
+	defer s.webrtcManager.RemovePeerConnection(peerID)
+	defer s.forgetErrors(peerID)
+	peer.SetRemoteAddr(r.RemoteAddr)
+	peer.SetUser(identity.User)
+
+	// A reconnecting client presents the token of its previous connection
+	requested := r.Header.Get(protocol.ResumeTokenHeader)
+	resumed := s.webrtcManager.ResumeSession(peerID, requested)
+	if resumed == nil && requested != "" {
+		s.logger.Info("Peer %s could not resume its session (expired or unknown token)", peerID)
+	}
+
+	header := http.Header{}
+	header.Set(protocol.FeaturesHeader, strings.Join(serverFeatures(), ","))
+	header.Set(protocol.ResumeTokenHeader, peer.ResumeToken())
+
+	upgraded, err := s.upgrader.Upgrade(w, r, header)
+	if err != nil {
+		s.logger.Error("Failed to upgrade to WebSocket: %v", err)
+		return
+	}
+	defer upgraded.Close()
+	writeMu.Lock()
+	conn = upgraded
+	writeMu.Unlock()
+
+	// An admin disconnect removes the peer; closing the socket ends the read loop
+	go func() {
+		<-peer.Done()
+		upgraded.Close()
+	}()
+
+	if resumed != nil {
+		go s.sendTranscriptionResults(peerID, peer, resumed)
+	}
+
+	for {
+		_, data, err := upgraded.ReadMessage()
+		if err != nil {
+			s.logger.Debug("WebSocket stream closed (peer %s): %v", peerID, err)
+			return
+		}
+		peer.Receive(data)
+	}
+}
+
 // handleDataChannelMessage handles messages received over the DataChannel
 func (s *Server) handleDataChannelMessage(peerID string, peer *webrtc.PeerConnection, msg *protocol.Message) {
 	switch msg.Type {
@@ -185,37 +386,48 @@ func (s *Server) handleDataChannelMessage(peerID string, peer *webrtc.PeerConnec
 			s.logger.Debug("Sent pong to peer %s", peerID)
 		}
 
-	case protocol.MessageTypeAudioChunk:
-		// Parse audio chunk
-		var audioData protocol.AudioChunkData
-		if err := json.Unmarshal(msg.Data, &audioData); err != nil {
-			s.logger.Error("Failed to unmarshal audio chunk: %v", err)
+	case protocol.MessageTypeControlHello:
+		var hello protocol.HelloData
+		if err := json.Unmarshal(msg.Data, &hello); err != nil {
+			s.logger.Error("Failed to unmarshal hello: %v", err)
+			s.sendError(peerID, peer, protocol.ErrorCodeInvalidMessage, "invalid control.hello data: "+err.Error(), nil)
 			return
 		}
+		peer.SetHello(&hello)
+
+		// Both sides speak the older of the two versions
+		welcome := s.webrtcManager.Welcome()
+		welcome.ProtocolVersion = min(hello.ProtocolVersion, protocol.ProtocolVersion)
+		for _, feature := range serverFeatures() {
+			if feature != protocol.FeatureHello {
+				welcome.Features = append(welcome.Features, feature)
+			}
+		}
+		s.logger.Info("Peer %s speaks protocol v%d (client v%d), encodings %v, features %v",
+			peerID, welcome.ProtocolVersion, hello.ProtocolVersion, hello.AudioEncodings, hello.Features)
 
-		s.logger.Debug("Received audio chunk: seq=%d, size=%d bytes",
-			audioData.SequenceID, len(audioData.Data))
-
-		// Debug first chunk to see what we actually got
-		if audioData.SequenceID == 0 && len(audioData.Data) >= 20 {
-			s.logger.Debug("First chunk, first 20 bytes (hex): %x", audioData.Data[:20])
-			s.logger.Debug("First chunk, first 5 samples (int16): %d %d %d %d %d",
-				int16(audioData.Data[0])|int16(audioData.Data[1])<<8,
-				int16(audioData.Data[2])|int16(audioData.Data[3])<<8,
-				int16(audioData.Data[4])|int16(audioData.Data[5])<<8,
-				int16(audioData.Data[6])|int16(audioData.Data[7])<<8,
-				int16(audioData.Data[8])|int16(audioData.Data[9])<<8)
+		welcomeJSON, err := json.Marshal(welcome)
+		if err != nil {
+			s.logger.Error("Failed to marshal welcome: %v", err)
+			return
+		}
+		if err := peer.SendMessage(&protocol.Message{
+			Type:      protocol.MessageTypeControlWelcome,
+			Timestamp: time.Now().UnixMilli(),
+			Data:      welcomeJSON,
+		}); err != nil {
+			s.logger.Error("Failed to send welcome to peer %s: %v", peerID, err)
 		}
 
-		// Pass to this peer's transcription pipeline
-		pipeline := s.webrtcManager.GetPeerPipeline(peerID)
-		if pipeline != nil && pipeline.IsActive() {
-			if err := pipeline.ProcessChunk(audioData.Data, msg.Timestamp); err != nil {
-				s.logger.Error("Failed to process audio chunk: %v", err)
-			}
-		} else {
-			s.logger.Debug("No active pipeline for peer %s, dropping audio chunk", peerID)
+	case protocol.MessageTypeAudioChunk:
+		// Parse audio chunk (clients without binary framing send it as JSON)
+		var audioData protocol.AudioChunkData
+		if err := json.Unmarshal(msg.Data, &audioData); err != nil {
+			s.logger.Error("Failed to unmarshal audio chunk: %v", err)
+			s.sendError(peerID, peer, protocol.ErrorCodeInvalidMessage, "invalid audio chunk: "+err.Error(), nil)
+			return
 		}
+		s.handleAudioChunk(peerID, peer, audioData, msg.Timestamp)
 
 	case protocol.MessageTypeControlStart:
 		s.logger.Info("Received start command from peer %s", peerID)
@@ -225,6 +437,7 @@ func (s *Server) handleDataChannelMessage(peerID string, peer *webrtc.PeerConnec
 		if msg.Data != nil {
 			if err := json.Unmarshal(msg.Data, &controlData); err != nil {
 				s.logger.Error("Failed to parse control start data: %v", err)
+				s.sendError(peerID, peer, protocol.ErrorCodeInvalidMessage, "invalid control.start data: "+err.Error(), nil)
 				return
 			}
 			s.logger.Info("Client settings: VAD=%.0f, Silence=%dms, Min=%dms, Max=%dms, Density=%.1f%%",
@@ -233,35 +446,71 @@ func (s *Server) handleDataChannelMessage(peerID string, peer *webrtc.PeerConnec
 				controlData.MinChunkDurationMs,
 				controlData.MaxChunkDurationMs,
 				controlData.SpeechDensityThreshold*100)
+			s.logger.Info("Client Whisper settings: language=%q, translate=%v, threads=%d, temperature=%.2f, prompt=%d chars, vocabulary=%d terms",
+				controlData.Language,
+				controlData.Translate,
+				controlData.Threads,
+				controlData.Temperature,
+				len(controlData.InitialPrompt),
+				len(controlData.Vocabulary))
 		} else {
-			s.logger.Warn("No settings provided in control.start, using defaults")
-			// Use default values if not provided
-			controlData = protocol.ControlStartData{
-				VADEnergyThreshold:     500.0,
-				SilenceThresholdMs:     1000,
-				MinChunkDurationMs:     500,
-				MaxChunkDurationMs:     30000,
-				SpeechDensityThreshold: 0.6,
-			}
+			s.logger.Warn("No settings provided in control.start, using server defaults")
 		}
 
-		// Create pipeline with client settings
-		pipeline, err := s.webrtcManager.CreatePipelineForPeer(peerID, &controlData)
+		// Create pipeline with client settings (unset ones fall back to the server's)
+		pipeline, started, err := s.webrtcManager.CreatePipelineForPeer(peerID, &controlData)
 		if err != nil {
 			s.logger.Error("Failed to create pipeline: %v", err)
+			code := protocol.ErrorCodePipelineFailed
+			if errors.Is(err, webrtc.ErrInvalidSettings) {
+				code = protocol.ErrorCodeInvalidSettings
+			}
+			s.sendError(peerID, peer, code, err.Error(), nil)
 			return
 		}
 
 		// Start the pipeline
 		if err := pipeline.Start(); err != nil {
 			s.logger.Error("Failed to start pipeline: %v", err)
+			s.sendError(peerID, peer, protocol.ErrorCodePipelineFailed, "failed to start pipeline: "+err.Error(), nil)
 		} else {
 			s.logger.Info("Transcription pipeline started for peer %s", peerID)
 
+			// Tell the client which settings the session actually uses
+			if startedJSON, err := json.Marshal(started); err != nil {
+				s.logger.Error("Failed to marshal session settings: %v", err)
+			} else if err := peer.SendMessage(&protocol.Message{
+				Type:      protocol.MessageTypeControlStarted,
+				Timestamp: time.Now().UnixMilli(),
+				Data:      startedJSON,
+			}); err != nil {
+				s.logger.Error("Failed to send session settings to peer %s: %v", peerID, err)
+			}
+
 			// Start result sender goroutine
 			go s.sendTranscriptionResults(peerID, peer, pipeline)
 		}
 
+	case protocol.MessageTypeTranscriptResend:
+		var resendData protocol.TranscriptResendData
+		if err := json.Unmarshal(msg.Data, &resendData); err != nil {
+			s.logger.Error("Failed to unmarshal resend request: %v", err)
+			s.sendError(peerID, peer, protocol.ErrorCodeInvalidMessage, "invalid transcript.resend data: "+err.Error(), nil)
+			return
+		}
+		s.logger.Info("Peer %s requested re-delivery of %d transcripts", peerID, len(resendData.SequenceIDs))
+
+		// Only the current session's transcripts can be re-delivered
+		missing := resendData.SequenceIDs
+		pipeline := s.webrtcManager.GetPeerPipeline(peerID)
+		if pipeline != nil && (resendData.SessionID == 0 || resendData.SessionID == pipeline.SessionID()) {
+			missing = pipeline.Redeliver(resendData.SequenceIDs)
+		}
+		if len(missing) > 0 {
+			s.sendError(peerID, peer, protocol.ErrorCodeResendUnavailable,
+				"transcripts are no longer available for re-delivery", missing)
+		}
+
 	case protocol.MessageTypeControlStop:
 		s.logger.Info("Received stop command from peer %s", peerID)
 
@@ -270,13 +519,82 @@ func (s *Server) handleDataChannelMessage(peerID string, peer *webrtc.PeerConnec
 		if pipeline != nil {
 			if err := pipeline.Stop(); err != nil {
 				s.logger.Error("Failed to stop pipeline: %v", err)
+				s.sendError(peerID, peer, protocol.ErrorCodePipelineFailed, "failed to stop pipeline: "+err.Error(), nil)
 			} else {
 				s.logger.Info("Transcription pipeline stopped for peer %s", peerID)
+				s.sendAudioStats(peerID, peer, pipeline)
 			}
 		}
 
 	default:
 		s.logger.Warn("Unknown message type: %s", msg.Type)
+		s.sendError(peerID, peer, protocol.ErrorCodeInvalidMessage, "unknown message type: "+string(msg.Type), nil)
+	}
+}
+
+// handleAudioChunk validates an audio chunk and passes it to the peer's pipeline
+// Chunks arrive either as binary audio frames or as JSON audio.chunk messages
+func (s *Server) handleAudioChunk(peerID string, peer *webrtc.PeerConnection, audioData protocol.AudioChunkData, timestamp int64) {
+	encodedSize := len(audioData.Data)
+	peer.RecordAudio(encodedSize)
+	metrics.AudioBytesReceived.With(peerID).Add(float64(encodedSize))
+	audioData, err := peer.DecodeAudio(audioData)
+	if err != nil {
+		s.logger.Debug("Failed to decode %s audio from peer %s: %v", audioData.Encoding, peerID, err)
+		s.sendError(peerID, peer, protocol.ErrorCodeAudioFormatUnsupported, "failed to decode audio: "+err.Error(), nil)
+		return
+	}
+
+	// The pipeline downmixes and resamples to 16kHz mono, within limits
+	if err := transcription.CheckInputFormat(audioData.SampleRate, audioData.Channels); err != nil {
+		s.logger.Debug("Unsupported audio from peer %s: %v", peerID, err)
+		s.sendError(peerID, peer, protocol.ErrorCodeAudioFormatUnsupported, err.Error(), nil)
+		return
+	}
+
+	s.logger.Debug("Received audio chunk: seq=%d, size=%d bytes (%d on the wire)",
+		audioData.SequenceID, len(audioData.Data), encodedSize)
+
+	// Debug first chunk to see what we actually got
+	if audioData.SequenceID == 0 && len(audioData.Data) >= 20 {
+		s.logger.Debug("First chunk, first 20 bytes (hex): %x", audioData.Data[:20])
+		s.logger.Debug("First chunk, first 5 samples (int16): %d %d %d %d %d",
+			int16(audioData.Data[0])|int16(audioData.Data[1])<<8,
+			int16(audioData.Data[2])|int16(audioData.Data[3])<<8,
+			int16(audioData.Data[4])|int16(audioData.Data[5])<<8,
+			int16(audioData.Data[6])|int16(audioData.Data[7])<<8,
+			int16(audioData.Data[8])|int16(audioData.Data[9])<<8)
+	}
+
+	// Pass to this peer's transcription pipeline
+	pipeline := s.webrtcManager.GetPeerPipeline(peerID)
+	if pipeline != nil && pipeline.IsActive() {
+		check := transcription.SequenceInOrder
+		if peer.TrackTransport() {
+			// Track frames are numbered per track, not by the client, and the chunks
+			// it replays over the DataChannel after a reconnect come from its own
+			// counter; both arrive in order, so neither is checked against the other
+			err = pipeline.ProcessUnsequencedAudio(audioData.Data, audioData.SampleRate, audioData.Channels, timestamp)
+		} else {
+			check, err = pipeline.ProcessAudio(audioData.Data, audioData.SampleRate, audioData.Channels, audioData.SequenceID, timestamp)
+		}
+		if check != transcription.SequenceInOrder {
+			// Tell the client about audio lost or repeated on the way
+			s.sendAudioStats(peerID, peer, pipeline)
+		}
+		if errors.Is(err, transcription.ErrUnsupportedFormat) {
+			s.logger.Debug("Unsupported audio from peer %s: %v", peerID, err)
+			s.sendError(peerID, peer, protocol.ErrorCodeAudioFormatUnsupported, err.Error(), nil)
+		} else if err != nil {
+			s.logger.Error("Failed to process audio chunk: %v", err)
+			s.sendError(peerID, peer, protocol.ErrorCodePipelineFailed, "failed to process audio: "+err.Error(), nil)
+		}
+	} else if pipeline != nil && time.Since(pipeline.StoppedAt()) < stopGracePeriod {
+		// Audio captured just before the stop can arrive after it
+		s.logger.Debug("Dropping audio chunk received after stop from peer %s", peerID)
+	} else {
+		s.logger.Debug("No active pipeline for peer %s, dropping audio chunk", peerID)
+		s.sendError(peerID, peer, protocol.ErrorCodeNotStarted, "audio received without an active session", nil)
 	}
 }
 
@@ -285,24 +603,70 @@ func (s *Server) sendTranscriptionResults(peerID string, peer *webrtc.PeerConnec
 	s.logger.Info("Starting transcription result sender for peer %s", peerID)
 	defer s.logger.Info("Stopped transcription result sender for peer %s", peerID)
 
-	for result := range pipeline.Results() {
-		// Check if there was an error
+	// A resumed session's results wait until the new connection can carry them
+	select {
+	case <-peer.Ready():
+	case <-peer.Done():
+		return
+	}
+
+	for {
+		// Once the peer is gone, a resumed connection takes over delivery
+		var result transcription.TranscriptionResult
+		var ok bool
+		select {
+		case result, ok = <-pipeline.Results():
+		case <-peer.Done():
+		}
+		if !ok {
+			break
+		}
+
+		// Failed and dropped chunks still get an (empty) final so the client sees no gap
 		if result.Error != nil {
 			s.logger.Error("Transcription error: %v", result.Error)
-			continue
+			code, message := protocol.ErrorCodeInferenceFailed, "transcription failed: "+result.Error.Error()
+			switch {
+			case errors.Is(result.Error, transcription.ErrModelBusy):
+				code, message = protocol.ErrorCodeModelBusy, "server is busy, chunk was not transcribed"
+			case errors.Is(result.Error, transcription.ErrResultDropped):
+				code, message = protocol.ErrorCodeResultDropped, "transcription result was dropped on the server"
+			}
+			if !result.IsPartial {
+				s.sendError(peerID, peer, code, message, []uint64{result.ChunkIndex})
+			}
+			result.Text = ""
+			result.Segments = nil
 		}
 
-		// Skip empty transcriptions
-		if result.Text == "" {
+		// Skip empty partials (empty finals are sent so clients can detect missing sequence IDs),
+		// and partials for clients that do not show them
+		if result.IsPartial && (result.Text == "" || !peer.ClientFeature(protocol.FeaturePartials, true)) {
 			continue
 		}
+		if !peer.ClientFeature(protocol.FeatureTimestamps, true) {
+			result.Segments = nil
+		}
 
-		s.logger.Info("Transcription result: %q", result.Text)
+		// Partials are interim hypotheses; the next final supersedes them
+		msgType := protocol.MessageTypeTranscriptFinal
+		if result.IsPartial {
+			msgType = protocol.MessageTypeTranscriptPartial
+			s.logger.Debug("Partial transcription result: %q", result.Text)
+		} else {
+			s.logger.Info("Transcription result: %q", result.Text)
+		}
 
 		// Create transcription message
 		transcriptData := protocol.TranscriptData{
-			Text:    result.Text,
-			IsFinal: true,
+			Text:          result.Text,
+			IsFinal:       !result.IsPartial,
+			Confidence:    result.Confidence,
+			LowConfidence: result.LowConfidence,
+			SequenceID:    result.ChunkIndex,
+			Redelivered:   result.Redelivered,
+			SessionID:     pipeline.SessionID(),
+			Segments:      toProtocolSegments(result.Segments),
 		}
 
 		transcriptJSON, err := json.Marshal(transcriptData)
@@ -312,7 +676,7 @@ func (s *Server) sendTranscriptionResults(peerID string, peer *webrtc.PeerConnec
 		}
 
 		msg := &protocol.Message{
-			Type:      protocol.MessageTypeTranscriptFinal,
+			Type:      msgType,
 			Timestamp: result.Timestamp,
 			Data:      transcriptJSON,
 		}
@@ -320,6 +684,10 @@ func (s *Server) sendTranscriptionResults(peerID string, peer *webrtc.PeerConnec
 		// Send to client
 		if err := peer.SendMessage(msg); err != nil {
 			s.logger.Error("Failed to send transcription to peer %s: %v", peerID, err)
+			// Put an undelivered final back so a resumed connection sends it
+			if !result.IsPartial {
+				pipeline.Redeliver([]uint64{result.ChunkIndex})
+			}
 			break
 		}
 
@@ -329,6 +697,122 @@ func (s *Server) sendTranscriptionResults(peerID string, peer *webrtc.PeerConnec
 	s.logger.Info("Transcription result sender stopped for peer %s", peerID)
 }
 
+// sendAudioStats reports gaps and duplicates in the session's audio chunks to the client
+func (s *Server) sendAudioStats(peerID string, peer *webrtc.PeerConnection, pipeline *transcription.TranscriptionPipeline) {
+	// Older clients do not know the message
+	if !peer.ClientFeature(protocol.FeatureAudioStats, false) {
+		return
+	}
+
+	stats := pipeline.GetStats().SequenceStats
+	statsJSON, err := json.Marshal(protocol.AudioStatsData{
+		SessionID:  pipeline.SessionID(),
+		Received:   stats.Received,
+		Missing:    stats.Missing,
+		Gaps:       stats.Gaps,
+		Duplicates: stats.Duplicates,
+		Late:       stats.Late,
+	})
+	if err != nil {
+		s.logger.Error("Failed to marshal audio stats: %v", err)
+		return
+	}
+
+	msg := &protocol.Message{
+		Type:      protocol.MessageTypeAudioStats,
+		Timestamp: time.Now().UnixMilli(),
+		Data:      statsJSON,
+	}
+	if err := peer.SendMessage(msg); err != nil {
+		s.logger.Error("Failed to send audio stats to peer %s: %v", peerID, err)
+	}
+}
+
+// sendError sends an error message to the client
+// Errors without sequence IDs are throttled so per-chunk failures do not flood the client
+func (s *Server) sendError(peerID string, peer *webrtc.PeerConnection, code, message string, sequenceIDs []uint64) {
+	if len(sequenceIDs) == 0 && !s.shouldSendError(peerID, code) {
+		return
+	}
+	s.sendErrorUnthrottled(peerID, peer, code, message, sequenceIDs)
+}
+
+// sendErrorUnthrottled sends an error message to the client even if it was sent recently,
+// for errors that must always arrive (e.g. the session being terminated)
+func (s *Server) sendErrorUnthrottled(peerID string, peer *webrtc.PeerConnection, code, message string, sequenceIDs []uint64) {
+	errorJSON, err := json.Marshal(protocol.ErrorData{
+		Code:        code,
+		Message:     message,
+		SequenceIDs: sequenceIDs,
+	})
+	if err != nil {
+		s.logger.Error("Failed to marshal error data: %v", err)
+		return
+	}
+
+	msg := &protocol.Message{
+		Type:      protocol.MessageTypeError,
+		Timestamp: time.Now().UnixMilli(),
+		Data:      errorJSON,
+	}
+	if err := peer.SendMessage(msg); err != nil {
+		s.logger.Error("Failed to send error to peer %s: %v", peerID, err)
+		return
+	}
+	s.logger.Warn("Sent error to peer %s: %s (%s)", peerID, code, message)
+}
+
+// shouldSendError returns false if the same error was sent to the peer recently
+func (s *Server) shouldSendError(peerID, code string) bool {
+	s.errorsSentMu.Lock()
+	defer s.errorsSentMu.Unlock()
+
+	sent, ok := s.errorsSent[peerID]
+	if !ok {
+		sent = make(map[string]time.Time)
+		s.errorsSent[peerID] = sent
+	}
+	if last, ok := sent[code]; ok && time.Since(last) < errorThrottle {
+		return false
+	}
+	sent[code] = time.Now()
+	return true
+}
+
+// forgetErrors clears the error throttle state of a disconnected peer
+func (s *Server) forgetErrors(peerID string) {
+	s.errorsSentMu.Lock()
+	defer s.errorsSentMu.Unlock()
+	delete(s.errorsSent, peerID)
+}
+
+// toProtocolSegments converts pipeline segments to their wire representation
+func toProtocolSegments(segments []transcription.Segment) []protocol.TranscriptSegment {
+	if len(segments) == 0 {
+		return nil
+	}
+
+	out := make([]protocol.TranscriptSegment, len(segments))
+	for i, seg := range segments {
+		tokens := make([]protocol.TranscriptToken, len(seg.Tokens))
+		for j, tok := range seg.Tokens {
+			tokens[j] = protocol.TranscriptToken{
+				Text:        tok.Text,
+				StartMs:     tok.Start.Milliseconds(),
+				EndMs:       tok.End.Milliseconds(),
+				Probability: float64(tok.Probability),
+			}
+		}
+		out[i] = protocol.TranscriptSegment{
+			Text:    seg.Text,
+			StartMs: seg.Start.Milliseconds(),
+			EndMs:   seg.End.Milliseconds(),
+			Tokens:  tokens,
+		}
+	}
+	return out
+}
+
 // handleAnalyzeAudio analyzes audio samples and returns energy statistics
 func (s *Server) handleAnalyzeAudio(w http.ResponseWriter, r *http.Request) {
 	if r.Method != http.MethodPost {
@@ -391,8 +875,14 @@ func (s *Server) handleAnalyzeAudio(w http.ResponseWriter, r *http.Request) {
 		stats.SampleCount, stats.Min, stats.Max, stats.Avg, stats.P5, stats.P95)
 
 	// Return statistics
+	writeJSON(w, http.StatusOK, stats)
+}
+
+// writeJSON writes a JSON response
+func writeJSON(w http.ResponseWriter, code int, response interface{}) {
 	w.Header().Set("Content-Type", "application/json")
-	json.NewEncoder(w).Encode(stats)
+	w.WriteHeader(code)
+	json.NewEncoder(w).Encode(response)
 }
 
 // AudioStatistics holds energy statistics for audio samples
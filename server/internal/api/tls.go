@@ -0,0 +1,106 @@
+package api
+
+import (
+	"crypto/tls"
+	"fmt"
+	"os"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/lucianHymer/streaming-transcription/shared/logger"
+)
+
+// certCheckInterval is how often the certificate files are checked for changes
+const certCheckInterval = 10 * time.Second
+
+// certReloader serves a TLS certificate and reloads it when its files change,
+// so renewed certificates are picked up without a restart
+// A pair that fails to load (e.g. a renewal wrote the certificate but not yet the
+// key) keeps the previous certificate and is retried at the next check
+type certReloader struct {
+	certFile string
+	keyFile  string
+	logger   *logger.ContextLogger
+	cert     atomic.Pointer[tls.Certificate]
+
+	mu      sync.Mutex
+	modTime time.Time // Newest modification time of the loaded files
+	checked time.Time
+}
+
+// newCertReloader loads the certificate and key
+func newCertReloader(certFile, keyFile string, log *logger.ContextLogger) (*certReloader, error) {
+	c := &certReloader{
+		certFile: certFile,
+		keyFile:  keyFile,
+		logger:   log,
+	}
+	modTime, err := c.filesModTime()
+	if err != nil {
+		return nil, err
+	}
+	if err := c.load(modTime); err != nil {
+		return nil, err
+	}
+	c.checked = time.Now()
+	return c, nil
+}
+
+// GetCertificate returns the current certificate (for tls.Config)
+func (c *certReloader) GetCertificate(*tls.ClientHelloInfo) (*tls.Certificate, error) {
+	c.reloadIfChanged()
+	return c.cert.Load(), nil
+}
+
+// reloadIfChanged reloads the certificate if its files changed since it was loaded,
+// checking at most once per certCheckInterval
+func (c *certReloader) reloadIfChanged() {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if time.Since(c.checked) < certCheckInterval {
+		return
+	}
+	c.checked = time.Now()
+
+	modTime, err := c.filesModTime()
+	if err != nil {
+		c.logger.Error("Failed to check TLS certificate, keeping the current one: %v", err)
+		return
+	}
+	if !modTime.After(c.modTime) {
+		return
+	}
+	if err := c.load(modTime); err != nil {
+		c.logger.Error("Failed to reload TLS certificate, keeping the current one: %v", err)
+		return
+	}
+	c.logger.Info("Reloaded TLS certificate from %s", c.certFile)
+}
+
+// load reads the certificate and key (caller holds mu, or c is not yet shared)
+func (c *certReloader) load(modTime time.Time) error {
+	cert, err := tls.LoadX509KeyPair(c.certFile, c.keyFile)
+	if err != nil {
+		return fmt.Errorf("failed to load TLS certificate: %w", err)
+	}
+	c.cert.Store(&cert)
+	c.modTime = modTime
+	return nil
+}
+
+// filesModTime returns the newer modification time of the certificate and key files
+func (c *certReloader) filesModTime() (time.Time, error) {
+	var newest time.Time
+	for _, path := range []string{c.certFile, c.keyFile} {
+		info, err := os.Stat(path)
+		if err != nil {
+			return time.Time{}, fmt.Errorf("failed to stat TLS file: %w", err)
+		}
+		if info.ModTime().After(newest) {
+			newest = info.ModTime()
+		}
+	}
+	return newest, nil
+}
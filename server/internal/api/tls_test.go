@@ -0,0 +1,88 @@
+package api
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"math/big"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/lucianHymer/streaming-transcription/shared/logger"
+)
+
+// writeTestCert writes a self-signed certificate with the given serial number
+func writeTestCert(t *testing.T, certFile, keyFile string, serial int64) {
+	t.Helper()
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatalf("GenerateKey failed: %v", err)
+	}
+	template := &x509.Certificate{
+		SerialNumber: big.NewInt(serial),
+		Subject:      pkix.Name{CommonName: "localhost"},
+		DNSNames:     []string{"localhost"},
+		NotBefore:    time.Now().Add(-time.Hour),
+		NotAfter:     time.Now().Add(time.Hour),
+	}
+	der, err := x509.CreateCertificate(rand.Reader, template, template, &key.PublicKey, key)
+	if err != nil {
+		t.Fatalf("CreateCertificate failed: %v", err)
+	}
+	keyDER, err := x509.MarshalECPrivateKey(key)
+	if err != nil {
+		t.Fatalf("MarshalECPrivateKey failed: %v", err)
+	}
+	os.WriteFile(certFile, pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der}), 0600)
+	os.WriteFile(keyFile, pem.EncodeToMemory(&pem.Block{Type: "EC PRIVATE KEY", Bytes: keyDER}), 0600)
+}
+
+func TestCertReloader(t *testing.T) {
+	dir := t.TempDir()
+	certFile, keyFile := filepath.Join(dir, "cert.pem"), filepath.Join(dir, "key.pem")
+	writeTestCert(t, certFile, keyFile, 1)
+
+	log := logger.New(false).With("test")
+	certs, err := newCertReloader(certFile, keyFile, log)
+	if err != nil {
+		t.Fatalf("newCertReloader failed: %v", err)
+	}
+	serial := func() int64 {
+		cert, _ := certs.GetCertificate(nil)
+		leaf, err := x509.ParseCertificate(cert.Certificate[0])
+		if err != nil {
+			t.Fatalf("ParseCertificate failed: %v", err)
+		}
+		return leaf.SerialNumber.Int64()
+	}
+
+	// A renewed certificate is picked up at the next check
+	writeTestCert(t, certFile, keyFile, 2)
+	later := time.Now().Add(time.Minute)
+	os.Chtimes(certFile, later, later)
+	if serial() != 1 {
+		t.Error("Expected the files not to be checked again before the interval")
+	}
+	certs.checked = time.Time{}
+	if serial() != 2 {
+		t.Error("Expected the renewed certificate to be loaded")
+	}
+
+	// A half-written renewal keeps the current certificate until it loads
+	os.WriteFile(keyFile, []byte("not a key"), 0600)
+	latest := later.Add(time.Minute)
+	os.Chtimes(keyFile, latest, latest)
+	certs.checked = time.Time{}
+	if serial() != 2 {
+		t.Error("Expected the current certificate to be kept when the new files do not load")
+	}
+
+	if _, err := newCertReloader(certFile, filepath.Join(dir, "missing.pem"), log); err == nil {
+		t.Error("Expected an error for a missing key file")
+	}
+}
@@ -0,0 +1,170 @@
+package auth
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Authentication errors
+var (
+	ErrMissingCredentials = errors.New("missing credentials")
+	ErrInvalidCredentials = errors.New("invalid credentials")
+	ErrTokenExpired       = errors.New("token expired")
+	ErrQuotaExceeded      = errors.New("too many connections for user")
+)
+
+// Authentication methods reported in Identity.Method
+const (
+	MethodAnonymous = "anonymous" // Authentication is disabled
+	MethodAPIKey    = "api_key"
+	MethodToken     = "token" // HMAC-signed token
+)
+
+// Identity is the user a connection authenticated as
+type Identity struct {
+	User   string
+	Method string
+}
+
+// APIKey is a static key and the user it identifies
+type APIKey struct {
+	User string
+	Key  string
+}
+
+// Config holds authentication settings
+// With no API keys and no token secret, authentication is disabled
+type Config struct {
+	APIKeys         []APIKey
+	TokenSecret     string // Secret signing HMAC tokens (empty = tokens not accepted)
+	MaxPeersPerUser int    // Concurrent connections per user (0 = unlimited)
+}
+
+// Authenticator checks the credentials of incoming connections and counts
+// each user's connections against the quota
+type Authenticator struct {
+	keys            map[[sha256.Size]byte]string // Hashed key -> user
+	tokenSecret     []byte
+	maxPeersPerUser int
+
+	mu    sync.Mutex
+	peers map[string]int // Connections per user
+}
+
+// New creates an authenticator
+func New(config Config) *Authenticator {
+	a := &Authenticator{
+		keys:            make(map[[sha256.Size]byte]string),
+		tokenSecret:     []byte(config.TokenSecret),
+		maxPeersPerUser: config.MaxPeersPerUser,
+		peers:           make(map[string]int),
+	}
+	for _, key := range config.APIKeys {
+		a.keys[sha256.Sum256([]byte(key.Key))] = key.User
+	}
+	return a
+}
+
+// Enabled reports whether connections must present credentials
+func (a *Authenticator) Enabled() bool {
+	return len(a.keys) > 0 || len(a.tokenSecret) > 0
+}
+
+// Authenticate checks the bearer credentials of a request
+// Without authentication configured, every request is the anonymous user
+func (a *Authenticator) Authenticate(r *http.Request) (Identity, error) {
+	if !a.Enabled() {
+		return Identity{User: "anonymous", Method: MethodAnonymous}, nil
+	}
+
+	credential, ok := strings.CutPrefix(r.Header.Get("Authorization"), "Bearer ")
+	if !ok || credential == "" {
+		return Identity{}, ErrMissingCredentials
+	}
+
+	// Keys are looked up by hash, so the comparison does not leak the key through timing
+	if user, ok := a.keys[sha256.Sum256([]byte(credential))]; ok {
+		return Identity{User: user, Method: MethodAPIKey}, nil
+	}
+	if len(a.tokenSecret) > 0 {
+		user, err := VerifyToken(a.tokenSecret, credential, time.Now())
+		if err != nil {
+			return Identity{}, err
+		}
+		return Identity{User: user, Method: MethodToken}, nil
+	}
+	return Identity{}, ErrInvalidCredentials
+}
+
+// Acquire counts a connection against its user's quota
+// The returned function releases it when the connection closes
+func (a *Authenticator) Acquire(identity Identity) (func(), error) {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	// The anonymous user is everyone, so a per-user quota does not apply
+	if a.maxPeersPerUser > 0 && identity.Method != MethodAnonymous && a.peers[identity.User] >= a.maxPeersPerUser {
+		return nil, fmt.Errorf("%w (%d of %d)", ErrQuotaExceeded, a.peers[identity.User], a.maxPeersPerUser)
+	}
+	a.peers[identity.User]++
+
+	var once sync.Once
+	return func() {
+		once.Do(func() {
+			a.mu.Lock()
+			defer a.mu.Unlock()
+			if a.peers[identity.User]--; a.peers[identity.User] <= 0 {
+				delete(a.peers, identity.User)
+			}
+		})
+	}, nil
+}
+
+// IssueToken creates a token for user that is valid until expires
+// Tokens have the form <user>.<expiry unix seconds>.<signature>
+func IssueToken(secret []byte, user string, expires time.Time) string {
+	payload := user + "." + strconv.FormatInt(expires.Unix(), 10)
+	return payload + "." + sign(secret, payload)
+}
+
+// VerifyToken checks a token's signature and expiry and returns its user
+func VerifyToken(secret []byte, token string, now time.Time) (string, error) {
+	// The user may contain dots, so split from the right
+	dot := strings.LastIndexByte(token, '.')
+	if dot < 0 {
+		return "", ErrInvalidCredentials
+	}
+	payload, signature := token[:dot], token[dot+1:]
+	if subtle.ConstantTimeCompare([]byte(signature), []byte(sign(secret, payload))) != 1 {
+		return "", ErrInvalidCredentials
+	}
+
+	dot = strings.LastIndexByte(payload, '.')
+	if dot <= 0 {
+		return "", ErrInvalidCredentials
+	}
+	expires, err := strconv.ParseInt(payload[dot+1:], 10, 64)
+	if err != nil {
+		return "", ErrInvalidCredentials
+	}
+	if now.Unix() >= expires {
+		return "", ErrTokenExpired
+	}
+	return payload[:dot], nil
+}
+
+// sign returns the base64url HMAC-SHA256 of payload
+func sign(secret []byte, payload string) string {
+	mac := hmac.New(sha256.New, secret)
+	mac.Write([]byte(payload))
+	return base64.RawURLEncoding.EncodeToString(mac.Sum(nil))
+}
@@ -0,0 +1,102 @@
+package auth
+
+import (
+	"errors"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+func TestAuthenticate(t *testing.T) {
+	secret := "a-token-secret-of-at-least-32-characters"
+	a := New(Config{
+		APIKeys:     []APIKey{{User: "alice", Key: "alice-key-0123456789"}},
+		TokenSecret: secret,
+	})
+
+	tests := []struct {
+		name          string
+		authorization string
+		wantUser      string
+		wantMethod    string
+		wantErr       error
+	}{
+		{"api key", "Bearer alice-key-0123456789", "alice", MethodAPIKey, nil},
+		{"token", "Bearer " + IssueToken([]byte(secret), "bob.smith", time.Now().Add(time.Hour)), "bob.smith", MethodToken, nil},
+		{"expired token", "Bearer " + IssueToken([]byte(secret), "bob", time.Now().Add(-time.Second)), "", "", ErrTokenExpired},
+		{"token signed with another secret", "Bearer " + IssueToken([]byte("another secret"), "bob", time.Now().Add(time.Hour)), "", "", ErrInvalidCredentials},
+		{"unknown key", "Bearer not-a-key", "", "", ErrInvalidCredentials},
+		{"no header", "", "", "", ErrMissingCredentials},
+		{"not a bearer credential", "Basic YWxpY2U6cHc=", "", "", ErrMissingCredentials},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r := httptest.NewRequest("GET", "/api/v1/stream/signal", nil)
+			if tt.authorization != "" {
+				r.Header.Set("Authorization", tt.authorization)
+			}
+			identity, err := a.Authenticate(r)
+			if !errors.Is(err, tt.wantErr) {
+				t.Fatalf("Expected error %v, got %v", tt.wantErr, err)
+			}
+			if identity.User != tt.wantUser || identity.Method != tt.wantMethod {
+				t.Errorf("Expected %s via %s, got %+v", tt.wantUser, tt.wantMethod, identity)
+			}
+		})
+	}
+
+	// A tampered token fails its signature check
+	token := IssueToken([]byte(secret), "bob", time.Now().Add(time.Hour))
+	if _, err := VerifyToken([]byte(secret), "mallory"+token[3:], time.Now()); !errors.Is(err, ErrInvalidCredentials) {
+		t.Errorf("Expected a tampered token to be rejected, got %v", err)
+	}
+}
+
+func TestAuthenticationDisabled(t *testing.T) {
+	a := New(Config{MaxPeersPerUser: 1})
+	if a.Enabled() {
+		t.Fatal("Expected authentication to be disabled without keys or secret")
+	}
+
+	identity, err := a.Authenticate(httptest.NewRequest("GET", "/", nil))
+	if err != nil || identity.Method != MethodAnonymous {
+		t.Fatalf("Expected the anonymous user, got %+v, %v", identity, err)
+	}
+
+	// The anonymous user is not limited by the per-user quota
+	for i := 0; i < 3; i++ {
+		if _, err := a.Acquire(identity); err != nil {
+			t.Fatalf("Expected no quota for anonymous connections, got %v", err)
+		}
+	}
+}
+
+func TestAcquireEnforcesQuota(t *testing.T) {
+	a := New(Config{APIKeys: []APIKey{{User: "alice", Key: "k"}}, MaxPeersPerUser: 2})
+	alice := Identity{User: "alice", Method: MethodAPIKey}
+
+	first, err := a.Acquire(alice)
+	if err != nil {
+		t.Fatalf("Acquire failed: %v", err)
+	}
+	if _, err := a.Acquire(alice); err != nil {
+		t.Fatalf("Acquire failed: %v", err)
+	}
+	if _, err := a.Acquire(alice); !errors.Is(err, ErrQuotaExceeded) {
+		t.Fatalf("Expected the third connection to exceed the quota, got %v", err)
+	}
+	if _, err := a.Acquire(Identity{User: "bob", Method: MethodToken}); err != nil {
+		t.Errorf("Expected another user to have their own quota, got %v", err)
+	}
+
+	// Releasing twice frees only one slot
+	first()
+	first()
+	if _, err := a.Acquire(alice); err != nil {
+		t.Fatalf("Expected a released slot to be reusable, got %v", err)
+	}
+	if _, err := a.Acquire(alice); !errors.Is(err, ErrQuotaExceeded) {
+		t.Errorf("Expected a double release not to free a second slot, got %v", err)
+	}
+}
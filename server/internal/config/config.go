@@ -3,6 +3,7 @@ package config
 import (
 	"fmt"
 	"os"
+	"runtime"
 
 	"gopkg.in/yaml.v3"
 )
@@ -15,29 +16,94 @@ type Config struct {
 		LogFormat   string `yaml:"log_format"` // text, json
 	} `yaml:"server"`
 
+	// Serve HTTPS/WSS instead of plain HTTP (both files or neither)
+	// The files are reloaded when they change, so renewed certificates need no restart
+	TLS struct {
+		CertFile string `yaml:"cert_file"`
+		KeyFile  string `yaml:"key_file"`
+	} `yaml:"tls"`
+
 	WebRTC struct {
 		ICEServers []ICEServer `yaml:"ice_servers"`
 	} `yaml:"webrtc"`
 
 	Transcription struct {
-		ModelPath      string `yaml:"model_path"`
-		Language       string `yaml:"language"`
-		Threads        int    `yaml:"threads"`
-		EnableDebugWAV bool   `yaml:"enable_debug_wav"` // Save chunks as WAV files for debugging
+		ModelPath           string  `yaml:"model_path"`
+		Language            string  `yaml:"language"`
+		Threads             int     `yaml:"threads"`               // Threads per transcription (0 = CPUs / max_concurrent)
+		InitialPrompt       string  `yaml:"initial_prompt"`        // Prompt to bias vocabulary/style (empty = built-in programming prompt)
+		Temperature         float64 `yaml:"temperature"`           // Sampling temperature (0 = deterministic)
+		ContextTokens       int     `yaml:"context_tokens"`        // Previous transcript tokens to prompt the next chunk with (0 = disabled)
+		MaxConcurrent       int     `yaml:"max_concurrent"`        // Concurrent transcriptions server-wide, one model copy each (default: 1)
+		ShortUtteranceMs    int     `yaml:"short_utterance_ms"`    // Chunks shorter than this are transcribed first (default: 3000ms)
+		MaxQueueDepth       int     `yaml:"max_queue_depth"`       // Chunks waiting for a slot before new ones are rejected (0 = unlimited)
+		EnableDebugWAV      bool    `yaml:"enable_debug_wav"`      // Save chunks as WAV files for debugging
+		PartialIntervalMs   int     `yaml:"partial_interval_ms"`   // New speech between partial transcripts (0 = disabled)
+		PartialMaxMs        int     `yaml:"partial_max_ms"`        // No partials once the chunk is longer than this (default: 10000ms, 0 = no limit)
+		MinConfidence       float64 `yaml:"min_confidence"`        // Flag/drop finals below this confidence (0 = disabled)
+		LowConfidenceAction string  `yaml:"low_confidence_action"` // flag, drop (default: flag)
 	} `yaml:"transcription"`
 
+	SessionLimits struct {
+		MaxThreads       int      `yaml:"max_threads"`       // Max threads a client may request (default and cap: CPUs / max_concurrent, 0 = not allowed)
+		MaxTemperature   float64  `yaml:"max_temperature"`   // Max temperature a client may request (default: 1.0, 0 = not allowed)
+		MaxPromptChars   int      `yaml:"max_prompt_chars"`  // Max length of prompt + vocabulary (default: 1000, 0 = not allowed)
+		AllowedLanguages []string `yaml:"allowed_languages"` // Languages clients may request (empty = any the model supports)
+	} `yaml:"session_limits"`
+
+	HallucinationFilter struct {
+		Enabled            bool     `yaml:"enabled"`               // Default: true
+		StripNonSpeechTags bool     `yaml:"strip_non_speech_tags"` // Remove [BLANK_AUDIO], (music), etc.
+		StockPhrases       []string `yaml:"stock_phrases"`         // Drop text consisting only of these (empty = built-in list)
+		MaxRepeats         int      `yaml:"max_repeats"`           // Collapse n-grams repeated more often than this (default: 3, 0 = disabled)
+		MaxWordsPerSecond  float64  `yaml:"max_words_per_second"`  // Drop text too long for the chunk's speech (default: 6.0, 0 = disabled)
+	} `yaml:"hallucination_filter"`
+
+	Delivery struct {
+		MemoryQueueSize int    `yaml:"memory_queue_size"` // Results held in memory per session before spilling to disk (default: 64)
+		SpillDir        string `yaml:"spill_dir"`         // Directory for spilled results (empty = system temp dir)
+		HistorySize     int    `yaml:"history_size"`      // Delivered finals kept per session for re-delivery (default: 256)
+		ResumeGraceMs   int    `yaml:"resume_grace_ms"`   // How long a disconnected client's session waits for it to resume (default: 30000ms, 0 = disabled)
+	} `yaml:"delivery"`
+
+	Health struct {
+		SelfTest           bool `yaml:"self_test"`            // Transcribe a built-in clip at startup before reporting ready (default: true)
+		InferenceTimeoutMs int  `yaml:"inference_timeout_ms"` // A transcription running longer than this fails liveness (default: 120000ms, 0 = never)
+	} `yaml:"health"`
+
+	Admin struct {
+		Token string `yaml:"token"` // Bearer token for the admin API (empty = admin API disabled)
+	} `yaml:"admin"`
+
+	// Streaming clients present an API key or a signed token (no keys and no secret = disabled)
+	Auth struct {
+		APIKeys         []APIKey `yaml:"api_keys"`
+		TokenSecret     string   `yaml:"token_secret"`       // Secret signing HMAC tokens (issue them with -issue-token)
+		MaxPeersPerUser int      `yaml:"max_peers_per_user"` // Concurrent connections per user (0 = unlimited)
+		AllowedOrigins  []string `yaml:"allowed_origins"`    // Browser origins allowed to connect (empty = same origin only, "*" = any)
+	} `yaml:"auth"`
+
 	NoiseSuppression struct {
 		ModelPath string `yaml:"model_path"`
 	} `yaml:"noise_suppression"`
 
 	VAD struct {
-		EnergyThreshold    float64 `yaml:"energy_threshold"`      // VAD energy threshold (default: 100.0)
-		SilenceThresholdMs int     `yaml:"silence_threshold_ms"`  // Silence duration to trigger chunk (default: 1000ms)
-		MinChunkDurationMs int     `yaml:"min_chunk_duration_ms"` // Minimum chunk duration (default: 500ms)
-		MaxChunkDurationMs int     `yaml:"max_chunk_duration_ms"` // Maximum chunk duration (default: 30000ms)
+		VADSettings `yaml:",inline"` // Used for settings a client's control.start leaves unset
+		Min         VADSettings      `yaml:"min"`          // Lowest values a client may request (0 = no bound)
+		Max         VADSettings      `yaml:"max"`          // Highest values a client may request (0 = no bound)
+		OutOfRange  string           `yaml:"out_of_range"` // "clamp" or "reject" client settings outside min/max (default: clamp)
 	} `yaml:"vad"`
 }
 
+// VADSettings are the per-session VAD settings: the server defaults and their bounds
+type VADSettings struct {
+	EnergyThreshold        float64 `yaml:"energy_threshold"`         // VAD energy threshold (default: 500.0)
+	SilenceThresholdMs     int     `yaml:"silence_threshold_ms"`     // Silence duration to trigger chunk (default: 1000ms)
+	MinChunkDurationMs     int     `yaml:"min_chunk_duration_ms"`    // Minimum chunk duration (default: 500ms)
+	MaxChunkDurationMs     int     `yaml:"max_chunk_duration_ms"`    // Maximum chunk duration (default: 30000ms)
+	SpeechDensityThreshold float64 `yaml:"speech_density_threshold"` // Speech density for short utterances (default: 0.6)
+}
+
 // ICEServer represents a WebRTC ICE server configuration
 type ICEServer struct {
 	URLs       []string `yaml:"urls"`
@@ -45,31 +111,186 @@ type ICEServer struct {
 	Credential string   `yaml:"credential,omitempty"`
 }
 
+// APIKey is a static key a streaming client presents, and the user it identifies
+type APIKey struct {
+	User string `yaml:"user"`
+	Key  string `yaml:"key"`
+}
+
 // Load reads and parses the configuration file
+// Settings missing from the file keep their defaults, so an explicit 0 or false
+// in the file is distinguishable from an unset value
 func Load(path string) (*Config, error) {
 	data, err := os.ReadFile(path)
 	if err != nil {
 		return nil, fmt.Errorf("failed to read config file: %w", err)
 	}
 
-	var cfg Config
-	if err := yaml.Unmarshal(data, &cfg); err != nil {
+	cfg := defaults()
+	if err := yaml.Unmarshal(data, cfg); err != nil {
 		return nil, fmt.Errorf("failed to parse config file: %w", err)
 	}
 
-	// Set defaults
-	if cfg.Server.BindAddress == "" {
-		cfg.Server.BindAddress = "localhost:8080"
+	if err := cfg.resolve(); err != nil {
+		return nil, err
 	}
-
-	return &cfg, nil
+	return cfg, nil
 }
 
 // Default returns a default configuration
 func Default() *Config {
+	cfg := defaults()
+	cfg.resolve()
+	return cfg
+}
+
+// defaults returns the default values of all settings that have a fixed default
+func defaults() *Config {
 	cfg := &Config{}
 	cfg.Server.BindAddress = "localhost:8080"
 	cfg.Server.LogLevel = "info"
 	cfg.Server.LogFormat = "text"
+	cfg.Transcription.PartialMaxMs = 10000
+	cfg.Transcription.LowConfidenceAction = "flag"
+	cfg.Transcription.ShortUtteranceMs = 3000
+	cfg.Transcription.MaxConcurrent = 1
+	cfg.SessionLimits.MaxThreads = runtime.NumCPU()
+	cfg.SessionLimits.MaxTemperature = 1.0
+	cfg.SessionLimits.MaxPromptChars = 1000
+	cfg.Delivery.MemoryQueueSize = 64
+	cfg.Delivery.HistorySize = 256
+	cfg.Delivery.ResumeGraceMs = 30000
+	cfg.Health.SelfTest = true
+	cfg.Health.InferenceTimeoutMs = 120000
+	cfg.VAD.VADSettings = VADSettings{
+		EnergyThreshold:        500.0,
+		SilenceThresholdMs:     1000,
+		MinChunkDurationMs:     500,
+		MaxChunkDurationMs:     30000,
+		SpeechDensityThreshold: 0.6,
+	}
+	cfg.VAD.Min = VADSettings{
+		SilenceThresholdMs: 200,
+		MaxChunkDurationMs: 1000,
+	}
+	cfg.VAD.Max = VADSettings{
+		SilenceThresholdMs:     10000,
+		MinChunkDurationMs:     10000,
+		MaxChunkDurationMs:     30000,
+		SpeechDensityThreshold: 1.0,
+	}
+	cfg.VAD.OutOfRange = "clamp"
+	cfg.HallucinationFilter.Enabled = true
+	cfg.HallucinationFilter.StripNonSpeechTags = true
+	cfg.HallucinationFilter.MaxRepeats = 3
+	cfg.HallucinationFilter.MaxWordsPerSecond = 6.0
 	return cfg
 }
+
+// resolve fills in settings derived from others and validates the configuration
+func (cfg *Config) resolve() error {
+	if cfg.Server.BindAddress == "" {
+		cfg.Server.BindAddress = "localhost:8080"
+	}
+	if cfg.Transcription.MaxConcurrent <= 0 {
+		cfg.Transcription.MaxConcurrent = 1
+	}
+
+	// Concurrent transcriptions share the CPUs: workers x threads must not exceed them
+	perWorker := ThreadsPerWorker(cfg.Transcription.MaxConcurrent)
+	if cfg.Transcription.Threads == 0 {
+		cfg.Transcription.Threads = perWorker
+	}
+	if cfg.SessionLimits.MaxThreads > perWorker {
+		cfg.SessionLimits.MaxThreads = perWorker
+	}
+
+	if cfg.Delivery.MemoryQueueSize == 0 {
+		cfg.Delivery.MemoryQueueSize = 64
+	}
+	if cfg.Delivery.HistorySize == 0 {
+		cfg.Delivery.HistorySize = 256
+	}
+	if cfg.Transcription.LowConfidenceAction != "flag" && cfg.Transcription.LowConfidenceAction != "drop" {
+		return fmt.Errorf("invalid low_confidence_action %q (expected flag or drop)", cfg.Transcription.LowConfidenceAction)
+	}
+	if cfg.VAD.OutOfRange != "clamp" && cfg.VAD.OutOfRange != "reject" {
+		return fmt.Errorf("invalid vad.out_of_range %q (expected clamp or reject)", cfg.VAD.OutOfRange)
+	}
+	if cfg.Admin.Token != "" && len(cfg.Admin.Token) < minAdminTokenLength {
+		return fmt.Errorf("admin.token is too short (at least %d characters)", minAdminTokenLength)
+	}
+	if (cfg.TLS.CertFile == "") != (cfg.TLS.KeyFile == "") {
+		return fmt.Errorf("tls.cert_file and tls.key_file must be set together")
+	}
+	if err := cfg.validateAuth(); err != nil {
+		return err
+	}
+	return cfg.validateVAD()
+}
+
+// validateAuth checks that API keys are long enough and each identifies one user
+func (cfg *Config) validateAuth() error {
+	seen := make(map[string]bool)
+	for i, key := range cfg.Auth.APIKeys {
+		if key.User == "" {
+			return fmt.Errorf("auth.api_keys[%d] has no user", i)
+		}
+		if len(key.Key) < minAPIKeyLength {
+			return fmt.Errorf("auth.api_keys[%d] (user %s) is too short (at least %d characters)", i, key.User, minAPIKeyLength)
+		}
+		if seen[key.Key] {
+			return fmt.Errorf("auth.api_keys[%d] (user %s) repeats another key", i, key.User)
+		}
+		seen[key.Key] = true
+	}
+	if cfg.Auth.TokenSecret != "" && len(cfg.Auth.TokenSecret) < minTokenSecretLength {
+		return fmt.Errorf("auth.token_secret is too short (at least %d characters)", minTokenSecretLength)
+	}
+	if cfg.Auth.MaxPeersPerUser < 0 {
+		return fmt.Errorf("invalid auth.max_peers_per_user %d (must not be negative)", cfg.Auth.MaxPeersPerUser)
+	}
+	return nil
+}
+
+// validateVAD checks that each VAD default is positive and lies within its bounds
+func (cfg *Config) validateVAD() error {
+	vad := cfg.VAD
+	settings := []struct {
+		name          string
+		value, lo, hi float64
+	}{
+		{"energy_threshold", vad.EnergyThreshold, vad.Min.EnergyThreshold, vad.Max.EnergyThreshold},
+		{"silence_threshold_ms", float64(vad.SilenceThresholdMs), float64(vad.Min.SilenceThresholdMs), float64(vad.Max.SilenceThresholdMs)},
+		{"min_chunk_duration_ms", float64(vad.MinChunkDurationMs), float64(vad.Min.MinChunkDurationMs), float64(vad.Max.MinChunkDurationMs)},
+		{"max_chunk_duration_ms", float64(vad.MaxChunkDurationMs), float64(vad.Min.MaxChunkDurationMs), float64(vad.Max.MaxChunkDurationMs)},
+		{"speech_density_threshold", vad.SpeechDensityThreshold, vad.Min.SpeechDensityThreshold, vad.Max.SpeechDensityThreshold},
+	}
+	for _, s := range settings {
+		if s.value <= 0 {
+			return fmt.Errorf("invalid vad.%s %g (must be positive)", s.name, s.value)
+		}
+		if (s.lo > 0 && s.value < s.lo) || (s.hi > 0 && s.value > s.hi) {
+			return fmt.Errorf("vad.%s %g is outside vad.min and vad.max", s.name, s.value)
+		}
+	}
+	if vad.MinChunkDurationMs > vad.MaxChunkDurationMs {
+		return fmt.Errorf("vad.min_chunk_duration_ms %d exceeds vad.max_chunk_duration_ms %d", vad.MinChunkDurationMs, vad.MaxChunkDurationMs)
+	}
+	return nil
+}
+
+// Shortest secrets accepted, so they cannot be guessed
+const (
+	minAdminTokenLength  = 16
+	minAPIKeyLength      = 16
+	minTokenSecretLength = 32
+)
+
+// ThreadsPerWorker returns each concurrent transcription's share of the CPUs
+func ThreadsPerWorker(workers int) int {
+	if n := runtime.NumCPU() / workers; n > 1 {
+		return n
+	}
+	return 1
+}
@@ -0,0 +1,145 @@
+package config
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func loadYAML(t *testing.T, content string) *Config {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), "server.yaml")
+	if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+		t.Fatalf("Failed to write config: %v", err)
+	}
+	cfg, err := Load(path)
+	if err != nil {
+		t.Fatalf("Load failed: %v", err)
+	}
+	return cfg
+}
+
+func TestLoadKeepsDefaultsForMissingSections(t *testing.T) {
+	cfg := loadYAML(t, "server:\n  bind_address: \"0.0.0.0:9000\"\n")
+	def := Default()
+
+	if cfg.Server.BindAddress != "0.0.0.0:9000" {
+		t.Errorf("Expected bind address from file, got %q", cfg.Server.BindAddress)
+	}
+	filter := cfg.HallucinationFilter
+	if !filter.Enabled || !filter.StripNonSpeechTags {
+		t.Error("Expected filter enabled by default when the section is missing")
+	}
+	if filter.MaxRepeats != def.HallucinationFilter.MaxRepeats || filter.MaxWordsPerSecond != def.HallucinationFilter.MaxWordsPerSecond {
+		t.Errorf("Expected default filter limits, got %+v", filter)
+	}
+}
+
+func TestLoadHonoursExplicitZero(t *testing.T) {
+	cfg := loadYAML(t, `
+hallucination_filter:
+  enabled: true
+  max_repeats: 0
+  max_words_per_second: 0
+`)
+
+	if cfg.HallucinationFilter.MaxRepeats != 0 || cfg.HallucinationFilter.MaxWordsPerSecond != 0 {
+		t.Errorf("Expected explicit 0 to disable the rules, got %+v", cfg.HallucinationFilter)
+	}
+	if !cfg.HallucinationFilter.StripNonSpeechTags {
+		t.Error("Expected unset strip_non_speech_tags to keep its default")
+	}
+}
+
+func TestLoadSessionLimits(t *testing.T) {
+	cfg := loadYAML(t, "session_limits:\n  max_threads: 0\n")
+
+	if cfg.SessionLimits.MaxThreads != 0 {
+		t.Errorf("Expected explicit max_threads 0 to be kept, got %d", cfg.SessionLimits.MaxThreads)
+	}
+	if cfg.SessionLimits.MaxTemperature != 1.0 || cfg.SessionLimits.MaxPromptChars != 1000 {
+		t.Errorf("Expected default limits for unset options, got %+v", cfg.SessionLimits)
+	}
+}
+
+func TestLoadSplitsCPUsAcrossWorkers(t *testing.T) {
+	cfg := loadYAML(t, "transcription:\n  max_concurrent: 2\nsession_limits:\n  max_threads: 1000\n")
+
+	perWorker := ThreadsPerWorker(2)
+	if cfg.Transcription.Threads != perWorker {
+		t.Errorf("Expected %d threads per transcription, got %d", perWorker, cfg.Transcription.Threads)
+	}
+	if cfg.SessionLimits.MaxThreads != perWorker {
+		t.Errorf("Expected max_threads capped to %d, got %d", perWorker, cfg.SessionLimits.MaxThreads)
+	}
+	if def := Default(); def.Transcription.MaxConcurrent != 1 {
+		t.Errorf("Expected one model instance by default, got %d", def.Transcription.MaxConcurrent)
+	}
+}
+
+func TestLoadRejectsInvalidLowConfidenceAction(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "server.yaml")
+	os.WriteFile(path, []byte("transcription:\n  low_confidence_action: ignore\n"), 0644)
+
+	if _, err := Load(path); err == nil {
+		t.Error("Expected an error for an invalid low_confidence_action")
+	}
+}
+
+func TestLoadVADPolicy(t *testing.T) {
+	cfg := loadYAML(t, `
+vad:
+  energy_threshold: 300
+  max_chunk_duration_ms: 20000
+  max:
+    max_chunk_duration_ms: 20000
+  out_of_range: reject
+`)
+
+	if cfg.VAD.EnergyThreshold != 300 || cfg.VAD.SilenceThresholdMs != Default().VAD.SilenceThresholdMs {
+		t.Errorf("Expected energy threshold from file and default silence threshold, got %+v", cfg.VAD.VADSettings)
+	}
+	if cfg.VAD.Max.MaxChunkDurationMs != 20000 || cfg.VAD.Max.SpeechDensityThreshold != 1.0 {
+		t.Errorf("Expected max chunk bound from file and default density bound, got %+v", cfg.VAD.Max)
+	}
+
+	for _, content := range []string{
+		"vad:\n  out_of_range: ignore\n",
+		"vad:\n  max_chunk_duration_ms: 60000\n",
+		"vad:\n  min_chunk_duration_ms: 2000\n  max_chunk_duration_ms: 1500\n",
+		"vad:\n  speech_density_threshold: 0\n",
+	} {
+		path := filepath.Join(t.TempDir(), "server.yaml")
+		os.WriteFile(path, []byte(content), 0644)
+		if _, err := Load(path); err == nil {
+			t.Errorf("Expected an error for %q", content)
+		}
+	}
+}
+
+func TestLoadAuth(t *testing.T) {
+	cfg := loadYAML(t, `
+auth:
+  api_keys:
+    - user: alice
+      key: "0123456789abcdef"
+  max_peers_per_user: 2
+`)
+	if len(cfg.Auth.APIKeys) != 1 || cfg.Auth.APIKeys[0].User != "alice" || cfg.Auth.MaxPeersPerUser != 2 {
+		t.Errorf("Unexpected auth config: %+v", cfg.Auth)
+	}
+
+	for _, content := range []string{
+		"auth:\n  api_keys:\n    - key: \"0123456789abcdef\"\n",
+		"auth:\n  api_keys:\n    - user: alice\n      key: short\n",
+		"auth:\n  api_keys:\n    - user: alice\n      key: \"0123456789abcdef\"\n    - user: bob\n      key: \"0123456789abcdef\"\n",
+		"auth:\n  token_secret: too-short\n",
+		"auth:\n  max_peers_per_user: -1\n",
+	} {
+		path := filepath.Join(t.TempDir(), "server.yaml")
+		os.WriteFile(path, []byte(content), 0644)
+		if _, err := Load(path); err == nil {
+			t.Errorf("Expected an error for %q", content)
+		}
+	}
+}
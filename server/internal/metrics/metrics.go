@@ -0,0 +1,240 @@
+// Package metrics exposes server metrics in the Prometheus text format
+package metrics
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"math"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"sync/atomic"
+)
+
+// Counter is a value that only goes up
+type Counter struct {
+	bits atomic.Uint64 // float64 bits
+}
+
+// Add increases the counter by v (which must not be negative)
+func (c *Counter) Add(v float64) {
+	for {
+		old := c.bits.Load()
+		if c.bits.CompareAndSwap(old, math.Float64bits(math.Float64frombits(old)+v)) {
+			return
+		}
+	}
+}
+
+// Inc increases the counter by one
+func (c *Counter) Inc() {
+	c.Add(1)
+}
+
+// Value returns the current count
+func (c *Counter) Value() float64 {
+	return math.Float64frombits(c.bits.Load())
+}
+
+// CounterVec is a family of counters told apart by the value of one label
+type CounterVec struct {
+	label    string
+	mu       sync.Mutex
+	counters map[string]*Counter
+}
+
+// With returns the counter for a label value, creating it on first use
+func (v *CounterVec) With(value string) *Counter {
+	v.mu.Lock()
+	defer v.mu.Unlock()
+
+	c, ok := v.counters[value]
+	if !ok {
+		c = &Counter{}
+		v.counters[value] = c
+	}
+	return c
+}
+
+// Delete removes the counter for a label value (e.g. a peer that disconnected)
+func (v *CounterVec) Delete(value string) {
+	v.mu.Lock()
+	defer v.mu.Unlock()
+	delete(v.counters, value)
+}
+
+// Histogram counts observations in buckets by upper bound
+type Histogram struct {
+	bounds []float64 // Ascending upper bounds (+Inf is implicit)
+
+	mu     sync.Mutex
+	counts []uint64 // Per bucket, not cumulative; the last one is +Inf
+	sum    float64
+}
+
+// Observe records a value
+func (h *Histogram) Observe(v float64) {
+	i := sort.SearchFloat64s(h.bounds, v)
+
+	h.mu.Lock()
+	h.counts[i]++
+	h.sum += v
+	h.mu.Unlock()
+}
+
+// metric is a registered metric and how to write its samples
+type metric struct {
+	name  string
+	help  string
+	kind  string // counter, gauge or histogram
+	write func(w *bufio.Writer, name string)
+}
+
+// Registry holds metrics and writes them in the Prometheus text format
+type Registry struct {
+	mu      sync.Mutex
+	metrics []*metric
+}
+
+// NewRegistry creates an empty registry
+func NewRegistry() *Registry {
+	return &Registry{}
+}
+
+// register adds a metric, replacing an earlier one of the same name
+func (r *Registry) register(m *metric) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	for i, existing := range r.metrics {
+		if existing.name == m.name {
+			r.metrics[i] = m
+			return
+		}
+	}
+	r.metrics = append(r.metrics, m)
+}
+
+// NewCounter registers a counter
+func (r *Registry) NewCounter(name, help string) *Counter {
+	c := &Counter{}
+	r.register(&metric{name: name, help: help, kind: "counter", write: func(w *bufio.Writer, name string) {
+		writeSample(w, name, "", c.Value())
+	}})
+	return c
+}
+
+// NewCounterVec registers a family of counters with one label
+func (r *Registry) NewCounterVec(name, help, label string) *CounterVec {
+	v := &CounterVec{label: label, counters: make(map[string]*Counter)}
+	r.register(&metric{name: name, help: help, kind: "counter", write: func(w *bufio.Writer, name string) {
+		v.mu.Lock()
+		values := make([]string, 0, len(v.counters))
+		for value := range v.counters {
+			values = append(values, value)
+		}
+		counters := make(map[string]float64, len(values))
+		for _, value := range values {
+			counters[value] = v.counters[value].Value()
+		}
+		v.mu.Unlock()
+
+		sort.Strings(values)
+		for _, value := range values {
+			writeSample(w, name, labelPair(v.label, value), counters[value])
+		}
+	}})
+	return v
+}
+
+// NewHistogram registers a histogram with the given ascending bucket upper bounds
+func (r *Registry) NewHistogram(name, help string, buckets []float64) *Histogram {
+	h := &Histogram{bounds: buckets, counts: make([]uint64, len(buckets)+1)}
+	r.register(&metric{name: name, help: help, kind: "histogram", write: func(w *bufio.Writer, name string) {
+		h.mu.Lock()
+		counts := append([]uint64(nil), h.counts...)
+		sum := h.sum
+		h.mu.Unlock()
+
+		var cumulative uint64
+		for i, bound := range h.bounds {
+			cumulative += counts[i]
+			writeSample(w, name+"_bucket", labelPair("le", formatValue(bound)), float64(cumulative))
+		}
+		cumulative += counts[len(h.bounds)]
+		writeSample(w, name+"_bucket", labelPair("le", "+Inf"), float64(cumulative))
+		writeSample(w, name+"_sum", "", sum)
+		writeSample(w, name+"_count", "", float64(cumulative))
+	}})
+	return h
+}
+
+// GaugeFunc registers a gauge whose value is read from fn on every scrape
+func (r *Registry) GaugeFunc(name, help string, fn func() float64) {
+	r.register(&metric{name: name, help: help, kind: "gauge", write: func(w *bufio.Writer, name string) {
+		writeSample(w, name, "", fn())
+	}})
+}
+
+// CounterFunc registers a counter whose value is read from fn on every scrape
+func (r *Registry) CounterFunc(name, help string, fn func() float64) {
+	r.register(&metric{name: name, help: help, kind: "counter", write: func(w *bufio.Writer, name string) {
+		writeSample(w, name, "", fn())
+	}})
+}
+
+// Write writes all metrics in the Prometheus text format
+func (r *Registry) Write(out io.Writer) error {
+	r.mu.Lock()
+	metrics := append([]*metric(nil), r.metrics...)
+	r.mu.Unlock()
+
+	w := bufio.NewWriter(out)
+	for _, m := range metrics {
+		fmt.Fprintf(w, "# HELP %s %s\n", m.name, strings.ReplaceAll(m.help, "\n", " "))
+		fmt.Fprintf(w, "# TYPE %s %s\n", m.name, m.kind)
+		m.write(w, m.name)
+	}
+	return w.Flush()
+}
+
+// Handler serves the registry's metrics
+func (r *Registry) Handler() http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		if req.Method != http.MethodGet {
+			http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+			return
+		}
+		w.Header().Set("Content-Type", "text/plain; version=0.0.4; charset=utf-8")
+		r.Write(w)
+	})
+}
+
+// writeSample writes one sample line
+func writeSample(w *bufio.Writer, name, labels string, value float64) {
+	w.WriteString(name)
+	if labels != "" {
+		w.WriteString("{" + labels + "}")
+	}
+	w.WriteString(" " + formatValue(value) + "\n")
+}
+
+// labelPair formats a label with its value escaped
+func labelPair(name, value string) string {
+	value = strings.NewReplacer(`\`, `\\`, `"`, `\"`, "\n", `\n`).Replace(value)
+	return name + `="` + value + `"`
+}
+
+// formatValue formats a sample value (Prometheus spells infinity +Inf/-Inf)
+func formatValue(v float64) string {
+	switch {
+	case math.IsInf(v, 1):
+		return "+Inf"
+	case math.IsInf(v, -1):
+		return "-Inf"
+	}
+	return strconv.FormatFloat(v, 'g', -1, 64)
+}
@@ -0,0 +1,78 @@
+package metrics
+
+import (
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestRegistryWritesTextFormat(t *testing.T) {
+	r := NewRegistry()
+	chunks := r.NewCounter("test_chunks_total", "Chunks cut.")
+	bytes := r.NewCounterVec("test_bytes_total", "Bytes by peer.", "peer")
+	duration := r.NewHistogram("test_duration_seconds", "Chunk duration.", []float64{1, 5})
+	r.GaugeFunc("test_peers", "Connected peers.", func() float64 { return 2 })
+
+	chunks.Inc()
+	chunks.Add(2)
+	bytes.With(`b"1`).Add(10)
+	bytes.With("a").Add(1.5)
+	for _, v := range []float64{0.5, 1, 3, 30} {
+		duration.Observe(v)
+	}
+
+	var out strings.Builder
+	if err := r.Write(&out); err != nil {
+		t.Fatalf("Write failed: %v", err)
+	}
+
+	want := `# HELP test_chunks_total Chunks cut.
+# TYPE test_chunks_total counter
+test_chunks_total 3
+# HELP test_bytes_total Bytes by peer.
+# TYPE test_bytes_total counter
+test_bytes_total{peer="a"} 1.5
+test_bytes_total{peer="b\"1"} 10
+# HELP test_duration_seconds Chunk duration.
+# TYPE test_duration_seconds histogram
+test_duration_seconds_bucket{le="1"} 2
+test_duration_seconds_bucket{le="5"} 3
+test_duration_seconds_bucket{le="+Inf"} 4
+test_duration_seconds_sum 34.5
+test_duration_seconds_count 4
+# HELP test_peers Connected peers.
+# TYPE test_peers gauge
+test_peers 2
+`
+	if out.String() != want {
+		t.Errorf("Unexpected output:\n%s\nwant:\n%s", out.String(), want)
+	}
+}
+
+func TestCounterVecDelete(t *testing.T) {
+	r := NewRegistry()
+	bytes := r.NewCounterVec("test_bytes_total", "Bytes by peer.", "peer")
+	bytes.With("gone").Inc()
+	bytes.Delete("gone")
+
+	rec := httptest.NewRecorder()
+	r.Handler().ServeHTTP(rec, httptest.NewRequest("GET", "/metrics", nil))
+	if strings.Contains(rec.Body.String(), "gone") {
+		t.Errorf("Expected deleted counter to be gone, got:\n%s", rec.Body.String())
+	}
+	if ct := rec.Header().Get("Content-Type"); !strings.HasPrefix(ct, "text/plain; version=0.0.4") {
+		t.Errorf("Unexpected content type %q", ct)
+	}
+}
+
+func TestRegisterReplacesMetricOfSameName(t *testing.T) {
+	r := NewRegistry()
+	r.GaugeFunc("test_peers", "Connected peers.", func() float64 { return 1 })
+	r.GaugeFunc("test_peers", "Connected peers.", func() float64 { return 5 })
+
+	var out strings.Builder
+	r.Write(&out)
+	if strings.Count(out.String(), "# TYPE test_peers") != 1 || !strings.Contains(out.String(), "test_peers 5") {
+		t.Errorf("Expected the second registration to replace the first, got:\n%s", out.String())
+	}
+}
@@ -0,0 +1,41 @@
+package metrics
+
+// Default is the registry served on /metrics
+var Default = NewRegistry()
+
+// Server metrics, updated where the events happen
+// Gauges of current state (peers, pipelines, queue) are registered by the API server
+var (
+	ChunksCut = Default.NewCounter("transcription_chunks_total",
+		"Audio chunks cut by the VAD chunker and sent for transcription.")
+	ChunkDuration = Default.NewHistogram("transcription_chunk_duration_seconds",
+		"Duration of the audio chunks cut by the VAD chunker.",
+		[]float64{0.5, 1, 2, 3, 5, 10, 15, 20, 30})
+	InferenceDuration = Default.NewHistogram("transcription_inference_seconds",
+		"Time Whisper took to transcribe a chunk (partials included), excluding queue wait.",
+		[]float64{0.1, 0.25, 0.5, 1, 2, 5, 10, 20, 30})
+	RealtimeFactor = Default.NewHistogram("transcription_realtime_factor",
+		"Whisper inference time divided by the audio duration (below 1 is faster than real time).",
+		[]float64{0.05, 0.1, 0.2, 0.3, 0.5, 0.75, 1, 1.5, 2})
+	QueueWait = Default.NewHistogram("transcription_queue_wait_seconds",
+		"Time transcription jobs waited for a free Whisper worker.",
+		[]float64{0.01, 0.05, 0.1, 0.25, 0.5, 1, 2, 5, 10})
+	ResultsDropped = Default.NewCounterVec("transcription_results_dropped_total",
+		"Final transcripts whose text was not delivered, by reason.", "reason")
+	RNNoiseErrors = Default.NewCounter("transcription_rnnoise_errors_total",
+		"Audio chunks RNNoise failed to denoise (passed through unprocessed).")
+	SessionResumes = Default.NewCounterVec("transcription_session_resumes_total",
+		"Reconnecting clients presenting a resume token, by result (resumed, expired or wrong_user).", "result")
+	AudioBytesReceived = Default.NewCounterVec("transcription_audio_bytes_received_total",
+		"Audio payload bytes received, as sent on the wire (before Opus decoding), by peer.", "peer")
+	AuthRejected = Default.NewCounterVec("transcription_auth_rejected_total",
+		"Connections refused by authentication, by reason (missing, invalid, expired or quota).", "reason")
+)
+
+// Reasons counted by ResultsDropped
+const (
+	DropReasonError         = "error"          // Transcription failed
+	DropReasonFiltered      = "filtered"       // Hallucination filter removed all text
+	DropReasonLowConfidence = "low_confidence" // Below min_confidence with low_confidence_action: drop
+	DropReasonSpillFailed   = "spill_failed"   // Could not be spilled to or read back from disk
+)
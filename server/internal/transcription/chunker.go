@@ -15,10 +15,21 @@ type SmartChunkerConfig struct {
 	MaxChunkDuration       time.Duration // Maximum chunk duration (safety limit)
 	VADEnergyThreshold     float64       // Energy threshold for VAD
 	SpeechDensityThreshold float64       // Speech density threshold for short utterances
-	ChunkReadyCallback     func([]int16) // Called when chunk is ready for transcription
+	ChunkReadyCallback     func(Chunk)   // Called when chunk is ready for transcription
+	PartialInterval        time.Duration // New speech between partial snapshots (0 = disabled)
+	PartialMaxDuration     time.Duration // No snapshots once the buffer is longer than this (0 = no limit)
+	PartialCallback        func(Chunk)   // Called with a snapshot of the growing buffer (index of the upcoming chunk)
 	Logger                 *logger.Logger
 }
 
+// Chunk is a span of session audio handed out by the chunker
+type Chunk struct {
+	Index   uint64        // Monotonically increasing index, assigned when the chunk is cut
+	Offset  time.Duration // Position of the first sample in the session audio stream
+	Speech  time.Duration // Speech detected by VAD within the chunk
+	Samples []int16
+}
+
 // SmartChunker accumulates audio and chunks based on VAD silence detection
 type SmartChunker struct {
 	config      SmartChunkerConfig
@@ -29,6 +40,16 @@ type SmartChunker struct {
 	lastChunk   time.Time
 	totalSpeech time.Duration
 	log         *logger.ContextLogger
+
+	// Speech duration (per VAD) at the time of the last partial snapshot
+	lastPartialSpeech time.Duration
+
+	// Index assigned to the next chunk cut; monotonically increasing for the
+	// lifetime of the chunker so results can be put back in order downstream
+	nextChunkIndex uint64
+
+	// Session sample position of the first sample in buffer (reset on Reset)
+	bufferStartSample uint64
 }
 
 // NewSmartChunker creates a new VAD-based audio chunker
@@ -101,12 +122,18 @@ func (c *SmartChunker) ProcessSamples(samples []int16) {
 	}
 
 	// Check if we should chunk
-	c.checkAndChunk()
+	if c.checkAndChunk() {
+		return
+	}
+
+	// Still accumulating - hand out a partial snapshot if enough new speech arrived
+	c.checkAndPartial()
 }
 
 // checkAndChunk determines if we should trigger a chunk
+// Returns true if the buffer was flushed
 // Must be called with bufferMu locked
-func (c *SmartChunker) checkAndChunk() {
+func (c *SmartChunker) checkAndChunk() bool {
 	bufferDuration := c.getBufferDuration()
 	shouldChunk := c.vad.ShouldChunk()
 	vadStats := c.vad.Stats()
@@ -114,7 +141,7 @@ func (c *SmartChunker) checkAndChunk() {
 	// Safety: Always chunk if we hit max duration
 	if bufferDuration >= c.config.MaxChunkDuration {
 		c.flushChunk()
-		return
+		return true
 	}
 
 	// Check if VAD detected sufficient silence AND we have enough audio
@@ -139,8 +166,44 @@ func (c *SmartChunker) checkAndChunk() {
 		c.log.Debug("Chunking: speech=%.2fs, density=%.1f%%, buffer=%.2fs",
 			vadStats.SpeechDuration.Seconds(), speechDensity*100, bufferDuration.Seconds())
 		c.flushChunk()
+		return true
+	}
+
+	return false
+}
+
+// checkAndPartial emits a snapshot of the growing buffer for interim transcription
+// Snapshots are cut every PartialInterval of new speech, so silence never triggers one
+// Each snapshot is transcribed in full, so none are cut past PartialMaxDuration
+// Must be called with bufferMu locked
+func (c *SmartChunker) checkAndPartial() {
+	if c.config.PartialCallback == nil || c.config.PartialInterval <= 0 {
 		return
 	}
+	if c.config.PartialMaxDuration > 0 && c.getBufferDuration() > c.config.PartialMaxDuration {
+		return
+	}
+
+	speech := c.vad.Stats().SpeechDuration
+	if speech-c.lastPartialSpeech < c.config.PartialInterval {
+		return
+	}
+	c.lastPartialSpeech = speech
+
+	// Make a copy for the callback
+	snapshot := make([]int16, len(c.buffer))
+	copy(snapshot, c.buffer)
+
+	c.log.Debug("Partial snapshot: speech=%.2fs, buffer=%.2fs",
+		speech.Seconds(), c.getBufferDuration().Seconds())
+
+	// Call callback asynchronously
+	go c.config.PartialCallback(Chunk{
+		Index:   c.nextChunkIndex,
+		Offset:  c.samplesToDuration(c.bufferStartSample),
+		Speech:  speech,
+		Samples: snapshot,
+	})
 }
 
 // flushChunk sends accumulated audio for transcription
@@ -156,17 +219,28 @@ func (c *SmartChunker) flushChunk() {
 
 	vadStats := c.vad.Stats()
 
+	// Assign chunk index at cut time (callbacks may complete out of order)
+	cut := Chunk{
+		Index:   c.nextChunkIndex,
+		Offset:  c.samplesToDuration(c.bufferStartSample),
+		Speech:  vadStats.SpeechDuration,
+		Samples: chunk,
+	}
+	c.nextChunkIndex++
+
 	// Clear buffer
+	c.bufferStartSample += uint64(len(c.buffer))
 	c.buffer = c.buffer[:0]
 	c.lastChunk = time.Now()
 	c.totalSpeech += vadStats.SpeechDuration
 
 	// Reset VAD state
 	c.vad.Reset()
+	c.lastPartialSpeech = 0
 
 	// Call callback asynchronously
 	if c.config.ChunkReadyCallback != nil {
-		go c.config.ChunkReadyCallback(chunk)
+		go c.config.ChunkReadyCallback(cut)
 	}
 }
 
@@ -206,15 +280,21 @@ func (c *SmartChunker) Flush() {
 		c.log.Debug("Discarding final chunk: insufficient speech (%.2fs speech @ %.1f%% density in %.2fs buffer)",
 			vadStats.SpeechDuration.Seconds(), speechDensity*100, bufferDuration.Seconds())
 		// Clear buffer without transcribing
+		c.bufferStartSample += uint64(len(c.buffer))
 		c.buffer = c.buffer[:0]
 		c.vad.Reset()
+		c.lastPartialSpeech = 0
 	}
 }
 
 // getBufferDuration returns the current buffer duration
 // Must be called with bufferMu locked
 func (c *SmartChunker) getBufferDuration() time.Duration {
-	numSamples := len(c.buffer)
+	return c.samplesToDuration(uint64(len(c.buffer)))
+}
+
+// samplesToDuration converts a sample count to a duration at the chunker sample rate
+func (c *SmartChunker) samplesToDuration(numSamples uint64) time.Duration {
 	seconds := float64(numSamples) / float64(c.config.SampleRate)
 	return time.Duration(seconds * float64(time.Second))
 }
@@ -225,6 +305,7 @@ func (c *SmartChunker) GetStats() ChunkerStats {
 	defer c.bufferMu.Unlock()
 
 	return ChunkerStats{
+		ChunksCut:      c.nextChunkIndex,
 		BufferDuration: c.getBufferDuration(),
 		BufferSamples:  len(c.buffer),
 		TotalSpeech:    c.totalSpeech,
@@ -234,6 +315,8 @@ func (c *SmartChunker) GetStats() ChunkerStats {
 }
 
 // Reset clears the chunker state
+// The chunk index is deliberately kept so indices never repeat while
+// transcriptions from before the reset may still be in flight
 func (c *SmartChunker) Reset() {
 	c.bufferMu.Lock()
 	defer c.bufferMu.Unlock()
@@ -243,10 +326,13 @@ func (c *SmartChunker) Reset() {
 	c.startTime = time.Now()
 	c.lastChunk = time.Now()
 	c.totalSpeech = 0
+	c.lastPartialSpeech = 0
+	c.bufferStartSample = 0
 }
 
 // ChunkerStats holds statistics about the chunker
 type ChunkerStats struct {
+	ChunksCut      uint64
 	BufferDuration time.Duration
 	BufferSamples  int
 	TotalSpeech    time.Duration
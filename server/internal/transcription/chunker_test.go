@@ -0,0 +1,213 @@
+package transcription
+
+import (
+	"sort"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/lucianHymer/streaming-transcription/shared/logger"
+)
+
+// chunkRecorder collects chunks handed out by the chunker's asynchronous callbacks
+type chunkRecorder struct {
+	mu       sync.Mutex
+	chunks   []Chunk
+	partials []Chunk
+}
+
+func (r *chunkRecorder) chunk(c Chunk) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.chunks = append(r.chunks, c)
+}
+
+func (r *chunkRecorder) partial(c Chunk) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.partials = append(r.partials, c)
+}
+
+// wait polls until the recorder holds the expected number of chunks and partials
+// Chunks are sorted by index, since callbacks run concurrently
+func (r *chunkRecorder) wait(t *testing.T, chunks, partials int) {
+	t.Helper()
+	deadline := time.Now().Add(time.Second)
+	for time.Now().Before(deadline) {
+		r.mu.Lock()
+		done := len(r.chunks) >= chunks && len(r.partials) >= partials
+		sort.Slice(r.chunks, func(i, j int) bool { return r.chunks[i].Index < r.chunks[j].Index })
+		r.mu.Unlock()
+		if done {
+			return
+		}
+		time.Sleep(time.Millisecond)
+	}
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	t.Fatalf("Expected %d chunks and %d partials, got %d and %d", chunks, partials, len(r.chunks), len(r.partials))
+}
+
+func newTestChunker(r *chunkRecorder, partialInterval, partialMax time.Duration) *SmartChunker {
+	return NewSmartChunker(SmartChunkerConfig{
+		SampleRate:         16000,
+		SilenceThreshold:   500 * time.Millisecond,
+		MinChunkDuration:   500 * time.Millisecond,
+		MaxChunkDuration:   30 * time.Second,
+		VADEnergyThreshold: 100,
+		ChunkReadyCallback: r.chunk,
+		PartialInterval:    partialInterval,
+		PartialMaxDuration: partialMax,
+		PartialCallback:    r.partial,
+		Logger:             logger.New(false),
+	})
+}
+
+// audio returns d of samples at the given amplitude (0 = silence, 1000 = speech)
+func audio(d time.Duration, amplitude int16) []int16 {
+	samples := make([]int16, int(d.Seconds()*16000))
+	for i := range samples {
+		if i%2 == 0 {
+			samples[i] = amplitude
+		} else {
+			samples[i] = -amplitude
+		}
+	}
+	return samples
+}
+
+// feed passes samples to the chunker in 100ms blocks, as the pipeline does
+func feed(c *SmartChunker, samples []int16) {
+	for len(samples) > 0 {
+		n := 1600
+		if n > len(samples) {
+			n = len(samples)
+		}
+		c.ProcessSamples(samples[:n])
+		samples = samples[n:]
+	}
+}
+
+func TestChunkerIndicesAndOffsets(t *testing.T) {
+	r := &chunkRecorder{}
+	c := newTestChunker(r, 0, 0)
+
+	// Two utterances, each followed by enough silence to cut a chunk
+	feed(c, audio(time.Second, 1000))
+	feed(c, audio(500*time.Millisecond, 0))
+	feed(c, audio(time.Second, 1000))
+	feed(c, audio(500*time.Millisecond, 0))
+	r.wait(t, 2, 0)
+
+	if r.chunks[0].Index != 0 || r.chunks[1].Index != 1 {
+		t.Errorf("Expected indices 0 and 1, got %d and %d", r.chunks[0].Index, r.chunks[1].Index)
+	}
+	if r.chunks[0].Offset != 0 || r.chunks[1].Offset != 1500*time.Millisecond {
+		t.Errorf("Expected offsets 0s and 1.5s, got %v and %v", r.chunks[0].Offset, r.chunks[1].Offset)
+	}
+	if r.chunks[0].Speech != time.Second {
+		t.Errorf("Expected 1s of speech, got %v", r.chunks[0].Speech)
+	}
+}
+
+func TestChunkerOffsetAfterDiscardedFlush(t *testing.T) {
+	r := &chunkRecorder{}
+	c := newTestChunker(r, 0, 0)
+
+	// Too little speech to transcribe: Flush discards the buffer
+	feed(c, audio(100*time.Millisecond, 1000))
+	feed(c, audio(300*time.Millisecond, 0))
+	c.Flush()
+
+	feed(c, audio(time.Second, 1000))
+	c.Flush()
+	r.wait(t, 1, 0)
+
+	// The discarded audio still advances session time, but not the chunk index
+	if r.chunks[0].Index != 0 {
+		t.Errorf("Expected index 0, got %d", r.chunks[0].Index)
+	}
+	if r.chunks[0].Offset != 400*time.Millisecond {
+		t.Errorf("Expected offset 400ms, got %v", r.chunks[0].Offset)
+	}
+}
+
+func TestChunkerResetKeepsIndices(t *testing.T) {
+	r := &chunkRecorder{}
+	c := newTestChunker(r, 0, 0)
+
+	feed(c, audio(time.Second, 1000))
+	c.Flush()
+	c.Reset()
+	feed(c, audio(time.Second, 1000))
+	c.Flush()
+	r.wait(t, 2, 0)
+
+	// Session time restarts, indices do not
+	for _, chunk := range r.chunks {
+		if chunk.Offset != 0 {
+			t.Errorf("Expected offset 0 after reset, got %v", chunk.Offset)
+		}
+	}
+	if r.chunks[0].Index == r.chunks[1].Index {
+		t.Errorf("Expected distinct indices across reset, got %d twice", r.chunks[0].Index)
+	}
+}
+
+func TestChunkerPartialSnapshots(t *testing.T) {
+	r := &chunkRecorder{}
+	c := newTestChunker(r, 500*time.Millisecond, 0)
+
+	feed(c, audio(1200*time.Millisecond, 1000))
+	r.wait(t, 0, 2)
+
+	r.mu.Lock()
+	for _, partial := range r.partials {
+		if partial.Index != 0 {
+			t.Errorf("Expected partial for upcoming chunk 0, got %d", partial.Index)
+		}
+	}
+	r.mu.Unlock()
+
+	// Silence adds no new speech, so no further partials
+	feed(c, audio(400*time.Millisecond, 0))
+	time.Sleep(20 * time.Millisecond)
+	r.mu.Lock()
+	if len(r.partials) != 2 {
+		t.Errorf("Expected 2 partials, got %d", len(r.partials))
+	}
+	r.mu.Unlock()
+}
+
+func TestChunkerPartialMaxDuration(t *testing.T) {
+	r := &chunkRecorder{}
+	c := newTestChunker(r, 500*time.Millisecond, time.Second)
+
+	feed(c, audio(3*time.Second, 1000))
+	time.Sleep(20 * time.Millisecond)
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if len(r.partials) != 2 {
+		t.Errorf("Expected 2 partials before the cap, got %d", len(r.partials))
+	}
+	for _, partial := range r.partials {
+		if d := time.Duration(len(partial.Samples)) * time.Second / 16000; d > time.Second {
+			t.Errorf("Partial snapshot of %v exceeds the 1s cap", d)
+		}
+	}
+}
+
+func TestChunkerPartialsDisabled(t *testing.T) {
+	r := &chunkRecorder{}
+	c := newTestChunker(r, 0, 0)
+
+	feed(c, audio(2*time.Second, 1000))
+	time.Sleep(20 * time.Millisecond)
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if len(r.partials) != 0 {
+		t.Errorf("Expected no partials when disabled, got %d", len(r.partials))
+	}
+}
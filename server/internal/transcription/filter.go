@@ -0,0 +1,242 @@
+package transcription
+
+import (
+	"math"
+	"regexp"
+	"strings"
+	"sync/atomic"
+	"time"
+	"unicode"
+)
+
+// DefaultStockPhrases are phrases Whisper is known to produce on silence or noise
+// (mostly subtitle credits and video outros from its training data)
+var DefaultStockPhrases = []string{
+	"thank you for watching",
+	"thanks for watching",
+	"thank you so much for watching",
+	"please subscribe",
+	"like and subscribe",
+	"don't forget to like and subscribe",
+	"subscribe to my channel",
+	"see you in the next video",
+	"subtitles by the amara.org community",
+	"transcription by castingwords",
+	"you",
+}
+
+// FilterConfig holds configuration for the hallucination filter
+type FilterConfig struct {
+	Enabled            bool
+	StripNonSpeechTags bool     // Remove known tags ([BLANK_AUDIO], (music), *laughs*, ♪) and text that is only tags
+	StockPhrases       []string // Drop text that consists only of one of these phrases (empty = defaults)
+	MaxRepeats         int      // Collapse an n-gram repeated more than this many times in a row (0 = disabled)
+	MaxWordsPerSecond  float64  // Drop text with more words than plausible for the speech duration (0 = disabled)
+}
+
+// FilterReason describes why the filter changed a transcription
+type FilterReason string
+
+const (
+	FilterReasonNone              FilterReason = ""
+	FilterReasonNonSpeechTags     FilterReason = "non_speech_tags"
+	FilterReasonStockPhrase       FilterReason = "stock_phrase"
+	FilterReasonRepetition        FilterReason = "repetition"
+	FilterReasonImplausibleLength FilterReason = "implausible_length"
+)
+
+// HallucinationFilter removes boilerplate Whisper produces on near-silent or noisy audio
+type HallucinationFilter struct {
+	config       FilterConfig
+	stockPhrases map[string]bool
+
+	// Counters (how often each rule fired)
+	checked           atomic.Uint64
+	nonSpeechTags     atomic.Uint64
+	stockPhrase       atomic.Uint64
+	repetition        atomic.Uint64
+	implausibleLength atomic.Uint64
+	dropped           atomic.Uint64
+}
+
+// FilterStats holds hallucination filter counters
+type FilterStats struct {
+	Checked           uint64 // Transcriptions that went through the filter
+	NonSpeechTags     uint64 // Transcriptions with non-speech tags stripped
+	StockPhrases      uint64 // Transcriptions dropped as stock phrases
+	RepetitionLoops   uint64 // Transcriptions with repetition loops collapsed
+	ImplausibleLength uint64 // Transcriptions dropped for too many words per second of speech
+	Dropped           uint64 // Transcriptions that ended up empty
+}
+
+// NonSpeechTags are the annotations Whisper emits for non-speech audio
+// Only these are stripped from within text, so "fmt.Println(x)" or "a * b * c" survive
+var NonSpeechTags = []string{
+	"blank_audio", "blank audio", "silence", "no speech", "inaudible", "unintelligible",
+	"music", "music playing", "upbeat music", "soft music", "applause", "laughs", "laughter",
+	"laughing", "chuckles", "coughs", "coughing", "sighs", "sniffs", "clears throat",
+	"noise", "background noise", "static", "beep", "beeping", "typing", "clicking",
+	"keyboard clicking", "footsteps", "wind", "breathing", "sound",
+}
+
+// nonSpeechTagPattern matches known non-speech tags in brackets, parentheses or
+// asterisks, and music symbols
+var nonSpeechTagPattern = buildNonSpeechTagPattern(NonSpeechTags)
+
+// bracketedOnlyPattern matches text made up entirely of bracketed annotations
+// (e.g. "[Speaking in foreign language]"), which is never speech
+var bracketedOnlyPattern = regexp.MustCompile(`^(?:\s*(?:\[[^\]]*\]|\([^)]*\)|\*[^*]*\*|[♪♫]+))+\s*$`)
+
+// buildNonSpeechTagPattern builds a case-insensitive pattern for the given tags
+func buildNonSpeechTagPattern(tags []string) *regexp.Regexp {
+	quoted := make([]string, len(tags))
+	for i, tag := range tags {
+		quoted[i] = strings.ReplaceAll(regexp.QuoteMeta(tag), " ", `[\s_]+`)
+	}
+	alternatives := strings.Join(quoted, "|")
+	return regexp.MustCompile(`(?i)\[\s*(?:` + alternatives + `)\s*\]|\(\s*(?:` + alternatives + `)\s*\)|\*\s*(?:` + alternatives + `)\s*\*|[♪♫]+`)
+}
+
+// NewHallucinationFilter creates a new hallucination filter
+func NewHallucinationFilter(config FilterConfig) *HallucinationFilter {
+	phrases := config.StockPhrases
+	if len(phrases) == 0 {
+		phrases = DefaultStockPhrases
+	}
+
+	stockPhrases := make(map[string]bool, len(phrases))
+	for _, phrase := range phrases {
+		stockPhrases[normalizeText(phrase)] = true
+	}
+
+	return &HallucinationFilter{
+		config:       config,
+		stockPhrases: stockPhrases,
+	}
+}
+
+// Apply filters a transcription of a chunk containing the given amount of speech (per VAD)
+// Returns the cleaned text (empty if the whole text was rejected) and the last rule that fired
+func (f *HallucinationFilter) Apply(text string, speech time.Duration) (string, FilterReason) {
+	if !f.config.Enabled || text == "" {
+		return text, FilterReasonNone
+	}
+
+	f.checked.Add(1)
+	reason := FilterReasonNone
+
+	// 1. Bracketed non-speech tags
+	if f.config.StripNonSpeechTags {
+		stripped := strings.Join(strings.Fields(nonSpeechTagPattern.ReplaceAllString(text, " ")), " ")
+		if bracketedOnlyPattern.MatchString(stripped) {
+			stripped = ""
+		}
+		if stripped != text {
+			f.nonSpeechTags.Add(1)
+			reason = FilterReasonNonSpeechTags
+			text = stripped
+		}
+	}
+
+	// 2. Repetition loops (collapsed first so a looped stock phrase is still recognized)
+	if text != "" && f.config.MaxRepeats > 0 {
+		if collapsed, ok := collapseRepeats(text, f.config.MaxRepeats); ok {
+			f.repetition.Add(1)
+			reason = FilterReasonRepetition
+			text = collapsed
+		}
+	}
+
+	// 3. Known stock phrases (only when they are the entire text)
+	if text != "" && f.stockPhrases[normalizeText(text)] {
+		f.stockPhrase.Add(1)
+		reason = FilterReasonStockPhrase
+		text = ""
+	}
+
+	// 4. More words than could have been spoken in the detected speech
+	if text != "" && f.config.MaxWordsPerSecond > 0 {
+		words := len(strings.Fields(text))
+		allowed := int(math.Ceil(f.config.MaxWordsPerSecond*speech.Seconds())) + 2 // Slack for very short utterances
+		if words > allowed {
+			f.implausibleLength.Add(1)
+			reason = FilterReasonImplausibleLength
+			text = ""
+		}
+	}
+
+	if text == "" {
+		f.dropped.Add(1)
+	}
+
+	return text, reason
+}
+
+// Stats returns the filter counters
+func (f *HallucinationFilter) Stats() FilterStats {
+	return FilterStats{
+		Checked:           f.checked.Load(),
+		NonSpeechTags:     f.nonSpeechTags.Load(),
+		StockPhrases:      f.stockPhrase.Load(),
+		RepetitionLoops:   f.repetition.Load(),
+		ImplausibleLength: f.implausibleLength.Load(),
+		Dropped:           f.dropped.Load(),
+	}
+}
+
+// collapseRepeats collapses any n-gram (1-4 words) repeated consecutively more than maxRepeats times
+// Returns the collapsed text and whether anything changed
+func collapseRepeats(text string, maxRepeats int) (string, bool) {
+	words := strings.Fields(text)
+	changed := false
+
+	for n := 1; n <= 4; n++ {
+		var out []string
+		i := 0
+		for i < len(words) {
+			if i+n > len(words) {
+				out = append(out, words[i:]...)
+				break
+			}
+
+			// Count consecutive repeats of words[i:i+n]
+			repeats := 1
+			for j := i + n; j+n <= len(words) && sameWords(words[i:i+n], words[j:j+n]); j += n {
+				repeats++
+			}
+
+			if repeats > maxRepeats {
+				out = append(out, words[i:i+n]...)
+				i += repeats * n
+				changed = true
+			} else {
+				out = append(out, words[i])
+				i++
+			}
+		}
+		words = out
+	}
+
+	return strings.Join(words, " "), changed
+}
+
+// sameWords compares two word slices ignoring case and punctuation
+func sameWords(a, b []string) bool {
+	for i := range a {
+		if normalizeText(a[i]) != normalizeText(b[i]) {
+			return false
+		}
+	}
+	return true
+}
+
+// normalizeText lowercases text and strips punctuation for comparisons
+func normalizeText(text string) string {
+	var b strings.Builder
+	for _, r := range strings.ToLower(text) {
+		if unicode.IsLetter(r) || unicode.IsDigit(r) || unicode.IsSpace(r) || r == '\'' || r == '.' {
+			b.WriteRune(r)
+		}
+	}
+	return strings.Join(strings.Fields(strings.Trim(b.String(), ". ")), " ")
+}
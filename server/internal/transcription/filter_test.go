@@ -0,0 +1,144 @@
+package transcription
+
+import (
+	"testing"
+	"time"
+)
+
+func newTestFilter() *HallucinationFilter {
+	return NewHallucinationFilter(FilterConfig{
+		Enabled:            true,
+		StripNonSpeechTags: true,
+		MaxRepeats:         3,
+		MaxWordsPerSecond:  6.0,
+	})
+}
+
+func TestFilterNonSpeechTags(t *testing.T) {
+	f := newTestFilter()
+
+	text, reason := f.Apply("[BLANK_AUDIO]", 2*time.Second)
+	if text != "" || reason != FilterReasonNonSpeechTags {
+		t.Errorf("Expected tag-only text to be dropped, got %q (%s)", text, reason)
+	}
+
+	text, _ = f.Apply("Hello (music) world ♪", 2*time.Second)
+	if text != "Hello world" {
+		t.Errorf("Expected tags stripped, got %q", text)
+	}
+
+	text, _ = f.Apply("So *laughs* [Applause] anyway", 2*time.Second)
+	if text != "So anyway" {
+		t.Errorf("Expected known tags stripped, got %q", text)
+	}
+
+	// Unknown annotations are only dropped when they are the whole text
+	text, reason = f.Apply("[Speaking in foreign language]", 2*time.Second)
+	if text != "" || reason != FilterReasonNonSpeechTags {
+		t.Errorf("Expected annotation-only text to be dropped, got %q (%s)", text, reason)
+	}
+}
+
+func TestFilterKeepsCodeLikeText(t *testing.T) {
+	f := newTestFilter()
+
+	for _, input := range []string{
+		"call fmt.Println(x) now",
+		"multiply a * b * c",
+		"index items[i] in the loop",
+		"wrap it in (parentheses) please",
+	} {
+		if text, reason := f.Apply(input, 3*time.Second); text != input {
+			t.Errorf("Expected %q to be kept, got %q (%s)", input, text, reason)
+		}
+	}
+}
+
+func TestFilterStockPhrases(t *testing.T) {
+	f := newTestFilter()
+
+	for _, input := range []string{"Thank you for watching!", " thanks for watching.", "You"} {
+		text, reason := f.Apply(input, time.Second)
+		if text != "" || reason != FilterReasonStockPhrase {
+			t.Errorf("Expected %q to be dropped as stock phrase, got %q (%s)", input, text, reason)
+		}
+	}
+
+	// Stock phrases inside real speech are kept
+	input := "I said thank you for watching the kids"
+	if text, _ := f.Apply(input, 3*time.Second); text != input {
+		t.Errorf("Expected %q to be kept, got %q", input, text)
+	}
+}
+
+func TestFilterRepetitionLoops(t *testing.T) {
+	f := newTestFilter()
+
+	text, reason := f.Apply("so so so so so we should go", 3*time.Second)
+	if text != "so we should go" || reason != FilterReasonRepetition {
+		t.Errorf("Expected single-word loop collapsed, got %q (%s)", text, reason)
+	}
+
+	text, _ = f.Apply("I think that I think that I think that I think that is fine", 5*time.Second)
+	if text != "I think that is fine" {
+		t.Errorf("Expected phrase loop collapsed, got %q", text)
+	}
+
+	// A looped stock phrase collapses and is then dropped
+	text, _ = f.Apply("Thank you for watching. Thank you for watching. Thank you for watching. Thank you for watching.", 5*time.Second)
+	if text != "" {
+		t.Errorf("Expected looped stock phrase dropped, got %q", text)
+	}
+
+	// Repeats at or below the limit are legitimate
+	input := "no no no that is wrong"
+	if text, _ := f.Apply(input, 3*time.Second); text != input {
+		t.Errorf("Expected %q to be kept, got %q", input, text)
+	}
+}
+
+func TestFilterImplausibleLength(t *testing.T) {
+	f := newTestFilter()
+
+	// 12 words from 0.5s of speech is implausible (allowed: ceil(6*0.5)+2 = 5)
+	text, reason := f.Apply("this is a much longer sentence than anyone could say that fast", 500*time.Millisecond)
+	if text != "" || reason != FilterReasonImplausibleLength {
+		t.Errorf("Expected implausible text to be dropped, got %q (%s)", text, reason)
+	}
+
+	input := "this is a normal sentence"
+	if text, _ := f.Apply(input, 2*time.Second); text != input {
+		t.Errorf("Expected %q to be kept, got %q", input, text)
+	}
+}
+
+func TestFilterStats(t *testing.T) {
+	f := newTestFilter()
+
+	f.Apply("[BLANK_AUDIO]", time.Second)
+	f.Apply("thanks for watching", time.Second)
+	f.Apply("hello world", time.Second)
+
+	stats := f.Stats()
+	if stats.Checked != 3 {
+		t.Errorf("Expected 3 checked, got %d", stats.Checked)
+	}
+	if stats.NonSpeechTags != 1 || stats.StockPhrases != 1 {
+		t.Errorf("Unexpected rule counters: %+v", stats)
+	}
+	if stats.Dropped != 2 {
+		t.Errorf("Expected 2 dropped, got %d", stats.Dropped)
+	}
+}
+
+func TestFilterDisabled(t *testing.T) {
+	f := NewHallucinationFilter(FilterConfig{})
+
+	text, reason := f.Apply("[BLANK_AUDIO]", 0)
+	if text != "[BLANK_AUDIO]" || reason != FilterReasonNone {
+		t.Errorf("Disabled filter should not change text, got %q (%s)", text, reason)
+	}
+	if f.Stats().Checked != 0 {
+		t.Error("Disabled filter should not count")
+	}
+}
@@ -0,0 +1,114 @@
+package transcription
+
+import (
+	"errors"
+	"fmt"
+)
+
+const (
+	// MinInputSampleRate and MaxInputSampleRate bound the sample rates clients may send
+	MinInputSampleRate = 8000
+	MaxInputSampleRate = 192000
+	// MaxInputChannels bounds the channel count clients may send
+	MaxInputChannels = 8
+)
+
+// ErrUnsupportedFormat is returned for audio the pipeline cannot convert
+var ErrUnsupportedFormat = errors.New("unsupported audio format")
+
+// AudioInput converts a session's incoming 16-bit PCM to the pipeline's 16kHz mono
+// Channels are averaged, then a stateful resampler converts the rate, so the
+// stream can arrive in chunks of any size
+type AudioInput struct {
+	sampleRate int
+	channels   int
+	resampler  *Resampler // nil when the input is already at PipelineSampleRate
+}
+
+// NewAudioInput creates a converter for interleaved 16-bit PCM at the given format
+func NewAudioInput(sampleRate, channels int) (*AudioInput, error) {
+	if err := CheckInputFormat(sampleRate, channels); err != nil {
+		return nil, err
+	}
+
+	input := &AudioInput{sampleRate: sampleRate, channels: channels}
+	if sampleRate != PipelineSampleRate {
+		resampler, err := NewResampler(sampleRate, PipelineSampleRate)
+		if err != nil {
+			return nil, fmt.Errorf("%w: %v", ErrUnsupportedFormat, err)
+		}
+		input.resampler = resampler
+	}
+	return input, nil
+}
+
+// CheckInputFormat returns an ErrUnsupportedFormat error if audio at this format cannot be converted
+func CheckInputFormat(sampleRate, channels int) error {
+	if channels < 1 || channels > MaxInputChannels {
+		return fmt.Errorf("%w: %d channels (supported: 1-%d)", ErrUnsupportedFormat, channels, MaxInputChannels)
+	}
+	if sampleRate < MinInputSampleRate || sampleRate > MaxInputSampleRate {
+		return fmt.Errorf("%w: %dHz (supported: %d-%dHz)", ErrUnsupportedFormat, sampleRate, MinInputSampleRate, MaxInputSampleRate)
+	}
+	if up := PipelineSampleRate / gcd(sampleRate, PipelineSampleRate); up > maxResamplerPhases {
+		return fmt.Errorf("%w: %dHz cannot be resampled to %dHz (use a standard rate such as 44100 or 48000)",
+			ErrUnsupportedFormat, sampleRate, PipelineSampleRate)
+	}
+	return nil
+}
+
+// Matches returns true if the converter was created for this format
+func (in *AudioInput) Matches(sampleRate, channels int) bool {
+	return in.sampleRate == sampleRate && in.channels == channels
+}
+
+// Convert downmixes and resamples interleaved 16-bit little-endian PCM to 16kHz mono
+func (in *AudioInput) Convert(pcm []byte) ([]byte, error) {
+	frameSize := 2 * in.channels
+	if len(pcm)%frameSize != 0 {
+		return nil, fmt.Errorf("%w: %d bytes is not a whole number of %d-channel 16-bit frames",
+			ErrUnsupportedFormat, len(pcm), in.channels)
+	}
+	if in.channels == 1 && in.resampler == nil {
+		return pcm, nil
+	}
+
+	samples := bytesToInt16(pcm)
+	if in.channels > 1 {
+		mono := make([]int16, len(samples)/in.channels)
+		for i := range mono {
+			var sum int32
+			for _, s := range samples[i*in.channels : (i+1)*in.channels] {
+				sum += int32(s)
+			}
+			mono[i] = int16(sum / int32(in.channels))
+		}
+		samples = mono
+	}
+
+	if in.resampler != nil {
+		samples = in.resampler.ProcessInt16(samples)
+	}
+	return int16ToBytes(samples), nil
+}
+
+// bytesToInt16 converts 16-bit little-endian PCM to samples
+func bytesToInt16(data []byte) []int16 {
+	samples := make([]int16, len(data)/2)
+	for i := 0; i < len(samples); i++ {
+		// Little-endian conversion
+		samples[i] = int16(data[i*2]) | int16(data[i*2+1])<<8
+	}
+	return samples
+}
+
+// int16ToBytes converts samples to 16-bit little-endian PCM
+func int16ToBytes(samples []int16) []byte {
+	data := make([]byte, len(samples)*2)
+	for i, sample := range samples {
+		// Little-endian conversion
+		data[i*2] = byte(sample)
+		data[i*2+1] = byte(sample >> 8)
+	}
+	return data
+}
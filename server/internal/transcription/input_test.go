@@ -0,0 +1,93 @@
+package transcription
+
+import (
+	"errors"
+	"testing"
+)
+
+func TestCheckInputFormat(t *testing.T) {
+	for _, format := range [][2]int{{16000, 1}, {48000, 2}, {44100, 1}, {8000, 1}, {11025, 2}, {96000, 8}} {
+		if err := CheckInputFormat(format[0], format[1]); err != nil {
+			t.Errorf("Expected %dHz, %d channels to be supported, got %v", format[0], format[1], err)
+		}
+	}
+
+	for _, format := range [][2]int{{16000, 0}, {16000, 9}, {4000, 1}, {384000, 1}, {44101, 1}} {
+		if err := CheckInputFormat(format[0], format[1]); !errors.Is(err, ErrUnsupportedFormat) {
+			t.Errorf("Expected ErrUnsupportedFormat for %dHz, %d channels, got %v", format[0], format[1], err)
+		}
+	}
+}
+
+func TestAudioInputPassesThroughPipelineFormat(t *testing.T) {
+	input, err := NewAudioInput(16000, 1)
+	if err != nil {
+		t.Fatalf("NewAudioInput failed: %v", err)
+	}
+
+	pcm := int16ToBytes([]int16{1, -2, 3})
+	converted, err := input.Convert(pcm)
+	if err != nil {
+		t.Fatalf("Convert failed: %v", err)
+	}
+	if string(converted) != string(pcm) {
+		t.Errorf("Expected 16kHz mono to pass through, got %v", bytesToInt16(converted))
+	}
+}
+
+func TestAudioInputDownmixesChannels(t *testing.T) {
+	input, err := NewAudioInput(16000, 2)
+	if err != nil {
+		t.Fatalf("NewAudioInput failed: %v", err)
+	}
+
+	converted, err := input.Convert(int16ToBytes([]int16{100, 300, -1000, 1000, 32767, 32767}))
+	if err != nil {
+		t.Fatalf("Convert failed: %v", err)
+	}
+
+	mono := bytesToInt16(converted)
+	expected := []int16{200, 0, 32767}
+	if len(mono) != len(expected) {
+		t.Fatalf("Expected %d samples, got %d", len(expected), len(mono))
+	}
+	for i := range expected {
+		if mono[i] != expected[i] {
+			t.Errorf("Sample %d: expected %d, got %d", i, expected[i], mono[i])
+		}
+	}
+}
+
+func TestAudioInputResamplesAcrossChunks(t *testing.T) {
+	input, err := NewAudioInput(48000, 2)
+	if err != nil {
+		t.Fatalf("NewAudioInput failed: %v", err)
+	}
+
+	// One second of 48kHz stereo in 200ms chunks becomes one second of 16kHz mono
+	chunk := int16ToBytes(make([]int16, 48000/5*2))
+	total := 0
+	for i := 0; i < 5; i++ {
+		converted, err := input.Convert(chunk)
+		if err != nil {
+			t.Fatalf("Convert failed: %v", err)
+		}
+		total += len(converted) / 2
+	}
+	if total < 15999 || total > 16001 {
+		t.Errorf("Expected about 16000 samples, got %d", total)
+	}
+}
+
+func TestAudioInputRejectsPartialFrames(t *testing.T) {
+	input, err := NewAudioInput(16000, 2)
+	if err != nil {
+		t.Fatalf("NewAudioInput failed: %v", err)
+	}
+	if _, err := input.Convert(make([]byte, 6)); !errors.Is(err, ErrUnsupportedFormat) {
+		t.Errorf("Expected ErrUnsupportedFormat for 1.5 stereo frames, got %v", err)
+	}
+	if !input.Matches(16000, 2) || input.Matches(48000, 2) {
+		t.Error("Matches does not reflect the input format")
+	}
+}
@@ -0,0 +1,381 @@
+package transcription
+
+import (
+	"bufio"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"sync"
+
+	"github.com/lucianHymer/streaming-transcription/server/internal/metrics"
+	"github.com/lucianHymer/streaming-transcription/shared/logger"
+)
+
+// ErrResultDropped marks a result that could not be delivered (its text is lost)
+var ErrResultDropped = errors.New("transcription result dropped")
+
+// OutboxConfig holds configuration for a session's result outbox
+type OutboxConfig struct {
+	MemorySize  int    // Results held in memory before spilling to disk (default: 64)
+	SpillDir    string // Directory for spill files (empty = os.TempDir())
+	HistorySize int    // Delivered finals kept for re-delivery (default: 256)
+}
+
+// Outbox is a bounded, lossless queue of results for one session
+// Final results never block the producer: once the memory queue is full they are
+// spilled to a file and read back in order. Partials are only queued if there is room.
+// Delivered finals are kept in a history so clients can request re-delivery.
+type Outbox struct {
+	mu   sync.Mutex
+	cond *sync.Cond
+	out  chan TranscriptionResult
+	done chan struct{} // Closed by Close
+	log  *logger.ContextLogger
+
+	queue      []TranscriptionResult
+	memorySize int
+	closed     bool
+	sending    bool // A result taken from the queue is waiting for the consumer
+
+	// Spill file (results that did not fit in memory, oldest first)
+	spillDir    string
+	spillFile   *os.File
+	spillWriter *bufio.Writer
+	spillRead   *os.File
+	spillReader *bufio.Reader
+	spilled     int      // Results in the spill file not yet read back
+	spillIndex  []uint64 // Chunk indices of those results, oldest first
+
+	// Delivered finals, for re-delivery
+	history     map[uint64]TranscriptionResult
+	historyKeys []uint64
+	historySize int
+
+	// Statistics
+	totalSpilled uint64
+	totalDropped uint64
+}
+
+// OutboxStats holds outbox statistics
+type OutboxStats struct {
+	Queued  int    // Results waiting in memory
+	Spilled int    // Results waiting on disk
+	Total   uint64 // Results spilled to disk since the session started
+	Dropped uint64 // Results that could not be delivered
+}
+
+// spillRecord is the on-disk form of a result (errors are stored as text)
+type spillRecord struct {
+	Result TranscriptionResult
+	Error  string
+}
+
+// NewOutbox creates an outbox and starts delivering to its Results channel
+func NewOutbox(config OutboxConfig, log *logger.Logger) *Outbox {
+	if config.MemorySize <= 0 {
+		config.MemorySize = 64
+	}
+	if config.HistorySize <= 0 {
+		config.HistorySize = 256
+	}
+	if config.SpillDir == "" {
+		config.SpillDir = os.TempDir()
+	}
+
+	o := &Outbox{
+		out:         make(chan TranscriptionResult),
+		done:        make(chan struct{}),
+		log:         log.With("outbox"),
+		memorySize:  config.MemorySize,
+		spillDir:    config.SpillDir,
+		history:     make(map[uint64]TranscriptionResult),
+		historySize: config.HistorySize,
+	}
+	o.cond = sync.NewCond(&o.mu)
+
+	go o.deliver()
+
+	return o
+}
+
+// Push queues a final result, spilling to disk if the memory queue is full
+// If the spill fails the result is replaced by an ErrResultDropped marker so the
+// client is told about the loss
+func (o *Outbox) Push(result TranscriptionResult) {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+
+	if o.closed {
+		return
+	}
+
+	// Keep order: once anything is on disk, later results go to disk too
+	if o.spilled == 0 && len(o.queue) < o.memorySize {
+		o.queue = append(o.queue, result)
+		o.cond.Signal()
+		return
+	}
+
+	if err := o.spill(result); err != nil {
+		o.totalDropped++
+		metrics.ResultsDropped.With(metrics.DropReasonSpillFailed).Inc()
+		o.log.ErrorWithFields("Failed to spill result, dropping it", map[string]interface{}{
+			"chunk": result.ChunkIndex,
+			"error": err.Error(),
+		})
+		// The marker bypasses the size limit; it carries no text
+		o.queue = append(o.queue, TranscriptionResult{
+			ChunkIndex: result.ChunkIndex,
+			Timestamp:  result.Timestamp,
+			Error:      ErrResultDropped,
+		})
+	}
+	o.cond.Signal()
+}
+
+// Offer queues a disposable result (a partial) only if it can be delivered right away
+func (o *Outbox) Offer(result TranscriptionResult) bool {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+
+	if o.closed || o.spilled > 0 || len(o.queue) > 0 {
+		return false
+	}
+	o.queue = append(o.queue, result)
+	o.cond.Signal()
+	return true
+}
+
+// Redeliver queues delivered finals again, returning the indices no longer in the history
+func (o *Outbox) Redeliver(indices []uint64) []uint64 {
+	var missing []uint64
+	for _, index := range indices {
+		o.mu.Lock()
+		result, ok := o.history[index]
+		o.mu.Unlock()
+
+		if !ok {
+			missing = append(missing, index)
+			continue
+		}
+		result.Redelivered = true
+		o.Push(result)
+	}
+	return missing
+}
+
+// spill appends a result to the spill file (caller holds o.mu)
+func (o *Outbox) spill(result TranscriptionResult) error {
+	if o.spillFile == nil {
+		file, err := os.CreateTemp(o.spillDir, "transcription-outbox-*.jsonl")
+		if err != nil {
+			return fmt.Errorf("failed to create spill file: %w", err)
+		}
+		o.spillFile = file
+		o.spillWriter = bufio.NewWriter(file)
+		o.log.Warn("Client is not keeping up, spilling results to %s", file.Name())
+	}
+
+	record := spillRecord{Result: result}
+	if result.Error != nil {
+		record.Error = result.Error.Error()
+		record.Result.Error = nil
+	}
+
+	data, err := json.Marshal(record)
+	if err != nil {
+		return fmt.Errorf("failed to encode result: %w", err)
+	}
+	if _, err := o.spillWriter.Write(append(data, '\n')); err != nil {
+		return fmt.Errorf("failed to write spill file: %w", err)
+	}
+	if err := o.spillWriter.Flush(); err != nil {
+		return fmt.Errorf("failed to write spill file: %w", err)
+	}
+
+	o.spilled++
+	o.spillIndex = append(o.spillIndex, result.ChunkIndex)
+	o.totalSpilled++
+	return nil
+}
+
+// unspill reads spilled results back into the memory queue (caller holds o.mu)
+func (o *Outbox) unspill() {
+	if o.spillReader == nil {
+		file, err := os.Open(o.spillFile.Name())
+		if err != nil {
+			o.failSpill(err)
+			return
+		}
+		o.spillRead = file
+		o.spillReader = bufio.NewReader(file)
+	}
+
+	for o.spilled > 0 && len(o.queue) < o.memorySize {
+		line, err := o.spillReader.ReadBytes('\n')
+		if err != nil {
+			o.failSpill(err)
+			return
+		}
+
+		var record spillRecord
+		if err := json.Unmarshal(line, &record); err != nil {
+			o.failSpill(err)
+			return
+		}
+		if record.Error != "" {
+			record.Result.Error = errors.New(record.Error)
+		}
+		o.queue = append(o.queue, record.Result)
+		o.spilled--
+		o.spillIndex = o.spillIndex[1:]
+	}
+
+	if o.spilled == 0 {
+		o.removeSpill()
+	}
+}
+
+// failSpill gives up on the spill file, reporting everything in it as dropped (caller holds o.mu)
+func (o *Outbox) failSpill(err error) {
+	o.log.Error("Failed to read spill file, %d results lost: %v", o.spilled, err)
+	o.totalDropped += uint64(o.spilled)
+	metrics.ResultsDropped.With(metrics.DropReasonSpillFailed).Add(float64(o.spilled))
+	for _, index := range o.spillIndex {
+		o.queue = append(o.queue, TranscriptionResult{
+			ChunkIndex: index,
+			Timestamp:  currentTimeMillis(),
+			Error:      ErrResultDropped,
+		})
+	}
+	o.spilled = 0
+	o.spillIndex = nil
+	o.removeSpill()
+}
+
+// removeSpill closes and deletes the spill file (caller holds o.mu)
+func (o *Outbox) removeSpill() {
+	if o.spillFile == nil {
+		return
+	}
+	o.spillFile.Close()
+	if o.spillRead != nil {
+		o.spillRead.Close()
+	}
+	os.Remove(o.spillFile.Name())
+	o.spillFile = nil
+	o.spillWriter = nil
+	o.spillRead = nil
+	o.spillReader = nil
+}
+
+// deliver moves results from the queue to the Results channel, blocking on the consumer
+func (o *Outbox) deliver() {
+	defer close(o.out)
+
+	for {
+		o.mu.Lock()
+		for len(o.queue) == 0 && o.spilled == 0 && !o.closed {
+			o.cond.Wait()
+		}
+		if o.closed {
+			o.mu.Unlock()
+			return
+		}
+		if len(o.queue) == 0 {
+			o.unspill()
+			if len(o.queue) == 0 {
+				o.mu.Unlock()
+				continue
+			}
+		}
+
+		result := o.queue[0]
+		o.queue = o.queue[1:]
+		if !result.IsPartial && result.Error == nil && !result.Redelivered {
+			o.remember(result)
+		}
+		o.sending = true
+		o.mu.Unlock()
+
+		// Blocks while the client is slow; Push keeps accepting meanwhile
+		select {
+		case o.out <- result:
+		case <-o.done:
+			return
+		}
+
+		o.mu.Lock()
+		o.sending = false
+		o.mu.Unlock()
+	}
+}
+
+// remember adds a delivered final to the re-delivery history (caller holds o.mu)
+func (o *Outbox) remember(result TranscriptionResult) {
+	if _, ok := o.history[result.ChunkIndex]; !ok {
+		o.historyKeys = append(o.historyKeys, result.ChunkIndex)
+	}
+	o.history[result.ChunkIndex] = result
+
+	for len(o.historyKeys) > o.historySize {
+		delete(o.history, o.historyKeys[0])
+		o.historyKeys = o.historyKeys[1:]
+	}
+}
+
+// Recent returns up to n of the most recently delivered finals, oldest first
+func (o *Outbox) Recent(n int) []TranscriptionResult {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+
+	keys := o.historyKeys[max(len(o.historyKeys)-n, 0):]
+	recent := make([]TranscriptionResult, 0, len(keys))
+	for _, index := range keys {
+		recent = append(recent, o.history[index])
+	}
+	return recent
+}
+
+// Results returns the channel results are delivered on (closed by Close)
+func (o *Outbox) Results() <-chan TranscriptionResult {
+	return o.out
+}
+
+// Empty reports whether every result pushed so far has been handed to the consumer
+func (o *Outbox) Empty() bool {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+	return len(o.queue) == 0 && o.spilled == 0 && !o.sending
+}
+
+// Stats returns the outbox statistics
+func (o *Outbox) Stats() OutboxStats {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+
+	return OutboxStats{
+		Queued:  len(o.queue),
+		Spilled: o.spilled,
+		Total:   o.totalSpilled,
+		Dropped: o.totalDropped,
+	}
+}
+
+// Close stops delivery, discards undelivered results and removes the spill file
+func (o *Outbox) Close() {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+
+	if o.closed {
+		return
+	}
+	o.closed = true
+	o.queue = nil
+	o.spilled = 0
+	o.spillIndex = nil
+	o.removeSpill()
+	close(o.done)
+	o.cond.Broadcast()
+}
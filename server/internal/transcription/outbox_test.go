@@ -0,0 +1,118 @@
+package transcription
+
+import (
+	"errors"
+	"os"
+	"testing"
+	"time"
+
+	"github.com/lucianHymer/streaming-transcription/shared/logger"
+)
+
+func receive(t *testing.T, o *Outbox) TranscriptionResult {
+	t.Helper()
+	select {
+	case result := <-o.Results():
+		return result
+	case <-time.After(time.Second):
+		t.Fatal("Timed out waiting for result")
+		return TranscriptionResult{}
+	}
+}
+
+func TestOutboxSpillsInOrder(t *testing.T) {
+	dir := t.TempDir()
+	o := NewOutbox(OutboxConfig{MemorySize: 2, SpillDir: dir}, logger.New(false))
+	defer o.Close()
+
+	// Nobody is reading, so everything past the memory queue spills
+	for i := uint64(0); i < 10; i++ {
+		o.Push(TranscriptionResult{ChunkIndex: i, Text: "chunk"})
+	}
+	if stats := o.Stats(); stats.Spilled == 0 {
+		t.Fatalf("Expected results to spill, got %+v", stats)
+	}
+	if o.Empty() {
+		t.Error("Expected the outbox not to be empty with results waiting")
+	}
+
+	for i := uint64(0); i < 10; i++ {
+		if result := receive(t, o); result.ChunkIndex != i {
+			t.Fatalf("Expected chunk %d, got %d", i, result.ChunkIndex)
+		}
+	}
+
+	if stats := o.Stats(); stats.Spilled != 0 || stats.Dropped != 0 {
+		t.Errorf("Unexpected stats after draining: %+v", stats)
+	}
+	if entries, _ := os.ReadDir(dir); len(entries) != 0 {
+		t.Errorf("Expected spill file to be removed, found %d files", len(entries))
+	}
+}
+
+func TestOutboxDropsUnspillableResults(t *testing.T) {
+	o := NewOutbox(OutboxConfig{MemorySize: 1, SpillDir: "/nonexistent/spill"}, logger.New(false))
+	defer o.Close()
+
+	o.Push(TranscriptionResult{ChunkIndex: 0, Text: "kept"})
+	o.Push(TranscriptionResult{ChunkIndex: 1, Text: "lost"})
+
+	if result := receive(t, o); result.ChunkIndex != 0 || result.Text != "kept" {
+		t.Fatalf("Expected chunk 0, got %+v", result)
+	}
+	result := receive(t, o)
+	if result.ChunkIndex != 1 || !errors.Is(result.Error, ErrResultDropped) {
+		t.Fatalf("Expected drop marker for chunk 1, got %+v", result)
+	}
+	if stats := o.Stats(); stats.Dropped != 1 {
+		t.Errorf("Expected 1 dropped result, got %+v", stats)
+	}
+}
+
+func TestOutboxPartialsAndRedelivery(t *testing.T) {
+	o := NewOutbox(OutboxConfig{}, logger.New(false))
+	defer o.Close()
+
+	if !o.Offer(TranscriptionResult{ChunkIndex: 0, Text: "partial", IsPartial: true}) {
+		t.Fatal("Expected partial to be accepted by an empty outbox")
+	}
+	if o.Offer(TranscriptionResult{ChunkIndex: 0, Text: "newer partial", IsPartial: true}) {
+		t.Error("Expected partial to be refused while results are queued")
+	}
+	receive(t, o)
+
+	o.Push(TranscriptionResult{ChunkIndex: 0, Text: "final"})
+	receive(t, o)
+
+	missing := o.Redeliver([]uint64{0, 5})
+	if len(missing) != 1 || missing[0] != 5 {
+		t.Errorf("Expected chunk 5 to be unavailable, got %v", missing)
+	}
+	result := receive(t, o)
+	if result.ChunkIndex != 0 || result.Text != "final" || !result.Redelivered {
+		t.Errorf("Expected redelivered final for chunk 0, got %+v", result)
+	}
+}
+
+func TestOutboxRecent(t *testing.T) {
+	o := NewOutbox(OutboxConfig{HistorySize: 2}, logger.New(false))
+	defer o.Close()
+
+	if recent := o.Recent(5); len(recent) != 0 {
+		t.Errorf("Expected no recent results, got %+v", recent)
+	}
+
+	for i, text := range []string{"one", "two", "three"} {
+		o.Push(TranscriptionResult{ChunkIndex: uint64(i), Text: text})
+		receive(t, o)
+	}
+
+	// Remembered once the delivery loop picks the result up, before it is received
+	recent := o.Recent(5)
+	if len(recent) != 2 || recent[0].Text != "two" || recent[1].Text != "three" {
+		t.Errorf("Expected the last two finals oldest first, got %+v", recent)
+	}
+	if recent := o.Recent(1); len(recent) != 1 || recent[0].Text != "three" {
+		t.Errorf("Expected only the latest final, got %+v", recent)
+	}
+}
@@ -5,29 +5,61 @@ import (
 	"fmt"
 	"os"
 	"sync"
+	"sync/atomic"
 	"time"
 
+	"github.com/lucianHymer/streaming-transcription/server/internal/metrics"
 	"github.com/lucianHymer/streaming-transcription/shared/logger"
 )
 
+// drainPollInterval is how often Drain checks for undelivered results
+const drainPollInterval = 20 * time.Millisecond
+
 // TranscriptionPipeline handles the complete audio-to-text pipeline
 // Flow: Raw Audio → RNNoise → VAD/Chunker → Whisper → Results
 type TranscriptionPipeline struct {
-	whisper    *WhisperTranscriberShared // Uses shared model
-	rnnoise    *RNNoiseProcessor
-	chunker    *SmartChunker
-	resultChan chan TranscriptionResult
-	mu         sync.RWMutex
-	active     bool
-	debugWAV   bool // Enable WAV file debugging
-	log        *logger.ContextLogger
+	whisper   *WhisperTranscriberShared // Uses shared model
+	rnnoise   *RNNoiseProcessor
+	chunker   *SmartChunker
+	outbox    *Outbox // Lossless, ordered delivery of results to the client
+	mu        sync.RWMutex
+	active    bool
+	stoppedAt time.Time // When Stop was last called
+	debugWAV  bool      // Enable WAV file debugging
+	log       *logger.ContextLogger
+	session   uint64 // Session ID echoed in results sent to the client
+
+	// Conversion of the client's audio format to 16kHz mono
+	inputMu  sync.Mutex
+	input    *AudioInput
+	sequence *sequenceTracker // Gaps and duplicates in the client's chunk sequence IDs
+
+	// Post-processing of final results
+	filter            *HallucinationFilter
+	minConfidence     float64
+	dropLowConfidence bool
+	transcribed       atomic.Uint64 // Final chunks transcribed without error
+
+	// Partial transcription state
+	partialBusy atomic.Bool // A partial transcription is in flight
+
+	// Reordering of final results (chunks may finish transcription out of order)
+	orderMu    sync.Mutex
+	nextResult uint64                         // Index of the next final result to deliver
+	pending    map[uint64]TranscriptionResult // Finished results waiting for earlier chunks
 }
 
 // TranscriptionResult holds transcription output
 type TranscriptionResult struct {
-	Text      string
-	Timestamp int64 // Unix timestamp in milliseconds
-	Error     error
+	Text          string
+	Segments      []Segment // Segment and token timings in session time (since Start)
+	Timestamp     int64     // Unix timestamp in milliseconds
+	ChunkIndex    uint64    // Index of the chunk within the session (results are delivered in this order)
+	IsPartial     bool      // Interim hypothesis, superseded by the final result for the same chunk
+	Confidence    float64   // Mean token probability in [0, 1]
+	LowConfidence bool      // Confidence is below the configured threshold
+	Redelivered   bool      // Sent again at the client's request
+	Error         error
 }
 
 // PipelineConfig holds configuration for the transcription pipeline
@@ -40,8 +72,14 @@ type PipelineConfig struct {
 	MaxChunkDuration       time.Duration // Maximum chunk duration
 	VADEnergyThreshold     float64       // VAD energy threshold
 	SpeechDensityThreshold float64       // Speech density threshold for short utterances
-	ResultChannelSize      int           // Size of result channel buffer
+	PartialInterval        time.Duration // New speech between partial transcripts (0 = disabled)
+	PartialMaxDuration     time.Duration // No partials once the chunk is longer than this (0 = no limit)
+	MinConfidence          float64       // Final results below this confidence are flagged or dropped (0 = disabled)
+	DropLowConfidence      bool          // Drop low-confidence results instead of flagging them
+	Filter                 FilterConfig  // Hallucination/boilerplate filter for final results
+	Outbox                 OutboxConfig  // Result outbox (memory queue, spill to disk, re-delivery history)
 	EnableDebugWAV         bool          // Save WAV files for debugging
+	SessionID              uint64        // Identifies the session (control.start) in results sent to the client
 }
 
 // NewTranscriptionPipeline creates a new transcription pipeline
@@ -65,20 +103,20 @@ func NewTranscriptionPipeline(config PipelineConfig) (*TranscriptionPipeline, er
 		return nil, fmt.Errorf("failed to create RNNoise processor: %w", err)
 	}
 
-	// Result channel
-	resultChanSize := config.ResultChannelSize
-	if resultChanSize == 0 {
-		resultChanSize = 10
-	}
-	resultChan := make(chan TranscriptionResult, resultChanSize)
-
 	pipeline := &TranscriptionPipeline{
-		whisper:    whisper,
-		rnnoise:    rnnoise,
-		resultChan: resultChan,
-		active:     false,
-		debugWAV:   config.EnableDebugWAV,
-		log:        log,
+		whisper:  whisper,
+		rnnoise:  rnnoise,
+		outbox:   NewOutbox(config.Outbox, config.WhisperConfig.Logger),
+		active:   false,
+		debugWAV: config.EnableDebugWAV,
+		log:      log,
+		session:  config.SessionID,
+		sequence: newSequenceTracker(),
+		pending:  make(map[uint64]TranscriptionResult),
+
+		filter:            NewHallucinationFilter(config.Filter),
+		minConfidence:     config.MinConfidence,
+		dropLowConfidence: config.DropLowConfidence,
 	}
 
 	// Create smart chunker with VAD
@@ -90,6 +128,9 @@ func NewTranscriptionPipeline(config PipelineConfig) (*TranscriptionPipeline, er
 		VADEnergyThreshold:     config.VADEnergyThreshold,
 		SpeechDensityThreshold: config.SpeechDensityThreshold,
 		ChunkReadyCallback:     pipeline.transcribeChunk,
+		PartialInterval:        config.PartialInterval,
+		PartialMaxDuration:     config.PartialMaxDuration,
+		PartialCallback:        pipeline.transcribePartial,
 		Logger:                 config.WhisperConfig.Logger,
 	})
 
@@ -110,6 +151,7 @@ func (p *TranscriptionPipeline) ProcessChunk(audioData []byte, timestamp int64)
 	denoisedBytes, err := p.rnnoise.ProcessBytes(audioData)
 	if err != nil {
 		p.log.Warn("RNNoise error: %v", err)
+		metrics.RNNoiseErrors.Inc()
 		// Continue with original audio on error
 		denoisedBytes = audioData
 	}
@@ -127,13 +169,91 @@ func (p *TranscriptionPipeline) ProcessChunk(audioData []byte, timestamp int64)
 	return nil
 }
 
+// ProcessAudio converts 16-bit PCM in the client's format to 16kHz mono and processes it
+// The converter keeps resampler state for the session; a format change starts a new one
+// Duplicate and late chunks (by sequence ID) are dropped; missing chunks are replaced
+// with silence up to MaxGapFill, and longer gaps end the current chunk
+func (p *TranscriptionPipeline) ProcessAudio(audioData []byte, sampleRate, channels int, sequenceID uint64, timestamp int64) (SequenceCheck, error) {
+	// Held throughout so gap handling and audio stay in sequence order
+	p.inputMu.Lock()
+	defer p.inputMu.Unlock()
+
+	check, skipped := p.sequence.Check(sequenceID)
+	if check == SequenceDuplicate || check == SequenceLate {
+		p.log.Debug("Dropping audio chunk seq=%d (duplicate or late)", sequenceID)
+		return check, nil
+	}
+
+	converted, err := p.convertInput(audioData, sampleRate, channels)
+	if err != nil {
+		return check, err
+	}
+
+	if check == SequenceGap {
+		if err := p.fillGap(skipped, len(converted)/2, timestamp); err != nil {
+			return check, err
+		}
+	}
+	return check, p.ProcessChunk(converted, timestamp)
+}
+
+// ProcessUnsequencedAudio is ProcessAudio for audio without client sequence IDs,
+// whose order and losses the transport already handled (the media track's jitter buffer)
+func (p *TranscriptionPipeline) ProcessUnsequencedAudio(audioData []byte, sampleRate, channels int, timestamp int64) error {
+	p.inputMu.Lock()
+	defer p.inputMu.Unlock()
+
+	converted, err := p.convertInput(audioData, sampleRate, channels)
+	if err != nil {
+		return err
+	}
+	return p.ProcessChunk(converted, timestamp)
+}
+
+// convertInput converts client audio to 16kHz mono (caller holds inputMu)
+func (p *TranscriptionPipeline) convertInput(audioData []byte, sampleRate, channels int) ([]byte, error) {
+	if p.input == nil || !p.input.Matches(sampleRate, channels) {
+		input, err := NewAudioInput(sampleRate, channels)
+		if err != nil {
+			return nil, err
+		}
+		if p.input != nil {
+			p.log.Warn("Audio format changed mid-session to %dHz, %d channels", sampleRate, channels)
+		} else if sampleRate != PipelineSampleRate || channels != 1 {
+			p.log.Info("Converting %dHz, %d-channel audio to %dHz mono", sampleRate, channels, PipelineSampleRate)
+		}
+		p.input = input
+	}
+	return p.input.Convert(audioData)
+}
+
+// fillGap handles chunks missing before the current one, assuming they were as long as it is
+func (p *TranscriptionPipeline) fillGap(skipped uint64, chunkSamples int, timestamp int64) error {
+	gapSeconds := float64(skipped) * float64(chunkSamples) / PipelineSampleRate
+	if gapSeconds <= MaxGapFill.Seconds() {
+		p.log.Warn("%d audio chunks missing, filling %.2fs with silence", skipped, gapSeconds)
+		return p.ProcessChunk(make([]byte, int(skipped)*chunkSamples*2), timestamp)
+	}
+
+	p.log.Warn("%d audio chunks (about %.1fs) missing, ending the current chunk", skipped, gapSeconds)
+	if remaining := p.rnnoise.Flush(); len(remaining) > 0 {
+		p.chunker.ProcessSamples(remaining)
+	}
+	p.chunker.Flush()
+	return nil
+}
+
 // transcribeChunk is called by the chunker when a chunk is ready for transcription
-func (p *TranscriptionPipeline) transcribeChunk(samples []int16) {
+// Chunks are transcribed concurrently, so results go through deliverFinal to restore order
+func (p *TranscriptionPipeline) transcribeChunk(chunk Chunk) {
+	index, samples := chunk.Index, chunk.Samples
 	duration := float64(len(samples)) / 16000.0
+	metrics.ChunksCut.Inc()
+	metrics.ChunkDuration.Observe(duration)
 
 	// Save debug WAV if enabled
 	if p.debugWAV {
-		p.saveDebugWAV(samples)
+		p.saveDebugWAV(index, samples)
 	}
 
 	// Convert int16 samples to float32 for Whisper
@@ -143,37 +263,194 @@ func (p *TranscriptionPipeline) transcribeChunk(samples []int16) {
 	}
 
 	// Transcribe
-	text, err := p.whisper.Transcribe(floatSamples)
+	output, err := p.whisper.Transcribe(floatSamples)
 
-	// Send result
 	result := TranscriptionResult{
-		Text:      text,
-		Timestamp: currentTimeMillis(),
-		Error:     err,
+		Text:       output.Text,
+		Segments:   output.Shift(chunk.Offset),
+		Timestamp:  currentTimeMillis(),
+		ChunkIndex: index,
+		Confidence: output.Confidence,
+		Error:      err,
 	}
 
-	select {
-	case p.resultChan <- result:
-		if err != nil {
-			p.log.ErrorWithFields("Transcription failed", map[string]interface{}{
-				"duration": fmt.Sprintf("%.1fs", duration),
-				"error":    err.Error(),
-			})
-		} else {
-			p.log.InfoWithFields("Transcription complete", map[string]interface{}{
-				"duration": fmt.Sprintf("%.1fs", duration),
-				"text":     text,
-			})
-		}
-	default:
-		p.log.WarnWithFields("Result dropped (channel full)", map[string]interface{}{
+	if err != nil {
+		metrics.ResultsDropped.With(metrics.DropReasonError).Inc()
+		p.log.ErrorWithFields("Transcription failed", map[string]interface{}{
+			"chunk":    index,
 			"duration": fmt.Sprintf("%.1fs", duration),
+			"error":    err.Error(),
+		})
+	} else {
+		p.transcribed.Add(1)
+		p.log.InfoWithFields("Transcription complete", map[string]interface{}{
+			"chunk":      index,
+			"duration":   fmt.Sprintf("%.1fs", duration),
+			"confidence": fmt.Sprintf("%.2f", output.Confidence),
+			"text":       output.Text,
+		})
+		p.applyFilter(&result, chunk.Speech)
+		p.applyConfidenceThreshold(&result)
+	}
+
+	// Send result (errors included, so every index is accounted for)
+	p.deliverFinal(result)
+}
+
+// applyFilter runs the hallucination filter over a final result
+// Segment text is only rewritten for single-segment results; otherwise timings are kept as-is
+func (p *TranscriptionPipeline) applyFilter(result *TranscriptionResult, speech time.Duration) {
+	filtered, reason := p.filter.Apply(result.Text, speech)
+	if reason == FilterReasonNone {
+		return
+	}
+
+	p.log.InfoWithFields("Hallucination filter fired", map[string]interface{}{
+		"chunk":    result.ChunkIndex,
+		"reason":   string(reason),
+		"speech":   fmt.Sprintf("%.1fs", speech.Seconds()),
+		"original": result.Text,
+		"filtered": filtered,
+	})
+
+	result.Text = filtered
+	if filtered == "" {
+		metrics.ResultsDropped.With(metrics.DropReasonFiltered).Inc()
+		result.Segments = nil
+	} else if len(result.Segments) == 1 {
+		result.Segments[0].Text = filtered
+	}
+}
+
+// applyConfidenceThreshold flags or drops a final result whose confidence is too low
+// Dropped results keep their chunk index but carry no text, so ordering is unaffected
+func (p *TranscriptionPipeline) applyConfidenceThreshold(result *TranscriptionResult) {
+	if p.minConfidence <= 0 || result.Text == "" || result.Confidence >= p.minConfidence {
+		return
+	}
+
+	if p.dropLowConfidence {
+		metrics.ResultsDropped.With(metrics.DropReasonLowConfidence).Inc()
+		p.log.WarnWithFields("Dropping low-confidence transcription", map[string]interface{}{
+			"chunk":      result.ChunkIndex,
+			"confidence": fmt.Sprintf("%.2f", result.Confidence),
+			"text":       result.Text,
 		})
+		result.Text = ""
+		result.Segments = nil
+		return
+	}
+
+	result.LowConfidence = true
+	p.log.WarnWithFields("Low-confidence transcription", map[string]interface{}{
+		"chunk":      result.ChunkIndex,
+		"confidence": fmt.Sprintf("%.2f", result.Confidence),
+	})
+}
+
+// deliverFinal queues a final result and releases every result that is now in order
+// A result is only sent once all chunks cut before it have been delivered
+func (p *TranscriptionPipeline) deliverFinal(result TranscriptionResult) {
+	p.orderMu.Lock()
+	defer p.orderMu.Unlock()
+
+	p.pending[result.ChunkIndex] = result
+	if result.ChunkIndex != p.nextResult {
+		p.log.Debug("Holding chunk %d until chunk %d is transcribed", result.ChunkIndex, p.nextResult)
+	}
+
+	for {
+		next, ok := p.pending[p.nextResult]
+		if !ok {
+			return
+		}
+		delete(p.pending, p.nextResult)
+		p.nextResult++
+
+		// Feed delivered text to the rolling prompt context (in chunk order)
+		if next.Error == nil && !next.LowConfidence {
+			p.whisper.AppendContext(next.Text)
+		}
+
+		// Never blocks: the outbox spills to disk if the client falls behind
+		p.outbox.Push(next)
+	}
+}
+
+// transcribePartial is called by the chunker with a snapshot of the growing buffer
+// Produces an interim hypothesis that the final transcription of the chunk supersedes
+func (p *TranscriptionPipeline) transcribePartial(chunk Chunk) {
+	index, samples := chunk.Index, chunk.Samples
+
+	// Only one partial at a time - if Whisper is still busy, skip this snapshot
+	if !p.partialBusy.CompareAndSwap(false, true) {
+		return
+	}
+	defer p.partialBusy.Store(false)
+
+	floatSamples := make([]float32, len(samples))
+	for i, sample := range samples {
+		floatSamples[i] = float32(sample) / 32768.0
+	}
+
+	// Skipped without running Whisper if the chunk is cut while the job is queued
+	output, err := p.whisper.TranscribePartial(floatSamples, func() bool {
+		return p.chunkCut(index)
+	})
+	if err != nil {
+		p.log.Debug("Partial transcription failed: %v", err)
+		return
+	}
+	text := output.Text
+
+	p.offerPartial(TranscriptionResult{
+		Text:       text,
+		Segments:   output.Shift(chunk.Offset),
+		Timestamp:  currentTimeMillis(),
+		ChunkIndex: index,
+		IsPartial:  true,
+		Confidence: output.Confidence,
+	}, len(samples))
+}
+
+// offerPartial hands a partial result to the outbox unless it is stale
+// Returns true if the partial was queued for the client
+func (p *TranscriptionPipeline) offerPartial(result TranscriptionResult, numSamples int) bool {
+	index := result.ChunkIndex
+
+	p.orderMu.Lock()
+	defer p.orderMu.Unlock()
+
+	// Drop partials whose chunk has since been cut (the final supersedes them)
+	// or that would overtake finals of earlier chunks still being transcribed
+	if p.chunkCut(index) || index != p.nextResult {
+		p.log.Debug("Discarding stale partial for chunk %d: %q", index, result.Text)
+		return false
 	}
+
+	// Partials are disposable - only send them if nothing is waiting ahead
+	if !p.outbox.Offer(result) {
+		p.log.Debug("Partial skipped (results still queued)")
+		return false
+	}
+
+	p.log.DebugWithFields("Partial transcription", map[string]interface{}{
+		"chunk":    index,
+		"duration": fmt.Sprintf("%.1fs", float64(numSamples)/16000.0),
+		"text":     result.Text,
+	})
+	return true
+}
+
+// chunkCut returns true once a chunk has been cut (or the session stopped),
+// so partials of it are superseded by the final
+func (p *TranscriptionPipeline) chunkCut(index uint64) bool {
+	return index < p.chunker.GetStats().ChunksCut || !p.IsActive()
 }
 
 // saveDebugWAV saves a chunk to WAV file for debugging
-func (p *TranscriptionPipeline) saveDebugWAV(samples []int16) {
+// Files sort in chunk order, so a session can be replayed with the server's -replay flag
+func (p *TranscriptionPipeline) saveDebugWAV(index uint64, samples []int16) {
 	// Convert samples to bytes
 	pcmData := make([]byte, len(samples)*2)
 	for i, sample := range samples {
@@ -181,7 +458,7 @@ func (p *TranscriptionPipeline) saveDebugWAV(samples []int16) {
 		pcmData[i*2+1] = byte(sample >> 8)
 	}
 
-	wavPath := fmt.Sprintf("/tmp/chunk-%d.wav", time.Now().Unix())
+	wavPath := fmt.Sprintf("/tmp/chunk-%d-%04d.wav", time.Now().Unix(), index)
 	if err := saveWAV(wavPath, pcmData, 16000, 1, 16); err != nil {
 		p.log.Warn("Failed to save debug WAV: %v", err)
 	} else {
@@ -203,6 +480,7 @@ func (p *TranscriptionPipeline) Start() error {
 	// Reset components
 	p.chunker.Reset()
 	p.rnnoise.Reset()
+	p.whisper.ResetContext()
 
 	return nil
 }
@@ -215,6 +493,7 @@ func (p *TranscriptionPipeline) Stop() error {
 		return fmt.Errorf("pipeline not active")
 	}
 	p.active = false
+	p.stoppedAt = time.Now()
 	p.mu.Unlock()
 
 	// Flush any remaining audio in chunker
@@ -230,9 +509,54 @@ func (p *TranscriptionPipeline) Stop() error {
 	return nil
 }
 
+// Drain waits until every chunk cut so far has been transcribed and its result handed
+// to the consumer of Results, so a stopped session can be closed without losing its
+// last utterance. Returns false if the timeout passes or cancel is closed first
+func (p *TranscriptionPipeline) Drain(timeout time.Duration, cancel <-chan struct{}) bool {
+	deadline := time.NewTimer(timeout)
+	defer deadline.Stop()
+	ticker := time.NewTicker(drainPollInterval)
+	defer ticker.Stop()
+
+	for !p.drained() {
+		select {
+		case <-ticker.C:
+		case <-deadline.C:
+			return false
+		case <-cancel:
+			return false
+		}
+	}
+	return true
+}
+
+// drained reports whether all cut chunks have been delivered
+func (p *TranscriptionPipeline) drained() bool {
+	p.orderMu.Lock()
+	delivered := p.nextResult
+	p.orderMu.Unlock()
+	return delivered >= p.chunker.GetStats().ChunksCut && p.outbox.Empty()
+}
+
 // Results returns the channel for receiving transcription results
 func (p *TranscriptionPipeline) Results() <-chan TranscriptionResult {
-	return p.resultChan
+	return p.outbox.Results()
+}
+
+// SessionID returns the ID of the session this pipeline transcribes
+func (p *TranscriptionPipeline) SessionID() uint64 {
+	return p.session
+}
+
+// Redeliver queues previously delivered finals again (e.g. ones the client missed)
+// Returns the chunk indices that are no longer available
+func (p *TranscriptionPipeline) Redeliver(indices []uint64) []uint64 {
+	return p.outbox.Redeliver(indices)
+}
+
+// RecentTranscripts returns up to n of the most recently delivered finals, oldest first
+func (p *TranscriptionPipeline) RecentTranscripts(n int) []TranscriptionResult {
+	return p.outbox.Recent(n)
 }
 
 // GetRNNoise returns the RNNoise processor (for calibration endpoint)
@@ -242,11 +566,38 @@ func (p *TranscriptionPipeline) GetRNNoise() *RNNoiseProcessor {
 
 // Close releases all resources
 func (p *TranscriptionPipeline) Close() error {
+	// Report audio lost or repeated on the way from the client
+	// (before taking p.mu: ProcessAudio holds inputMu while it takes p.mu)
+	p.inputMu.Lock()
+	sequenceStats := p.sequence.Stats()
+	p.inputMu.Unlock()
+	if sequenceStats.Gaps > 0 || sequenceStats.Duplicates > 0 || sequenceStats.Late > 0 {
+		p.log.InfoWithFields("Audio sequence summary", map[string]interface{}{
+			"received":   sequenceStats.Received,
+			"gaps":       sequenceStats.Gaps,
+			"missing":    sequenceStats.Missing,
+			"duplicates": sequenceStats.Duplicates,
+			"late":       sequenceStats.Late,
+		})
+	}
+
 	p.mu.Lock()
 	defer p.mu.Unlock()
 
 	p.active = false
 
+	// Report how often the hallucination filter fired during the session
+	if stats := p.filter.Stats(); stats.Checked > 0 {
+		p.log.InfoWithFields("Hallucination filter summary", map[string]interface{}{
+			"checked":            stats.Checked,
+			"non_speech_tags":    stats.NonSpeechTags,
+			"stock_phrases":      stats.StockPhrases,
+			"repetition_loops":   stats.RepetitionLoops,
+			"implausible_length": stats.ImplausibleLength,
+			"dropped":            stats.Dropped,
+		})
+	}
+
 	if p.whisper != nil {
 		p.whisper.Close()
 	}
@@ -255,7 +606,7 @@ func (p *TranscriptionPipeline) Close() error {
 		p.rnnoise.Close()
 	}
 
-	close(p.resultChan)
+	p.outbox.Close()
 
 	return nil
 }
@@ -267,20 +618,39 @@ func (p *TranscriptionPipeline) IsActive() bool {
 	return p.active
 }
 
+// StoppedAt returns when the pipeline was last stopped (zero if never)
+func (p *TranscriptionPipeline) StoppedAt() time.Time {
+	p.mu.RLock()
+	defer p.mu.RUnlock()
+	return p.stoppedAt
+}
+
 // GetStats returns current pipeline statistics
 func (p *TranscriptionPipeline) GetStats() PipelineStats {
 	chunkerStats := p.chunker.GetStats()
 
+	p.inputMu.Lock()
+	sequenceStats := p.sequence.Stats()
+	p.inputMu.Unlock()
+
 	return PipelineStats{
-		Active:       p.IsActive(),
-		ChunkerStats: chunkerStats,
+		Active:        p.IsActive(),
+		Transcribed:   p.transcribed.Load(),
+		ChunkerStats:  chunkerStats,
+		FilterStats:   p.filter.Stats(),
+		OutboxStats:   p.outbox.Stats(),
+		SequenceStats: sequenceStats,
 	}
 }
 
 // PipelineStats holds pipeline statistics
 type PipelineStats struct {
-	Active       bool
-	ChunkerStats ChunkerStats
+	Active        bool
+	Transcribed   uint64 // Final chunks transcribed without error
+	ChunkerStats  ChunkerStats
+	FilterStats   FilterStats
+	OutboxStats   OutboxStats
+	SequenceStats SequenceStats
 }
 
 // saveWAV writes PCM audio data to a WAV file
@@ -318,6 +688,50 @@ func saveWAV(filename string, pcmData []byte, sampleRate, channels, bitsPerSampl
 	return nil
 }
 
+// LoadWAV reads a 16-bit mono PCM WAV file (as written by the debug WAV option)
+func LoadWAV(filename string) ([]int16, error) {
+	data, err := os.ReadFile(filename)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read file: %w", err)
+	}
+	if len(data) < 12 || string(data[0:4]) != "RIFF" || string(data[8:12]) != "WAVE" {
+		return nil, fmt.Errorf("not a WAV file")
+	}
+
+	// Walk the subchunks looking for "fmt " and "data"
+	var channels, bitsPerSample uint16
+	for pos := 12; pos+8 <= len(data); {
+		id := string(data[pos : pos+4])
+		size := int(binary.LittleEndian.Uint32(data[pos+4 : pos+8]))
+		body := data[pos+8:]
+		if size > len(body) {
+			size = len(body)
+		}
+
+		switch id {
+		case "fmt ":
+			if size < 16 {
+				return nil, fmt.Errorf("invalid fmt chunk")
+			}
+			channels = binary.LittleEndian.Uint16(body[2:4])
+			bitsPerSample = binary.LittleEndian.Uint16(body[14:16])
+		case "data":
+			if channels != 1 || bitsPerSample != 16 {
+				return nil, fmt.Errorf("unsupported format: %d channels, %d bits (expected 16-bit mono)", channels, bitsPerSample)
+			}
+			samples := make([]int16, size/2)
+			for i := range samples {
+				samples[i] = int16(binary.LittleEndian.Uint16(body[i*2:]))
+			}
+			return samples, nil
+		}
+
+		pos += 8 + size + size%2 // Subchunks are word-aligned
+	}
+
+	return nil, fmt.Errorf("no data chunk found")
+}
+
 // Helper functions
 
 func currentTimeMillis() int64 {
@@ -0,0 +1,194 @@
+package transcription
+
+import (
+	"testing"
+
+	"github.com/lucianHymer/streaming-transcription/shared/logger"
+)
+
+// newTestPipeline builds an active pipeline without a Whisper model or RNNoise
+func newTestPipeline(t *testing.T) *TranscriptionPipeline {
+	t.Helper()
+	log := logger.New(false)
+
+	p := &TranscriptionPipeline{
+		whisper:  &WhisperTranscriberShared{},
+		outbox:   NewOutbox(OutboxConfig{SpillDir: t.TempDir()}, log),
+		active:   true,
+		log:      log.With("pipeline"),
+		pending:  make(map[uint64]TranscriptionResult),
+		filter:   NewHallucinationFilter(FilterConfig{}),
+		sequence: newSequenceTracker(),
+	}
+	p.chunker = NewSmartChunker(SmartChunkerConfig{Logger: log})
+	t.Cleanup(p.outbox.Close)
+	return p
+}
+
+func TestDeliverFinalRestoresOrder(t *testing.T) {
+	p := newTestPipeline(t)
+
+	// Chunks finish transcription out of order
+	for _, index := range []uint64{2, 0, 3, 1} {
+		p.deliverFinal(TranscriptionResult{ChunkIndex: index, Text: "chunk"})
+	}
+
+	for i := uint64(0); i < 4; i++ {
+		if result := receive(t, p.outbox); result.ChunkIndex != i {
+			t.Fatalf("Expected chunk %d, got %d", i, result.ChunkIndex)
+		}
+	}
+	if len(p.pending) != 0 {
+		t.Errorf("Expected no pending results, got %d", len(p.pending))
+	}
+}
+
+func TestDeliverFinalHoldsUntilGapFilled(t *testing.T) {
+	p := newTestPipeline(t)
+
+	p.deliverFinal(TranscriptionResult{ChunkIndex: 1, Text: "second"})
+	if stats := p.outbox.Stats(); stats.Queued != 0 {
+		t.Fatalf("Expected chunk 1 to be held until chunk 0 arrives, got %+v", stats)
+	}
+
+	// A failed transcription still fills its slot
+	p.deliverFinal(TranscriptionResult{ChunkIndex: 0, Error: ErrModelBusy})
+
+	if result := receive(t, p.outbox); result.ChunkIndex != 0 || result.Error == nil {
+		t.Fatalf("Expected failed chunk 0 first, got %+v", result)
+	}
+	if result := receive(t, p.outbox); result.ChunkIndex != 1 || result.Text != "second" {
+		t.Fatalf("Expected chunk 1 second, got %+v", result)
+	}
+}
+
+func TestOfferPartialDiscardsStale(t *testing.T) {
+	p := newTestPipeline(t)
+	partial := func(index uint64) TranscriptionResult {
+		return TranscriptionResult{ChunkIndex: index, Text: "partial", IsPartial: true}
+	}
+
+	// Chunk 0 is still being spoken: its partial goes out
+	if !p.offerPartial(partial(0), 16000) {
+		t.Fatal("Expected partial for the current chunk to be sent")
+	}
+	receive(t, p.outbox)
+
+	// Chunk 0 has been cut since the snapshot: the final supersedes the partial
+	p.chunker.nextChunkIndex = 1
+	if p.offerPartial(partial(0), 16000) {
+		t.Error("Expected partial for a cut chunk to be discarded")
+	}
+
+	// Chunk 1's partial must not overtake chunk 0's final
+	if p.offerPartial(partial(1), 16000) {
+		t.Error("Expected partial to wait for earlier finals")
+	}
+
+	p.deliverFinal(TranscriptionResult{ChunkIndex: 0, Text: "final"})
+	receive(t, p.outbox)
+	if !p.offerPartial(partial(1), 16000) {
+		t.Error("Expected partial to be sent once earlier finals are delivered")
+	}
+	receive(t, p.outbox)
+
+	// Nothing goes out after the pipeline stops
+	p.active = false
+	if p.offerPartial(partial(1), 16000) {
+		t.Error("Expected partial to be discarded by an inactive pipeline")
+	}
+}
+
+func TestApplyConfidenceThreshold(t *testing.T) {
+	segments := []Segment{{Text: "maybe"}}
+
+	p := newTestPipeline(t)
+	p.minConfidence = 0.5
+
+	// Flag: text is kept and marked
+	result := TranscriptionResult{Text: "maybe", Segments: segments, Confidence: 0.3}
+	p.applyConfidenceThreshold(&result)
+	if !result.LowConfidence || result.Text != "maybe" {
+		t.Errorf("Expected low-confidence result to be flagged, got %+v", result)
+	}
+
+	// Confident results are untouched
+	result = TranscriptionResult{Text: "sure", Confidence: 0.9}
+	p.applyConfidenceThreshold(&result)
+	if result.LowConfidence {
+		t.Errorf("Expected confident result to be kept as-is, got %+v", result)
+	}
+
+	// Drop: text and segments are removed, the result itself is kept for ordering
+	p.dropLowConfidence = true
+	result = TranscriptionResult{ChunkIndex: 7, Text: "maybe", Segments: segments, Confidence: 0.3}
+	p.applyConfidenceThreshold(&result)
+	if result.Text != "" || result.Segments != nil || result.ChunkIndex != 7 || result.LowConfidence {
+		t.Errorf("Expected low-confidence result to be emptied, got %+v", result)
+	}
+
+	// Disabled threshold
+	p.minConfidence = 0
+	result = TranscriptionResult{Text: "maybe", Confidence: 0.1}
+	p.applyConfidenceThreshold(&result)
+	if result.Text != "maybe" || result.LowConfidence {
+		t.Errorf("Expected no change with threshold disabled, got %+v", result)
+	}
+}
+
+func TestProcessAudioHandlesSequenceGaps(t *testing.T) {
+	p := newTestPipeline(t)
+	rnnoise, err := NewRNNoiseProcessor("", logger.New(false))
+	if err != nil {
+		t.Skipf("RNNoise unavailable: %v", err)
+	}
+	p.rnnoise = rnnoise
+
+	chunk := make([]byte, 3200*2) // 200ms of silence
+	process := func(sequenceID uint64) SequenceCheck {
+		t.Helper()
+		check, err := p.ProcessAudio(chunk, 16000, 1, sequenceID, 0)
+		if err != nil {
+			t.Fatalf("ProcessAudio(seq=%d) failed: %v", sequenceID, err)
+		}
+		return check
+	}
+
+	process(10)
+	if check := process(12); check != SequenceGap {
+		t.Fatalf("Expected a gap, got %v", check)
+	}
+	// The missing 200ms chunk is filled with silence
+	if samples := p.chunker.GetStats().BufferSamples; samples != 3*3200 {
+		t.Errorf("Expected %d buffered samples after filling the gap, got %d", 3*3200, samples)
+	}
+
+	if check := process(12); check != SequenceDuplicate {
+		t.Errorf("Expected a duplicate, got %v", check)
+	}
+	if samples := p.chunker.GetStats().BufferSamples; samples != 3*3200 {
+		t.Errorf("Expected the duplicate to be dropped, got %d buffered samples", samples)
+	}
+
+	// Gaps longer than MaxGapFill end the current chunk instead
+	process(100)
+	if samples := p.chunker.GetStats().BufferSamples; samples != 3200 {
+		t.Errorf("Expected only the new chunk buffered after a long gap, got %d samples", samples)
+	}
+
+	// Unsequenced audio is processed without touching the sequence
+	if err := p.ProcessUnsequencedAudio(chunk, 16000, 1, 0); err != nil {
+		t.Fatalf("ProcessUnsequencedAudio failed: %v", err)
+	}
+	if samples := p.chunker.GetStats().BufferSamples; samples != 2*3200 {
+		t.Errorf("Expected unsequenced audio to be buffered, got %d samples", samples)
+	}
+	if check := process(101); check != SequenceInOrder {
+		t.Errorf("Expected the sequence to continue after unsequenced audio, got %v", check)
+	}
+
+	stats := p.GetStats().SequenceStats
+	if stats.Gaps != 2 || stats.Missing != 88 || stats.Duplicates != 1 {
+		t.Errorf("Unexpected sequence stats: %+v", stats)
+	}
+}
@@ -1,119 +1,187 @@
 package transcription
 
-// Simple 3x resampler for 16kHz <-> 48kHz conversion
-// Since 48000 / 16000 = 3 (perfect integer ratio), we can use simple decimation/interpolation
-
-// Upsample16to48 converts 16kHz audio to 48kHz using linear interpolation
-// Input: 16-bit PCM samples at 16kHz
-// Output: 16-bit PCM samples at 48kHz (3x length)
-func Upsample16to48(input []int16) []int16 {
-	if len(input) == 0 {
-		return nil
+import (
+	"fmt"
+	"math"
+)
+
+// Streaming polyphase FIR resampler for rational rate changes (e.g. 44.1kHz -> 16kHz)
+// Used to convert client audio to 16kHz and for the RNNoise 16kHz <-> 48kHz round trip.
+// The input is conceptually upsampled by up (zero stuffing), low-pass filtered and
+// decimated by down; only the filter taps that hit non-zero samples are evaluated.
+// Filter history carries over between calls, so a stream can be fed in pieces of any
+// size without discontinuities at the boundaries.
+
+const (
+	// resamplerZeroCrossings is the number of sinc zero crossings on each side of the filter centre
+	resamplerZeroCrossings = 16
+	// resamplerRolloff places the cutoff just below the lower Nyquist frequency
+	resamplerRolloff = 0.92
+	// resamplerKaiserBeta trades transition width for stopband attenuation (~80dB)
+	resamplerKaiserBeta = 8.0
+	// maxResamplerPhases bounds the filter size for awkward rate ratios
+	maxResamplerPhases = 640
+)
+
+// Resampler converts a stream of samples from one sample rate to another
+type Resampler struct {
+	inRate  int
+	outRate int
+	up      int         // Interpolation factor (L)
+	down    int         // Decimation factor (M)
+	taps    int         // Taps per phase
+	phases  [][]float32 // phases[p][k] = prototype filter h[p + k*up]
+	history []float32   // Input samples; the first taps-1 are history from earlier calls
+	pos     int         // Upsampled position of the next output, relative to history[taps-1]
+}
+
+// NewResampler creates a resampler from inRate to outRate
+func NewResampler(inRate, outRate int) (*Resampler, error) {
+	if inRate <= 0 || outRate <= 0 {
+		return nil, fmt.Errorf("invalid sample rates %d -> %d", inRate, outRate)
 	}
 
-	// Output will be 3x the length
-	output := make([]int16, len(input)*3)
-
-	for i := 0; i < len(input); i++ {
-		baseIdx := i * 3
-
-		if i < len(input)-1 {
-			// Linear interpolation between current and next sample
-			curr := input[i]
-			next := input[i+1]
-			diff := next - curr
-
-			output[baseIdx] = curr
-			output[baseIdx+1] = curr + diff/3
-			output[baseIdx+2] = curr + 2*diff/3
-		} else {
-			// Last sample: just repeat
-			output[baseIdx] = input[i]
-			output[baseIdx+1] = input[i]
-			output[baseIdx+2] = input[i]
-		}
+	g := gcd(inRate, outRate)
+	up, down := outRate/g, inRate/g
+	if up > maxResamplerPhases {
+		return nil, fmt.Errorf("sample rate ratio %d:%d needs %d filter phases (max %d)", outRate, inRate, up, maxResamplerPhases)
 	}
 
-	return output
-}
+	// Filter length spans the zero crossings at the lower of the two rates
+	taps := 2 * resamplerZeroCrossings * max(up, down) / up
+	if taps < 2 {
+		taps = 2
+	}
+	length := taps * up
+
+	// Windowed-sinc low-pass at the upsampled rate, with gain up to make up for zero stuffing
+	cutoff := resamplerRolloff * 0.5 / float64(max(up, down)) // Cycles per upsampled sample
+	centre := float64(length-1) / 2
+	prototype := make([]float64, length)
+	for n := range prototype {
+		x := float64(n) - centre
+		prototype[n] = float64(up) * 2 * cutoff * sinc(2*cutoff*x) * kaiser(x/centre, resamplerKaiserBeta)
+	}
 
-// Downsample48to16 converts 48kHz audio to 16kHz using simple decimation
-// Input: 16-bit PCM samples at 48kHz
-// Output: 16-bit PCM samples at 16kHz (1/3 length)
-func Downsample48to16(input []int16) []int16 {
-	if len(input) == 0 {
-		return nil
+	phases := make([][]float32, up)
+	for p := range phases {
+		phases[p] = make([]float32, taps)
+		for k := range phases[p] {
+			phases[p][k] = float32(prototype[p+k*up])
+		}
 	}
 
-	// Output will be 1/3 the length
-	outputLen := len(input) / 3
-	output := make([]int16, outputLen)
+	return &Resampler{
+		inRate:  inRate,
+		outRate: outRate,
+		up:      up,
+		down:    down,
+		taps:    taps,
+		phases:  phases,
+		history: make([]float32, taps-1),
+	}, nil
+}
+
+// Process resamples the next piece of the stream
+// Output is delayed by the filter's group delay (Latency)
+func (r *Resampler) Process(input []float32) []float32 {
+	r.history = append(r.history, input...)
 
-	// Take every 3rd sample (with simple averaging for anti-aliasing)
-	for i := 0; i < outputLen; i++ {
-		idx := i * 3
+	output := make([]float32, 0, len(input)*r.up/r.down+1)
+	for {
+		newest := r.taps - 1 + r.pos/r.up
+		if newest >= len(r.history) {
+			break
+		}
 
-		// Average the 3 samples to prevent aliasing
-		if idx+2 < len(input) {
-			sum := int32(input[idx]) + int32(input[idx+1]) + int32(input[idx+2])
-			output[i] = int16(sum / 3)
-		} else {
-			output[i] = input[idx]
+		coeffs := r.phases[r.pos%r.up]
+		var sum float32
+		for k, c := range coeffs {
+			sum += c * r.history[newest-k]
 		}
+		output = append(output, sum)
+		r.pos += r.down
 	}
 
+	// Drop the samples no future output needs, keeping taps-1 of history
+	consumed := r.pos / r.up
+	r.history = append(r.history[:0], r.history[consumed:]...)
+	r.pos -= consumed * r.up
+
 	return output
 }
 
-// Upsample16to48Float converts 16kHz float32 audio to 48kHz
-// Used when RNNoise needs float32 input
-func Upsample16to48Float(input []float32) []float32 {
-	if len(input) == 0 {
-		return nil
+// ProcessInt16 resamples 16-bit samples, clipping the filtered output
+func (r *Resampler) ProcessInt16(input []int16) []int16 {
+	floats := make([]float32, len(input))
+	for i, s := range input {
+		floats[i] = float32(s)
 	}
 
-	output := make([]float32, len(input)*3)
+	resampled := r.Process(floats)
+	output := make([]int16, len(resampled))
+	for i, f := range resampled {
+		output[i] = clampInt16(f)
+	}
+	return output
+}
 
-	for i := 0; i < len(input); i++ {
-		baseIdx := i * 3
+// Latency returns the filter's group delay in output samples
+func (r *Resampler) Latency() float64 {
+	return float64(r.taps*r.up-1) / 2 / float64(r.down)
+}
 
-		if i < len(input)-1 {
-			curr := input[i]
-			next := input[i+1]
-			diff := next - curr
+// Reset clears the filter history, for the start of an unrelated stream
+func (r *Resampler) Reset() {
+	r.history = make([]float32, r.taps-1)
+	r.pos = 0
+}
 
-			output[baseIdx] = curr
-			output[baseIdx+1] = curr + diff/3
-			output[baseIdx+2] = curr + 2*diff/3
-		} else {
-			output[baseIdx] = input[i]
-			output[baseIdx+1] = input[i]
-			output[baseIdx+2] = input[i]
-		}
+// clampInt16 rounds a sample to the int16 range
+func clampInt16(f float32) int16 {
+	switch {
+	case f >= math.MaxInt16:
+		return math.MaxInt16
+	case f <= math.MinInt16:
+		return math.MinInt16
+	default:
+		return int16(math.Round(float64(f)))
 	}
-
-	return output
 }
 
-// Downsample48to16Float converts 48kHz float32 audio to 16kHz
-func Downsample48to16Float(input []float32) []float32 {
-	if len(input) == 0 {
-		return nil
+// sinc is the normalised sinc function sin(pi x) / (pi x)
+func sinc(x float64) float64 {
+	if x == 0 {
+		return 1
 	}
+	return math.Sin(math.Pi*x) / (math.Pi * x)
+}
 
-	outputLen := len(input) / 3
-	output := make([]float32, outputLen)
-
-	for i := 0; i < outputLen; i++ {
-		idx := i * 3
+// kaiser evaluates a Kaiser window at x in [-1, 1]
+func kaiser(x, beta float64) float64 {
+	if x < -1 || x > 1 {
+		return 0
+	}
+	return besselI0(beta*math.Sqrt(1-x*x)) / besselI0(beta)
+}
 
-		if idx+2 < len(input) {
-			sum := input[idx] + input[idx+1] + input[idx+2]
-			output[i] = sum / 3
-		} else {
-			output[i] = input[idx]
+// besselI0 is the zeroth-order modified Bessel function of the first kind
+func besselI0(x float64) float64 {
+	sum, term := 1.0, 1.0
+	for k := 1; k < 50; k++ {
+		term *= (x / (2 * float64(k))) * (x / (2 * float64(k)))
+		sum += term
+		if term < 1e-12*sum {
+			break
 		}
 	}
+	return sum
+}
 
-	return output
+// gcd returns the greatest common divisor of a and b
+func gcd(a, b int) int {
+	for b != 0 {
+		a, b = b, a%b
+	}
+	return a
 }
@@ -0,0 +1,259 @@
+package transcription
+
+import (
+	"math"
+	"testing"
+)
+
+// tone returns n samples of a unit sine at freq for the given sample rate
+func tone(n, rate int, freq float64) []float32 {
+	samples := make([]float32, n)
+	for i := range samples {
+		samples[i] = float32(math.Sin(2 * math.Pi * freq * float64(i) / float64(rate)))
+	}
+	return samples
+}
+
+// level returns the RMS level of samples in dB relative to a unit sine
+func level(samples []float32) float64 {
+	var sum float64
+	for _, s := range samples {
+		sum += float64(s) * float64(s)
+	}
+	return 10 * math.Log10(sum/float64(len(samples))/0.5)
+}
+
+// resampleInPieces feeds input to r in pieces of the given size
+func resampleInPieces(r *Resampler, input []float32, piece int) []float32 {
+	var output []float32
+	for start := 0; start < len(input); start += piece {
+		output = append(output, r.Process(input[start:min(start+piece, len(input))])...)
+	}
+	return output
+}
+
+func TestResamplerOutputLength(t *testing.T) {
+	for _, rates := range [][2]int{{48000, 16000}, {44100, 16000}, {8000, 16000}, {16000, 48000}} {
+		r, err := NewResampler(rates[0], rates[1])
+		if err != nil {
+			t.Fatalf("NewResampler(%d, %d) failed: %v", rates[0], rates[1], err)
+		}
+
+		// One second in 10ms pieces yields one second, give or take a sample
+		output := resampleInPieces(r, make([]float32, rates[0]), rates[0]/100)
+		if diff := len(output) - rates[1]; diff < -1 || diff > 1 {
+			t.Errorf("%d -> %d: expected %d samples, got %d", rates[0], rates[1], rates[1], len(output))
+		}
+	}
+}
+
+func TestResamplerIsIndependentOfPieceSize(t *testing.T) {
+	input := tone(4410, 44100, 440)
+
+	whole, _ := NewResampler(44100, 16000)
+	expected := whole.Process(input)
+
+	for _, piece := range []int{1, 7, 441, 1000} {
+		r, _ := NewResampler(44100, 16000)
+		output := resampleInPieces(r, input, piece)
+		if len(output) != len(expected) {
+			t.Fatalf("Piece size %d: expected %d samples, got %d", piece, len(expected), len(output))
+		}
+		for i := range output {
+			if math.Abs(float64(output[i]-expected[i])) > 1e-5 {
+				t.Fatalf("Piece size %d: sample %d is %f, expected %f", piece, i, output[i], expected[i])
+			}
+		}
+	}
+}
+
+func TestResamplerKeepsSpeechAndRemovesAliases(t *testing.T) {
+	r, _ := NewResampler(48000, 16000)
+	speech := resampleInPieces(r, tone(48000, 48000, 1000), 480)
+	if db := level(speech[len(speech)/4:]); math.Abs(db) > 0.1 {
+		t.Errorf("Expected a 1kHz tone to pass unchanged, got %.2fdB", db)
+	}
+
+	// 12kHz is above the 8kHz output Nyquist and would alias to 4kHz
+	r, _ = NewResampler(48000, 16000)
+	alias := resampleInPieces(r, tone(48000, 48000, 12000), 480)
+	if db := level(alias[len(alias)/4:]); db > -60 {
+		t.Errorf("Expected a 12kHz tone to be removed, got %.2fdB", db)
+	}
+}
+
+func TestResamplerRejectsAwkwardRatios(t *testing.T) {
+	if _, err := NewResampler(44101, 16000); err == nil {
+		t.Error("Expected an error for a ratio needing 16000 filter phases")
+	}
+	if _, err := NewResampler(0, 16000); err == nil {
+		t.Error("Expected an error for a zero rate")
+	}
+}
+
+func TestResamplerClipsInt16(t *testing.T) {
+	r, _ := NewResampler(16000, 48000)
+
+	// A full-scale square wave overshoots at its edges
+	input := make([]int16, 1600)
+	for i := range input {
+		input[i] = math.MaxInt16
+		if (i/40)%2 == 1 {
+			input[i] = math.MinInt16
+		}
+	}
+	clipped := 0
+	for _, s := range r.ProcessInt16(input) {
+		if s == math.MaxInt16 || s == math.MinInt16 {
+			clipped++
+		}
+	}
+	if clipped == 0 {
+		t.Error("Expected the overshoot to be clipped to the int16 range")
+	}
+}
+
+// The previous RNNoise resamplers, kept as a baseline: linear interpolation and
+// 3-sample averaging, applied to each 10ms frame independently
+
+func legacyUpsample16to48(input []int16) []int16 {
+	output := make([]int16, len(input)*3)
+	for i := range input {
+		if i < len(input)-1 {
+			diff := input[i+1] - input[i]
+			output[i*3] = input[i]
+			output[i*3+1] = input[i] + diff/3
+			output[i*3+2] = input[i] + 2*diff/3
+		} else {
+			output[i*3], output[i*3+1], output[i*3+2] = input[i], input[i], input[i]
+		}
+	}
+	return output
+}
+
+func legacyDownsample48to16(input []int16) []int16 {
+	output := make([]int16, len(input)/3)
+	for i := range output {
+		output[i] = int16((int32(input[i*3]) + int32(input[i*3+1]) + int32(input[i*3+2])) / 3)
+	}
+	return output
+}
+
+// toneInt16 returns n samples of a sine at freq with the given peak amplitude
+func toneInt16(n, rate int, freq, amplitude float64) []int16 {
+	samples := make([]int16, n)
+	for i, s := range tone(n, rate, freq) {
+		samples[i] = int16(amplitude * float64(s))
+	}
+	return samples
+}
+
+// levelInt16 returns the RMS level of samples in dB relative to a sine of the given amplitude
+func levelInt16(samples []int16, amplitude float64) float64 {
+	floats := make([]float32, len(samples))
+	for i, s := range samples {
+		floats[i] = float32(float64(s) / amplitude)
+	}
+	return level(floats)
+}
+
+// roundTrip passes 16kHz audio through 48kHz in 10ms frames, as RNNoiseProcessor does
+func roundTrip(input []int16, legacy bool) []int16 {
+	up, _ := NewResampler(PipelineSampleRate, RNNoiseSampleRate)
+	down, _ := NewResampler(RNNoiseSampleRate, PipelineSampleRate)
+
+	var output []int16
+	for start := 0; start+160 <= len(input); start += 160 {
+		frame := input[start : start+160]
+		if legacy {
+			output = append(output, legacyDownsample48to16(legacyUpsample16to48(frame))...)
+		} else {
+			output = append(output, down.ProcessInt16(up.ProcessInt16(frame))...)
+		}
+	}
+	return output
+}
+
+func TestRNNoiseRoundTripPassbandRipple(t *testing.T) {
+	const amplitude = 10000
+	var newMin, newMax, legacyMin, legacyMax = math.Inf(1), math.Inf(-1), math.Inf(1), math.Inf(-1)
+
+	// Speech band up to 6kHz
+	for freq := 100.0; freq <= 6000; freq += 100 {
+		input := toneInt16(PipelineSampleRate, PipelineSampleRate, freq, amplitude)
+
+		gain := levelInt16(roundTrip(input, false)[1600:], amplitude)
+		newMin, newMax = math.Min(newMin, gain), math.Max(newMax, gain)
+
+		gain = levelInt16(roundTrip(input, true)[1600:], amplitude)
+		legacyMin, legacyMax = math.Min(legacyMin, gain), math.Max(legacyMax, gain)
+	}
+
+	t.Logf("Passband ripple: %.3fdB (previous implementation: %.3fdB)", newMax-newMin, legacyMax-legacyMin)
+	if newMax-newMin > 0.1 {
+		t.Errorf("Expected passband ripple below 0.1dB, got %.3fdB (%.3f to %.3f)", newMax-newMin, newMin, newMax)
+	}
+	if newMax-newMin >= legacyMax-legacyMin {
+		t.Errorf("Expected less ripple than the previous implementation (%.3fdB), got %.3fdB", legacyMax-legacyMin, newMax-newMin)
+	}
+}
+
+func TestRNNoiseDownsamplerRejectsAliases(t *testing.T) {
+	const amplitude = 10000
+
+	// Energy above 8kHz at 48kHz (e.g. RNNoise artefacts) folds into the speech band at 16kHz
+	for _, freq := range []float64{9000, 12000, 15000, 20000} {
+		input := toneInt16(RNNoiseSampleRate, RNNoiseSampleRate, freq, amplitude)
+
+		var newOut, legacyOut []int16
+		down, _ := NewResampler(RNNoiseSampleRate, PipelineSampleRate)
+		for start := 0; start+480 <= len(input); start += 480 {
+			newOut = append(newOut, down.ProcessInt16(input[start:start+480])...)
+			legacyOut = append(legacyOut, legacyDownsample48to16(input[start:start+480])...)
+		}
+
+		newAlias, legacyAlias := levelInt16(newOut[1600:], amplitude), levelInt16(legacyOut[1600:], amplitude)
+		t.Logf("%.0fHz alias: %.1fdB (previous implementation: %.1fdB)", freq, newAlias, legacyAlias)
+		if newAlias > -60 {
+			t.Errorf("%.0fHz: expected aliases below -60dB, got %.1fdB", freq, newAlias)
+		}
+		if newAlias > legacyAlias-40 {
+			t.Errorf("%.0fHz: expected 40dB better rejection than the previous implementation (%.1fdB), got %.1fdB", freq, legacyAlias, newAlias)
+		}
+	}
+}
+
+func TestRNNoiseUpsamplerIsContinuousAcrossFrames(t *testing.T) {
+	const amplitude = 10000
+	input := toneInt16(PipelineSampleRate/2, PipelineSampleRate, 1000, amplitude)
+
+	// Reference: the whole signal upsampled in one call
+	whole, _ := NewResampler(PipelineSampleRate, RNNoiseSampleRate)
+	reference := whole.ProcessInt16(input)
+	legacyReference := legacyUpsample16to48(input)
+
+	framed, _ := NewResampler(PipelineSampleRate, RNNoiseSampleRate)
+	var newOut, legacyOut []int16
+	for start := 0; start+160 <= len(input); start += 160 {
+		newOut = append(newOut, framed.ProcessInt16(input[start:start+160])...)
+		legacyOut = append(legacyOut, legacyUpsample16to48(input[start:start+160])...)
+	}
+
+	// Errors introduced by the 10ms frame boundaries
+	maxError := func(a, b []int16) float64 {
+		var worst float64
+		for i := range a {
+			worst = math.Max(worst, math.Abs(float64(a[i])-float64(b[i])))
+		}
+		return worst
+	}
+	newError, legacyError := maxError(newOut, reference), maxError(legacyOut, legacyReference)
+
+	t.Logf("Frame boundary error: %.0f (previous implementation: %.0f)", newError, legacyError)
+	if newError > 1 {
+		t.Errorf("Expected framed output to match one-call output, max error %.0f", newError)
+	}
+	if legacyError <= newError {
+		t.Errorf("Expected the previous implementation to show boundary errors, max error %.0f", legacyError)
+	}
+}
@@ -17,6 +17,8 @@ const (
 	PipelineSampleRate = 16000
 	// RNNoise frame size: 10ms at 48kHz = 480 samples
 	RNNoiseFrameSize = 480
+	// RNNoiseAvailable reports whether noise suppression is compiled in
+	RNNoiseAvailable = false
 )
 
 // RNNoiseProcessor handles noise suppression using RNNoise
@@ -20,14 +20,18 @@ const (
 	PipelineSampleRate = 16000
 	// RNNoise frame size: 10ms at 48kHz = 480 samples
 	RNNoiseFrameSize = 480
+	// RNNoiseAvailable reports whether noise suppression is compiled in
+	RNNoiseAvailable = true
 )
 
 // RNNoiseProcessor handles noise suppression using RNNoise
 // Handles sample rate conversion (16kHz <-> 48kHz) automatically
 type RNNoiseProcessor struct {
 	denoiser     *rnnoise.RNNoise
-	buffer16kHz  []int16 // Buffer for incomplete 16kHz input frames
-	frameSize16k int     // Equivalent frame size at 16kHz (160 samples)
+	buffer16kHz  []int16    // Buffer for incomplete 16kHz input frames
+	frameSize16k int        // Equivalent frame size at 16kHz (160 samples)
+	upsampler    *Resampler // 16kHz -> 48kHz, filter state carries across frames
+	downsampler  *Resampler // 48kHz -> 16kHz, filter state carries across frames
 	log          *logger.ContextLogger
 }
 
@@ -42,12 +46,25 @@ func NewRNNoiseProcessor(modelPath string, log *logger.Logger) (*RNNoiseProcesso
 		return nil, fmt.Errorf("failed to create RNNoise denoiser: %w", err)
 	}
 
+	upsampler, err := NewResampler(PipelineSampleRate, RNNoiseSampleRate)
+	if err != nil {
+		denoiser.Close()
+		return nil, fmt.Errorf("failed to create upsampler: %w", err)
+	}
+	downsampler, err := NewResampler(RNNoiseSampleRate, PipelineSampleRate)
+	if err != nil {
+		denoiser.Close()
+		return nil, fmt.Errorf("failed to create downsampler: %w", err)
+	}
+
 	contextLog.Info("Initialized - noise suppression active (16kHz ↔ 48kHz resampling)")
 
 	return &RNNoiseProcessor{
 		denoiser:     denoiser,
 		buffer16kHz:  make([]int16, 0, 160),    // 10ms at 16kHz
 		frameSize16k: PipelineSampleRate / 100, // 10ms = 160 samples at 16kHz
+		upsampler:    upsampler,
+		downsampler:  downsampler,
 		log:          contextLog,
 	}, nil
 }
@@ -75,7 +92,7 @@ func (r *RNNoiseProcessor) ProcessChunk(samples []int16) ([]int16, error) {
 		r.buffer16kHz = r.buffer16kHz[r.frameSize16k:]
 
 		// Upsample to 48kHz (160 -> 480 samples)
-		frame48k := Upsample16to48(frame16k)
+		frame48k := r.upsampler.ProcessInt16(frame16k)
 
 		// Convert to float32 bytes for RNNoise
 		input48kBytes := int16ToFloat32Bytes(frame48k)
@@ -94,7 +111,7 @@ func (r *RNNoiseProcessor) ProcessChunk(samples []int16) ([]int16, error) {
 		denoised48k := float32BytesToInt16(output48kBytes)
 
 		// Downsample back to 16kHz (480 -> 160 samples)
-		denoised16k := Downsample48to16(denoised48k)
+		denoised16k := r.downsampler.ProcessInt16(denoised48k)
 
 		// Append to output
 		output = append(output, denoised16k...)
@@ -150,6 +167,8 @@ func (r *RNNoiseProcessor) Flush() []int16 {
 // Reset clears the internal buffers
 func (r *RNNoiseProcessor) Reset() {
 	r.buffer16kHz = r.buffer16kHz[:0]
+	r.upsampler.Reset()
+	r.downsampler.Reset()
 }
 
 // Close releases RNNoise resources
@@ -208,22 +227,3 @@ func float32BytesToInt16(bytes []byte) []int16 {
 	}
 	return samples
 }
-
-func bytesToInt16(data []byte) []int16 {
-	samples := make([]int16, len(data)/2)
-	for i := 0; i < len(samples); i++ {
-		// Little-endian conversion
-		samples[i] = int16(data[i*2]) | int16(data[i*2+1])<<8
-	}
-	return samples
-}
-
-func int16ToBytes(samples []int16) []byte {
-	data := make([]byte, len(samples)*2)
-	for i, sample := range samples {
-		// Little-endian conversion
-		data[i*2] = byte(sample)
-		data[i*2+1] = byte(sample >> 8)
-	}
-	return data
-}
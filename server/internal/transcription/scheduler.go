@@ -0,0 +1,341 @@
+package transcription
+
+import (
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/ggerganov/whisper.cpp/bindings/go/pkg/whisper"
+	"github.com/lucianHymer/streaming-transcription/server/internal/metrics"
+	"github.com/lucianHymer/streaming-transcription/shared/logger"
+)
+
+// ErrModelBusy is returned when the transcription queue is full
+var ErrModelBusy = errors.New("transcription queue full")
+
+// SchedulerConfig holds configuration for the inference scheduler
+type SchedulerConfig struct {
+	Workers       int // Concurrent transcriptions (each worker holds its own model instance)
+	MaxQueueDepth int // Jobs allowed to wait for a worker before new ones are rejected (0 = unlimited, finals evict partials)
+}
+
+// JobPriority orders queued transcription jobs (lower runs first)
+type JobPriority int
+
+const (
+	PriorityShort   JobPriority = iota // Final transcription of a short utterance
+	PriorityLong                       // Final transcription of a longer chunk
+	PriorityPartial                    // Interim transcription (disposable)
+	numPriorities
+)
+
+// inferenceJob is a queued transcription waiting for a free worker
+type inferenceJob struct {
+	client   uint64
+	priority JobPriority
+	run      func(whisper.Model)
+	queuedAt time.Time
+	done     chan error
+}
+
+// clientQueue holds one client's pending jobs, one FIFO per priority
+type clientQueue struct {
+	jobs [numPriorities][]*inferenceJob
+}
+
+// InferenceScheduler runs transcriptions on a fixed pool of workers
+// Jobs are picked by priority (short finals, long finals, partials) and round-robin
+// across clients within a priority, so one busy peer cannot starve the others
+type InferenceScheduler struct {
+	mu      sync.Mutex
+	cond    *sync.Cond
+	queues  map[uint64]*clientQueue
+	order   []uint64 // Round-robin order of clients with queued jobs
+	cursor  int      // Position in order to start the next search from
+	depth   int      // Total queued jobs
+	limit   int      // Maximum queued jobs (0 = unlimited)
+	busy    int      // Workers currently transcribing
+	workers int
+	closed  bool
+	running sync.WaitGroup // Worker goroutines
+	log     *logger.ContextLogger
+
+	// Start time of each worker's current job (zero when idle)
+	jobStarted []time.Time
+
+	// Statistics
+	started   uint64
+	completed uint64
+	rejected  uint64
+	evicted   uint64
+	totalWait time.Duration
+	maxWait   time.Duration
+	lastWait  time.Duration
+	nextID    uint64
+}
+
+// SchedulerStats holds inference scheduler statistics
+type SchedulerStats struct {
+	Workers    int           // Size of the worker pool
+	Busy       int           // Workers currently transcribing
+	QueueDepth int           // Jobs waiting for a worker
+	Clients    int           // Clients with queued jobs
+	Started    uint64        // Jobs picked up by a worker since startup
+	Completed  uint64        // Jobs finished since startup
+	Rejected   uint64        // Jobs rejected because the queue was full
+	Evicted    uint64        // Queued partials dropped to make room for finals
+	AvgWait    time.Duration // Mean time jobs spent queued
+	MaxWait    time.Duration // Longest time a job spent queued
+	LastWait   time.Duration // Queue time of the most recently started job
+	MaxQueue   int           // Maximum queued jobs (0 = unlimited)
+	LongestJob time.Duration // Time the longest-running current job has been running
+}
+
+// newInferenceScheduler starts one worker per model
+func newInferenceScheduler(models []whisper.Model, maxQueueDepth int, log *logger.Logger) *InferenceScheduler {
+	s := &InferenceScheduler{
+		queues:  make(map[uint64]*clientQueue),
+		limit:   maxQueueDepth,
+		workers: len(models),
+		log:     log.With("scheduler"),
+	}
+	s.jobStarted = make([]time.Time, len(models))
+	s.cond = sync.NewCond(&s.mu)
+
+	s.running.Add(len(models))
+	for i, model := range models {
+		go s.worker(i, model)
+	}
+
+	return s
+}
+
+// newClient returns an ID identifying a transcriber for fair scheduling
+func (s *InferenceScheduler) newClient() uint64 {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.nextID++
+	return s.nextID
+}
+
+// Run queues a job for the client and blocks until a worker has run it
+// Returns ErrModelBusy without running the job if the queue is full; a final job
+// instead takes the place of the oldest queued partial, if there is one
+func (s *InferenceScheduler) Run(client uint64, priority JobPriority, run func(whisper.Model)) error {
+	job := &inferenceJob{
+		client:   client,
+		priority: priority,
+		run:      run,
+		queuedAt: time.Now(),
+		done:     make(chan error, 1),
+	}
+
+	s.mu.Lock()
+	if s.closed {
+		s.mu.Unlock()
+		return fmt.Errorf("scheduler closed")
+	}
+	if s.limit > 0 && s.depth >= s.limit && (priority == PriorityPartial || !s.evictPartial()) {
+		s.rejected++
+		s.mu.Unlock()
+		return ErrModelBusy
+	}
+
+	queue, ok := s.queues[client]
+	if !ok {
+		queue = &clientQueue{}
+		s.queues[client] = queue
+		s.order = append(s.order, client)
+	}
+	queue.jobs[priority] = append(queue.jobs[priority], job)
+	s.depth++
+	s.cond.Signal()
+	s.mu.Unlock()
+
+	return <-job.done
+}
+
+// evictPartial fails the oldest queued partial job to free a queue slot (caller holds s.mu)
+// Returns false if no partial is queued
+func (s *InferenceScheduler) evictPartial() bool {
+	var oldest *inferenceJob
+	for _, queue := range s.queues {
+		if jobs := queue.jobs[PriorityPartial]; len(jobs) > 0 && (oldest == nil || jobs[0].queuedAt.Before(oldest.queuedAt)) {
+			oldest = jobs[0]
+		}
+	}
+	if oldest == nil {
+		return false
+	}
+
+	queue := s.queues[oldest.client]
+	queue.jobs[PriorityPartial] = queue.jobs[PriorityPartial][1:]
+	s.depth--
+	s.evicted++
+	if queue.empty() {
+		s.removeClient(oldest.client)
+	}
+
+	oldest.done <- ErrModelBusy
+	return true
+}
+
+// removeClient drops a client without queued jobs from the round-robin order (caller holds s.mu)
+func (s *InferenceScheduler) removeClient(client uint64) {
+	delete(s.queues, client)
+	for pos, id := range s.order {
+		if id != client {
+			continue
+		}
+		s.order = append(s.order[:pos], s.order[pos+1:]...)
+		if s.cursor > pos {
+			s.cursor--
+		}
+		break
+	}
+	if len(s.order) > 0 {
+		s.cursor %= len(s.order)
+	} else {
+		s.cursor = 0
+	}
+}
+
+// next removes and returns the next job to run (caller holds s.mu)
+func (s *InferenceScheduler) next() *inferenceJob {
+	for priority := JobPriority(0); priority < numPriorities; priority++ {
+		for i := 0; i < len(s.order); i++ {
+			pos := (s.cursor + i) % len(s.order)
+			client := s.order[pos]
+			queue := s.queues[client]
+			if len(queue.jobs[priority]) == 0 {
+				continue
+			}
+
+			job := queue.jobs[priority][0]
+			queue.jobs[priority] = queue.jobs[priority][1:]
+			s.depth--
+
+			// Continue round-robin with the following client
+			s.cursor = pos + 1
+			if queue.empty() {
+				delete(s.queues, client)
+				s.order = append(s.order[:pos], s.order[pos+1:]...)
+				s.cursor = pos
+			}
+			if len(s.order) > 0 {
+				s.cursor %= len(s.order)
+			} else {
+				s.cursor = 0
+			}
+			return job
+		}
+	}
+	return nil
+}
+
+// worker runs queued jobs on its own model instance until the scheduler closes
+func (s *InferenceScheduler) worker(id int, model whisper.Model) {
+	defer s.running.Done()
+
+	for {
+		s.mu.Lock()
+		for s.depth == 0 && !s.closed {
+			s.cond.Wait()
+		}
+		if s.closed {
+			s.mu.Unlock()
+			return
+		}
+
+		job := s.next()
+		wait := time.Since(job.queuedAt)
+		s.busy++
+		s.jobStarted[id] = time.Now()
+		s.started++
+		s.totalWait += wait
+		s.lastWait = wait
+		if wait > s.maxWait {
+			s.maxWait = wait
+		}
+		s.mu.Unlock()
+
+		metrics.QueueWait.Observe(wait.Seconds())
+		if wait > time.Second {
+			s.log.Debug("Worker %d: job waited %dms in queue (priority %d)", id, wait.Milliseconds(), job.priority)
+		}
+
+		job.run(model)
+		job.done <- nil
+
+		s.mu.Lock()
+		s.busy--
+		s.jobStarted[id] = time.Time{}
+		s.completed++
+		s.mu.Unlock()
+	}
+}
+
+// Stats returns the scheduler statistics
+func (s *InferenceScheduler) Stats() SchedulerStats {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	stats := SchedulerStats{
+		Workers:    s.workers,
+		Busy:       s.busy,
+		QueueDepth: s.depth,
+		Clients:    len(s.order),
+		Started:    s.started,
+		Completed:  s.completed,
+		Rejected:   s.rejected,
+		Evicted:    s.evicted,
+		MaxWait:    s.maxWait,
+		LastWait:   s.lastWait,
+		MaxQueue:   s.limit,
+	}
+	for _, started := range s.jobStarted {
+		if !started.IsZero() {
+			stats.LongestJob = max(stats.LongestJob, time.Since(started))
+		}
+	}
+	if s.started > 0 {
+		stats.AvgWait = s.totalWait / time.Duration(s.started)
+	}
+	return stats
+}
+
+// Close fails any queued jobs and waits for running jobs to finish
+func (s *InferenceScheduler) Close() {
+	s.mu.Lock()
+	if s.closed {
+		s.mu.Unlock()
+		return
+	}
+	s.closed = true
+
+	for _, queue := range s.queues {
+		for _, jobs := range queue.jobs {
+			for _, job := range jobs {
+				job.done <- fmt.Errorf("scheduler closed")
+			}
+		}
+	}
+	s.queues = make(map[uint64]*clientQueue)
+	s.order = nil
+	s.depth = 0
+	s.cond.Broadcast()
+	s.mu.Unlock()
+
+	s.running.Wait()
+}
+
+// empty returns true if the client has no queued jobs
+func (q *clientQueue) empty() bool {
+	for _, jobs := range q.jobs {
+		if len(jobs) > 0 {
+			return false
+		}
+	}
+	return true
+}
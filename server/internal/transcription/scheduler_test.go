@@ -0,0 +1,228 @@
+package transcription
+
+import (
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/ggerganov/whisper.cpp/bindings/go/pkg/whisper"
+	"github.com/lucianHymer/streaming-transcription/shared/logger"
+)
+
+func TestSchedulerPriorityAndFairness(t *testing.T) {
+	s := newInferenceScheduler(make([]whisper.Model, 1), 0, logger.New(false))
+	defer s.Close()
+
+	// Occupy the only worker so the following jobs queue up
+	release := make(chan struct{})
+	started := make(chan struct{})
+	go s.Run(0, PriorityLong, func(whisper.Model) {
+		close(started)
+		<-release
+	})
+	<-started
+
+	var mu sync.Mutex
+	var order []string
+	var wg sync.WaitGroup
+	submit := func(client uint64, priority JobPriority, name string) {
+		wg.Add(1)
+		go s.Run(client, priority, func(whisper.Model) {
+			mu.Lock()
+			order = append(order, name)
+			mu.Unlock()
+			wg.Done()
+		})
+
+		// Wait until queued so submission order is deterministic
+		want := s.Stats().QueueDepth + 1
+		for s.Stats().QueueDepth < want {
+			time.Sleep(time.Millisecond)
+		}
+	}
+
+	submit(1, PriorityPartial, "a-partial")
+	submit(1, PriorityLong, "a-long-1")
+	submit(1, PriorityLong, "a-long-2")
+	submit(2, PriorityLong, "b-long-1")
+	submit(2, PriorityShort, "b-short")
+
+	close(release)
+	wg.Wait()
+
+	expected := []string{"b-short", "a-long-1", "b-long-1", "a-long-2", "a-partial"}
+	if len(order) != len(expected) {
+		t.Fatalf("Expected %v, got %v", expected, order)
+	}
+	for i := range expected {
+		if order[i] != expected[i] {
+			t.Fatalf("Expected %v, got %v", expected, order)
+		}
+	}
+
+	// Jobs count as completed once their worker is done with them
+	deadline := time.Now().Add(time.Second)
+	for s.Stats().Completed < 6 && time.Now().Before(deadline) {
+		time.Sleep(time.Millisecond)
+	}
+	if stats := s.Stats(); stats.Started != 6 || stats.Completed != 6 || stats.QueueDepth != 0 {
+		t.Errorf("Unexpected stats: %+v", stats)
+	}
+}
+
+func TestSchedulerCountsCompletedAfterRun(t *testing.T) {
+	s := newInferenceScheduler(make([]whisper.Model, 1), 0, logger.New(false))
+	defer s.Close()
+
+	release := make(chan struct{})
+	started := make(chan struct{})
+	go s.Run(1, PriorityLong, func(whisper.Model) {
+		close(started)
+		<-release
+	})
+	<-started
+
+	if stats := s.Stats(); stats.Started != 1 || stats.Completed != 0 || stats.Busy != 1 {
+		t.Errorf("Expected a started but unfinished job, got %+v", stats)
+	}
+	close(release)
+}
+
+func TestSchedulerReportsLongestRunningJob(t *testing.T) {
+	s := newInferenceScheduler(make([]whisper.Model, 2), 0, logger.New(false))
+	defer s.Close()
+
+	if stats := s.Stats(); stats.LongestJob != 0 {
+		t.Errorf("Expected no running job, got %v", stats.LongestJob)
+	}
+
+	release := make(chan struct{})
+	started := make(chan struct{})
+	done := make(chan struct{})
+	go func() {
+		s.Run(1, PriorityLong, func(whisper.Model) {
+			close(started)
+			<-release
+		})
+		close(done)
+	}()
+	<-started
+
+	time.Sleep(20 * time.Millisecond)
+	if stats := s.Stats(); stats.LongestJob < 20*time.Millisecond {
+		t.Errorf("Expected the running job to be at least 20ms old, got %v", stats.LongestJob)
+	}
+
+	// The worker marks itself idle just after the job returns
+	close(release)
+	<-done
+	deadline := time.Now().Add(time.Second)
+	for s.Stats().LongestJob != 0 {
+		if time.Now().After(deadline) {
+			t.Fatalf("Expected no running job after it finished, got %v", s.Stats().LongestJob)
+		}
+		time.Sleep(time.Millisecond)
+	}
+}
+
+func TestSchedulerFinalEvictsQueuedPartial(t *testing.T) {
+	s := newInferenceScheduler(make([]whisper.Model, 1), 1, logger.New(false))
+	defer s.Close()
+
+	release := make(chan struct{})
+	started := make(chan struct{})
+	go s.Run(1, PriorityLong, func(whisper.Model) {
+		close(started)
+		<-release
+	})
+	<-started
+
+	partialErr := make(chan error, 1)
+	go func() {
+		partialErr <- s.Run(1, PriorityPartial, func(whisper.Model) {})
+	}()
+	for s.Stats().QueueDepth < 1 {
+		time.Sleep(time.Millisecond)
+	}
+
+	// A partial cannot take a full queue's slot
+	if err := s.Run(2, PriorityPartial, func(whisper.Model) {}); err != ErrModelBusy {
+		t.Errorf("Expected ErrModelBusy for a partial, got %v", err)
+	}
+
+	// A final can: the queued partial is dropped
+	finalErr := make(chan error, 1)
+	go func() {
+		finalErr <- s.Run(2, PriorityShort, func(whisper.Model) {})
+	}()
+	if err := <-partialErr; err != ErrModelBusy {
+		t.Errorf("Expected the queued partial to be evicted, got %v", err)
+	}
+
+	close(release)
+	if err := <-finalErr; err != nil {
+		t.Errorf("Expected the final to run, got %v", err)
+	}
+	if stats := s.Stats(); stats.Evicted != 1 || stats.Rejected != 1 {
+		t.Errorf("Unexpected stats: %+v", stats)
+	}
+}
+
+func TestSchedulerRejectsWhenQueueFull(t *testing.T) {
+	s := newInferenceScheduler(make([]whisper.Model, 1), 1, logger.New(false))
+	defer s.Close()
+
+	release := make(chan struct{})
+	started := make(chan struct{})
+	go s.Run(1, PriorityLong, func(whisper.Model) {
+		close(started)
+		<-release
+	})
+	<-started
+
+	go s.Run(1, PriorityLong, func(whisper.Model) {})
+	for s.Stats().QueueDepth < 1 {
+		time.Sleep(time.Millisecond)
+	}
+
+	if err := s.Run(2, PriorityShort, func(whisper.Model) {}); err != ErrModelBusy {
+		t.Errorf("Expected ErrModelBusy, got %v", err)
+	}
+	if stats := s.Stats(); stats.Rejected != 1 {
+		t.Errorf("Expected 1 rejected job, got %+v", stats)
+	}
+	close(release)
+}
+
+func TestSchedulerCloseFailsQueuedJobs(t *testing.T) {
+	s := newInferenceScheduler(make([]whisper.Model, 1), 0, logger.New(false))
+
+	release := make(chan struct{})
+	started := make(chan struct{})
+	go s.Run(1, PriorityLong, func(whisper.Model) {
+		close(started)
+		<-release
+	})
+	<-started
+
+	errChan := make(chan error, 1)
+	go func() {
+		errChan <- s.Run(2, PriorityShort, func(whisper.Model) {})
+	}()
+	for s.Stats().QueueDepth < 1 {
+		time.Sleep(time.Millisecond)
+	}
+
+	go func() {
+		time.Sleep(10 * time.Millisecond)
+		close(release)
+	}()
+	s.Close()
+
+	if err := <-errChan; err == nil {
+		t.Error("Expected queued job to fail when the scheduler closes")
+	}
+	if err := s.Run(1, PriorityShort, func(whisper.Model) {}); err == nil {
+		t.Error("Expected Run to fail after Close")
+	}
+}
@@ -0,0 +1,113 @@
+package transcription
+
+import (
+	"fmt"
+	"math"
+	"time"
+)
+
+// selfTestClipDuration is the length of the built-in self-test clip
+const selfTestClipDuration = 2 * time.Second
+
+// SelfTestResult is the outcome of running the built-in clip through RNNoise and Whisper
+type SelfTestResult struct {
+	Passed                 bool
+	NoiseSuppressionActive bool          // RNNoise denoised the clip (false in the pass-through build)
+	Duration               time.Duration // Whisper inference time, including queue wait
+	RealtimeFactor         float64       // Duration divided by the clip length
+	Text                   string        // Not checked: the clip is a synthetic tone, not speech
+	Error                  string
+	CompletedAt            time.Time
+}
+
+// SelfTest runs the built-in clip through RNNoise and Whisper with the server's
+// default settings, to find a broken build or model before clients do
+// It fails if either stage errors or Whisper takes longer than timeout (0 = no limit)
+func SelfTest(model *SharedWhisperModel, config WhisperConfig, rnnoiseModelPath string, timeout time.Duration) SelfTestResult {
+	result := selfTest(model, config, rnnoiseModelPath, timeout)
+	result.CompletedAt = time.Now()
+	return result
+}
+
+func selfTest(model *SharedWhisperModel, config WhisperConfig, rnnoiseModelPath string, timeout time.Duration) SelfTestResult {
+	var result SelfTestResult
+	clip := selfTestClip()
+
+	rnnoise, err := NewRNNoiseProcessor(rnnoiseModelPath, config.Logger)
+	if err != nil {
+		result.Error = fmt.Sprintf("noise suppression: %v", err)
+		return result
+	}
+	denoised, err := rnnoise.ProcessChunk(clip)
+	rnnoise.Close()
+	if err != nil {
+		result.Error = fmt.Sprintf("noise suppression: %v", err)
+		return result
+	}
+	result.NoiseSuppressionActive = RNNoiseAvailable
+
+	transcriber, err := NewWhisperTranscriberShared(model, config)
+	if err != nil {
+		result.Error = fmt.Sprintf("whisper: %v", err)
+		return result
+	}
+
+	samples := make([]float32, len(denoised))
+	for i, sample := range denoised {
+		samples[i] = float32(sample) / 32768.0
+	}
+
+	// A wedged worker never returns, so give up waiting rather than block startup
+	type outcome struct {
+		output WhisperOutput
+		err    error
+	}
+	done := make(chan outcome, 1)
+	var expired <-chan time.Time
+	if timeout > 0 {
+		expired = time.After(timeout)
+	}
+	start := time.Now()
+	go func() {
+		defer transcriber.Close()
+		output, err := transcriber.Transcribe(samples)
+		done <- outcome{output, err}
+	}()
+
+	select {
+	case t := <-done:
+		result.Duration = time.Since(start)
+		result.RealtimeFactor = result.Duration.Seconds() / selfTestClipDuration.Seconds()
+		if t.err != nil {
+			result.Error = fmt.Sprintf("whisper: %v", t.err)
+			return result
+		}
+		result.Text = t.output.Text
+		result.Passed = true
+	case <-expired:
+		result.Duration = time.Since(start)
+		result.Error = fmt.Sprintf("whisper did not finish within %v", timeout)
+	}
+	return result
+}
+
+// selfTestClip synthesizes the self-test clip at 16kHz: a voiced, vowel-like tone
+// (a 140Hz fundamental with harmonics near the formants of "ah") between short silences
+func selfTestClip() []int16 {
+	total := int(selfTestClipDuration.Seconds() * PipelineSampleRate)
+	silence := total / 8
+	clip := make([]int16, total)
+
+	for i := silence; i < total-silence; i++ {
+		t := float64(i) / PipelineSampleRate
+		var v float64
+		for harmonic := 1; harmonic <= 20; harmonic++ {
+			freq := 140 * float64(harmonic)
+			// Emphasize harmonics near the first two formants (about 700Hz and 1200Hz)
+			gain := math.Exp(-math.Pow((freq-700)/200, 2)) + 0.6*math.Exp(-math.Pow((freq-1200)/250, 2)) + 0.05
+			v += gain * math.Sin(2*math.Pi*freq*t) / float64(harmonic)
+		}
+		clip[i] = int16(v * 6000)
+	}
+	return clip
+}
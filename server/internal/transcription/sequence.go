@@ -0,0 +1,89 @@
+package transcription
+
+import "time"
+
+// MaxGapFill is the longest gap in a session's audio that is filled with silence
+// Longer gaps end the current chunk instead, so speech on either side is not stitched together
+const MaxGapFill = time.Second
+
+// maxTrackedMissing bounds the missing sequence IDs remembered to tell late chunks from duplicates
+const maxTrackedMissing = 1024
+
+// SequenceCheck is the outcome of checking an audio chunk's sequence ID
+type SequenceCheck int
+
+const (
+	SequenceInOrder   SequenceCheck = iota // The expected chunk (or the session's first)
+	SequenceGap                            // Chunks before this one are missing
+	SequenceDuplicate                      // Already received; dropped
+	SequenceLate                           // Arrived after its gap was filled or cut; dropped
+)
+
+// SequenceStats counts gaps and duplicates in the sequence IDs of a session's audio chunks
+type SequenceStats struct {
+	Received   uint64 // Chunks accepted
+	Duplicates uint64 // Chunks dropped because their sequence ID was already received
+	Late       uint64 // Chunks dropped because they arrived after their gap was handled
+	Missing    uint64 // Chunks skipped by gaps (including ones that arrived late)
+	Gaps       uint64 // Times the sequence skipped ahead
+}
+
+// sequenceTracker detects gaps and duplicates in the sequence IDs of a session's audio chunks
+// The first chunk sets the baseline, since clients number chunks across sessions
+type sequenceTracker struct {
+	started bool
+	next    uint64              // Sequence ID expected next
+	missing map[uint64]struct{} // Skipped sequence IDs that may still arrive late
+	stats   SequenceStats
+}
+
+// newSequenceTracker creates a tracker for a new session
+func newSequenceTracker() *sequenceTracker {
+	return &sequenceTracker{
+		missing: make(map[uint64]struct{}),
+	}
+}
+
+// Check classifies a sequence ID, returning how many chunks are missing before it on a gap
+func (t *sequenceTracker) Check(sequenceID uint64) (SequenceCheck, uint64) {
+	if !t.started {
+		t.started = true
+		t.next = sequenceID + 1
+		t.stats.Received++
+		return SequenceInOrder, 0
+	}
+
+	if sequenceID < t.next {
+		if _, ok := t.missing[sequenceID]; ok {
+			delete(t.missing, sequenceID)
+			t.stats.Late++
+			return SequenceLate, 0
+		}
+		t.stats.Duplicates++
+		return SequenceDuplicate, 0
+	}
+
+	skipped := sequenceID - t.next
+	t.next = sequenceID + 1
+	t.stats.Received++
+	if skipped == 0 {
+		return SequenceInOrder, 0
+	}
+
+	t.stats.Gaps++
+	t.stats.Missing += skipped
+
+	// Remember the most recent skipped IDs; older ones arriving late count as duplicates
+	if len(t.missing)+int(min(skipped, maxTrackedMissing)) > maxTrackedMissing {
+		clear(t.missing)
+	}
+	for id := sequenceID - min(skipped, maxTrackedMissing); id < sequenceID; id++ {
+		t.missing[id] = struct{}{}
+	}
+	return SequenceGap, skipped
+}
+
+// Stats returns the counts so far
+func (t *sequenceTracker) Stats() SequenceStats {
+	return t.stats
+}
@@ -0,0 +1,64 @@
+package transcription
+
+import "testing"
+
+func TestSequenceTrackerInOrder(t *testing.T) {
+	tracker := newSequenceTracker()
+
+	// Clients number chunks across sessions, so the first one sets the baseline
+	for id := uint64(40); id < 45; id++ {
+		if check, _ := tracker.Check(id); check != SequenceInOrder {
+			t.Fatalf("Expected seq=%d in order, got %v", id, check)
+		}
+	}
+	if stats := tracker.Stats(); stats.Received != 5 || stats.Gaps != 0 || stats.Duplicates != 0 {
+		t.Errorf("Unexpected stats: %+v", stats)
+	}
+}
+
+func TestSequenceTrackerGapsAndDuplicates(t *testing.T) {
+	tracker := newSequenceTracker()
+	tracker.Check(0)
+	tracker.Check(1)
+
+	check, skipped := tracker.Check(5)
+	if check != SequenceGap || skipped != 3 {
+		t.Fatalf("Expected a gap of 3, got %v, %d", check, skipped)
+	}
+
+	if check, _ := tracker.Check(5); check != SequenceDuplicate {
+		t.Errorf("Expected seq=5 again to be a duplicate, got %v", check)
+	}
+	if check, _ := tracker.Check(1); check != SequenceDuplicate {
+		t.Errorf("Expected seq=1 again to be a duplicate, got %v", check)
+	}
+	if check, _ := tracker.Check(3); check != SequenceLate {
+		t.Errorf("Expected missing seq=3 to arrive late, got %v", check)
+	}
+	if check, _ := tracker.Check(3); check != SequenceDuplicate {
+		t.Errorf("Expected seq=3 a second time to be a duplicate, got %v", check)
+	}
+	if check, _ := tracker.Check(6); check != SequenceInOrder {
+		t.Errorf("Expected seq=6 in order, got %v", check)
+	}
+
+	stats := tracker.Stats()
+	want := SequenceStats{Received: 4, Duplicates: 3, Late: 1, Missing: 3, Gaps: 1}
+	if stats != want {
+		t.Errorf("Expected %+v, got %+v", want, stats)
+	}
+}
+
+func TestSequenceTrackerBoundsMissing(t *testing.T) {
+	tracker := newSequenceTracker()
+	tracker.Check(0)
+	if _, skipped := tracker.Check(1_000_000); skipped != 999_999 {
+		t.Fatalf("Expected 999999 missing, got %d", skipped)
+	}
+	if len(tracker.missing) != maxTrackedMissing {
+		t.Errorf("Expected %d tracked missing IDs, got %d", maxTrackedMissing, len(tracker.missing))
+	}
+	if check, _ := tracker.Check(999_999); check != SequenceLate {
+		t.Errorf("Expected a recent missing ID to arrive late, got %v", check)
+	}
+}
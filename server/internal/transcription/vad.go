@@ -5,6 +5,23 @@ import (
 	"time"
 )
 
+// VADSettings are a session's speech detection and chunking settings
+type VADSettings struct {
+	EnergyThreshold        float64       // Energy threshold for speech detection
+	SilenceThreshold       time.Duration // Silence duration that ends a chunk
+	MinChunkDuration       time.Duration // Minimum chunk duration
+	MaxChunkDuration       time.Duration // Maximum chunk duration
+	SpeechDensityThreshold float64       // Speech density threshold for short utterances
+}
+
+// VADLimits bounds the VAD settings a client may request per session
+// A zero bound leaves that side of the setting open
+type VADLimits struct {
+	Min    VADSettings
+	Max    VADSettings
+	Reject bool // Reject out-of-range settings instead of clamping them
+}
+
 // VADConfig holds configuration for Voice Activity Detection
 type VADConfig struct {
 	SampleRate         int     // Audio sample rate (16kHz)
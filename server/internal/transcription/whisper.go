@@ -1,13 +1,36 @@
 package transcription
 
 import (
+	"time"
+
 	"github.com/lucianHymer/streaming-transcription/shared/logger"
 )
 
+// DefaultInitialPrompt primes Whisper for dictating programming instructions
+const DefaultInitialPrompt = "Voice commands for programming. Speaking to computer assistant. Direct address. Imperative mood. Technical instructions. JavaScript, TypeScript, Go, Solidity, Python, React, Node.js. Functions, variables, classes, interfaces, smart contracts, blockchain, API endpoints, database queries. Git commands, terminal operations, code editor."
+
+// MaxContextTokens is the most prompt tokens Whisper uses (half its 448-token text context)
+// Longer prompts are truncated from the start, so the base prompt is lost before recent context
+const MaxContextTokens = 224
+
 // WhisperConfig holds configuration for Whisper transcriber
 type WhisperConfig struct {
-	ModelPath string
-	Language  string // "en" or "auto"
-	Threads   uint   // Number of threads for processing
-	Logger    *logger.Logger
+	ModelPath      string
+	Language       string        // "en" or "auto"
+	Threads        uint          // Number of threads for processing
+	Translate      bool          // Translate to English
+	InitialPrompt  string        // Prompt to condition decoding (empty = DefaultInitialPrompt)
+	Temperature    float32       // Sampling temperature (0 = deterministic)
+	ContextTokens  int           // Token budget for previous transcripts appended to the prompt (0 = disabled)
+	ShortUtterance time.Duration // Final chunks shorter than this are scheduled ahead of longer ones
+	Logger         *logger.Logger
+}
+
+// WhisperLimits bounds the Whisper settings a client may request per session
+// A zero limit means clients may not set the option at all
+type WhisperLimits struct {
+	MaxThreads       uint     // Maximum threads per session
+	MaxTemperature   float32  // Maximum sampling temperature
+	MaxPromptChars   int      // Maximum initial prompt length (including vocabulary)
+	AllowedLanguages []string // Languages clients may request (empty = any the model supports)
 }
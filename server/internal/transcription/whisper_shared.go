@@ -1,51 +1,85 @@
 package transcription
 
 import (
+	"errors"
 	"fmt"
+	"os"
+	"path/filepath"
+	"runtime"
+	"strings"
 	"sync"
+	"time"
 
 	"github.com/ggerganov/whisper.cpp/bindings/go/pkg/whisper"
+	"github.com/lucianHymer/streaming-transcription/server/internal/metrics"
 	"github.com/lucianHymer/streaming-transcription/shared/logger"
 )
 
-// SharedWhisperModel wraps a Whisper model for sharing across multiple contexts
+// SharedWhisperModel wraps a pool of Whisper model instances shared by all connections
+// NOTE: the Go bindings keep decoder state in the model, so contexts created from one
+// model cannot transcribe concurrently; each scheduler worker gets its own instance
 type SharedWhisperModel struct {
-	model whisper.Model
-	mu    sync.RWMutex
-	path  string
-	log   *logger.ContextLogger
+	models    []whisper.Model
+	scheduler *InferenceScheduler
+	path      string
+	log       *logger.ContextLogger
 }
 
-// LoadSharedWhisperModel loads a Whisper model once for sharing
-func LoadSharedWhisperModel(modelPath string, log *logger.Logger) (*SharedWhisperModel, error) {
+// LoadSharedWhisperModel loads one Whisper model instance per scheduler worker
+func LoadSharedWhisperModel(modelPath string, config SchedulerConfig, log *logger.Logger) (*SharedWhisperModel, error) {
 	ctxLog := log.With("whisper-model")
-	ctxLog.Info("Loading shared Whisper model from %s", modelPath)
 
-	model, err := whisper.New(modelPath)
-	if err != nil {
-		return nil, fmt.Errorf("failed to load Whisper model: %w", err)
+	workers := config.Workers
+	if workers < 1 {
+		workers = 1
+	}
+	ctxLog.Info("Loading shared Whisper model from %s (%d instances)", modelPath, workers)
+
+	// Every instance holds its own copy of the weights
+	if info, err := os.Stat(modelPath); err == nil {
+		perModel := info.Size() / (1024 * 1024)
+		ctxLog.InfoWithFields("Expected model memory", map[string]interface{}{
+			"instances":    workers,
+			"per_model":    fmt.Sprintf("%dMB", perModel),
+			"expected_rss": fmt.Sprintf("%dMB", perModel*int64(workers)),
+		})
+	}
+
+	models := make([]whisper.Model, 0, workers)
+	for i := 0; i < workers; i++ {
+		model, err := whisper.New(modelPath)
+		if err != nil {
+			for _, loaded := range models {
+				loaded.Close()
+			}
+			return nil, fmt.Errorf("failed to load Whisper model: %w", err)
+		}
+		models = append(models, model)
 	}
 
 	ctxLog.Info("Shared Whisper model loaded successfully")
 
-	return &SharedWhisperModel{
-		model: model,
-		path:  modelPath,
-		log:   ctxLog,
-	}, nil
+	return NewSharedWhisperModel(models, modelPath, config, log), nil
 }
 
-// NewContext creates a new Whisper context from the shared model
-func (m *SharedWhisperModel) NewContext() (whisper.Context, error) {
-	m.mu.RLock()
-	defer m.mu.RUnlock()
-
-	ctx, err := m.model.NewContext()
-	if err != nil {
-		return nil, fmt.Errorf("failed to create context from shared model: %w", err)
+// NewSharedWhisperModel wraps already loaded model instances, one per scheduler worker
+func NewSharedWhisperModel(models []whisper.Model, modelPath string, config SchedulerConfig, log *logger.Logger) *SharedWhisperModel {
+	return &SharedWhisperModel{
+		models:    models,
+		scheduler: newInferenceScheduler(models, config.MaxQueueDepth, log),
+		path:      modelPath,
+		log:       log.With("whisper-model"),
 	}
+}
 
-	return ctx, nil
+// Languages returns the languages supported by the model
+func (m *SharedWhisperModel) Languages() []string {
+	return m.models[0].Languages()
+}
+
+// IsMultilingual returns true if the model supports languages other than English
+func (m *SharedWhisperModel) IsMultilingual() bool {
+	return m.models[0].IsMultilingual()
 }
 
 // GetPath returns the model path (for logging/debugging)
@@ -53,91 +87,378 @@ func (m *SharedWhisperModel) GetPath() string {
 	return m.path
 }
 
-// WhisperTranscriberShared handles audio transcription using a shared Whisper model
+// ModelInfo describes the loaded Whisper model
+type ModelInfo struct {
+	Path         string
+	SizeBytes    int64  // Size of the model file (0 if it cannot be read)
+	Type         string // Model name from the file name, e.g. "base.en" for ggml-base.en.bin
+	Multilingual bool
+	Instances    int // Copies loaded, one per scheduler worker
+}
+
+// Info describes the loaded model
+func (m *SharedWhisperModel) Info() ModelInfo {
+	info := ModelInfo{
+		Path:         m.path,
+		Type:         strings.TrimPrefix(strings.TrimSuffix(filepath.Base(m.path), filepath.Ext(m.path)), "ggml-"),
+		Multilingual: m.IsMultilingual(),
+		Instances:    len(m.models),
+	}
+	if stat, err := os.Stat(m.path); err == nil {
+		info.SizeBytes = stat.Size()
+	}
+	return info
+}
+
+// SchedulerStats returns the inference scheduler statistics
+func (m *SharedWhisperModel) SchedulerStats() SchedulerStats {
+	return m.scheduler.Stats()
+}
+
+// Close stops the scheduler and releases all model instances
+func (m *SharedWhisperModel) Close() error {
+	m.scheduler.Close()
+	for _, model := range m.models {
+		model.Close()
+	}
+	return nil
+}
+
+// WhisperTranscriberShared handles audio transcription using the shared model pool
+// Each transcription runs as a job on the inference scheduler with this transcriber's settings
 type WhisperTranscriberShared struct {
-	ctx     whisper.Context
-	mu      sync.Mutex
-	threads uint
-	log     *logger.ContextLogger
+	scheduler      *InferenceScheduler
+	client         uint64 // Scheduler client ID (fairness key)
+	language       string
+	threads        uint
+	translate      bool
+	temperature    float32
+	shortUtterance time.Duration
+	log            *logger.ContextLogger
+
+	// Rolling context: tail of previous final transcripts, appended to the base prompt
+	basePrompt    string
+	contextTokens int // Token budget for the tail (0 = disabled)
+	contextMu     sync.Mutex
+	contextText   string
 }
 
 // NewWhisperTranscriberShared creates a transcriber using the shared model
 func NewWhisperTranscriberShared(sharedModel *SharedWhisperModel, config WhisperConfig) (*WhisperTranscriberShared, error) {
 	log := config.Logger.With("whisper")
 
-	// Create context from shared model
-	ctx, err := sharedModel.NewContext()
-	if err != nil {
-		return nil, fmt.Errorf("failed to create context: %w", err)
+	// Validate language against the model
+	language := config.Language
+	if language == "" {
+		language = "auto"
 	}
-
-	// Configure context
-	if config.Language != "" {
-		ctx.SetLanguage(config.Language)
-	} else {
-		ctx.SetLanguage("auto")
+	if !sharedModel.IsMultilingual() && language != "auto" && language != "en" {
+		return nil, fmt.Errorf("model is English-only, cannot use language %q", language)
+	}
+	if language != "auto" && sharedModel.IsMultilingual() {
+		supported := false
+		for _, lang := range sharedModel.Languages() {
+			if lang == language {
+				supported = true
+				break
+			}
+		}
+		if !supported {
+			return nil, fmt.Errorf("failed to set language %q: unsupported language", language)
+		}
 	}
 
-	if config.Threads > 0 {
-		ctx.SetThreads(config.Threads)
+	// Set initial prompt (defaults to technical context)
+	initialPrompt := config.InitialPrompt
+	if initialPrompt == "" {
+		initialPrompt = DefaultInitialPrompt
 	}
 
-	ctx.SetTranslate(false)
-	ctx.SetTokenTimestamps(true)
+	// Without an explicit count, each worker gets its share of the CPUs
+	threads := config.Threads
+	if threads == 0 {
+		threads = uint(runtime.NumCPU() / sharedModel.scheduler.workers)
+		if threads == 0 {
+			threads = 1
+		}
+	}
 
-	// Set initial prompt for technical context
-	initialPrompt := "Voice commands for programming. Speaking to computer assistant. Direct address. Imperative mood. Technical instructions. JavaScript, TypeScript, Go, Solidity, Python, React, Node.js. Functions, variables, classes, interfaces, smart contracts, blockchain, API endpoints, database queries. Git commands, terminal operations, code editor."
-	ctx.SetInitialPrompt(initialPrompt)
+	contextTokens := contextBudget(initialPrompt, config.ContextTokens)
+	if contextTokens < config.ContextTokens {
+		log.Warn("Initial prompt leaves room for %d context tokens (%d requested)", contextTokens, config.ContextTokens)
+	}
 
-	log.InfoWithFields("Context created from shared model", map[string]interface{}{
-		"language": config.Language,
-		"threads":  config.Threads,
+	log.InfoWithFields("Transcriber created from shared model", map[string]interface{}{
+		"language":       language,
+		"threads":        threads,
+		"translate":      config.Translate,
+		"temperature":    config.Temperature,
+		"prompt_len":     len(initialPrompt),
+		"context_tokens": contextTokens,
 	})
 
 	return &WhisperTranscriberShared{
-		ctx:     ctx,
-		threads: config.Threads,
-		log:     log,
+		scheduler:      sharedModel.scheduler,
+		client:         sharedModel.scheduler.newClient(),
+		language:       language,
+		threads:        threads,
+		translate:      config.Translate,
+		temperature:    config.Temperature,
+		shortUtterance: config.ShortUtterance,
+		log:            log,
+		basePrompt:     initialPrompt,
+		contextTokens:  contextTokens,
 	}, nil
 }
 
-// Transcribe processes audio samples and returns the transcribed text
-func (w *WhisperTranscriberShared) Transcribe(audioSamples []float32) (string, error) {
-	w.mu.Lock()
-	defer w.mu.Unlock()
+// newContext creates a context on a worker's model configured with this transcriber's settings
+func (w *WhisperTranscriberShared) newContext(model whisper.Model) (whisper.Context, error) {
+	ctx, err := model.NewContext()
+	if err != nil {
+		return nil, fmt.Errorf("failed to create context from shared model: %w", err)
+	}
+
+	if ctx.IsMultilingual() {
+		if err := ctx.SetLanguage(w.language); err != nil {
+			return nil, fmt.Errorf("failed to set language %q: %w", w.language, err)
+		}
+	}
+
+	ctx.SetThreads(w.threads)
+
+	ctx.SetTranslate(w.translate)
+	ctx.SetTokenTimestamps(true)
+
+	ctx.SetTemperature(w.temperature)
+	ctx.SetInitialPrompt(w.Prompt())
+
+	return ctx, nil
+}
+
+// AppendContext adds a final transcript to the rolling context used to prompt the next chunk
+// The oldest words are dropped once the context exceeds its token budget
+func (w *WhisperTranscriberShared) AppendContext(text string) {
+	text = strings.TrimSpace(text)
+	if w.contextTokens <= 0 || text == "" {
+		return
+	}
+
+	w.contextMu.Lock()
+	defer w.contextMu.Unlock()
 
+	words := strings.Fields(w.contextText + " " + text)
+	for len(words) > 0 && estimateTokens(strings.Join(words, " ")) > w.contextTokens {
+		words = words[1:]
+	}
+	w.contextText = strings.Join(words, " ")
+}
+
+// ResetContext clears the rolling context (e.g. at the start of a new session)
+func (w *WhisperTranscriberShared) ResetContext() {
+	w.contextMu.Lock()
+	defer w.contextMu.Unlock()
+	w.contextText = ""
+}
+
+// Prompt returns the prompt the next transcription will use (base prompt plus rolling context)
+func (w *WhisperTranscriberShared) Prompt() string {
+	w.contextMu.Lock()
+	defer w.contextMu.Unlock()
+
+	if w.contextText == "" {
+		return w.basePrompt
+	}
+	return w.basePrompt + " " + w.contextText
+}
+
+// contextBudget returns the tokens left for rolling context after the base prompt
+// Whisper keeps only the last MaxContextTokens of the prompt, so base prompt and tail
+// must fit together or the base prompt is cut off
+func contextBudget(basePrompt string, requested int) int {
+	// One token for the space joining the prompt and the tail
+	room := MaxContextTokens - estimateTokens(basePrompt) - 1
+	if room < 0 {
+		room = 0
+	}
+	if requested > room {
+		return room
+	}
+	return requested
+}
+
+// estimateTokens approximates the Whisper token count of text
+// NOTE: the Go bindings do not expose the tokenizer; English BPE averages ~4 characters per token
+func estimateTokens(text string) int {
+	return (len(text) + 3) / 4
+}
+
+// WhisperOutput holds the text and timing information for one transcription
+type WhisperOutput struct {
+	Text       string
+	Segments   []Segment // Times are relative to the start of the transcribed audio
+	Confidence float64   // Mean text token probability (0 when no text tokens were produced)
+}
+
+// Segment is a timed span of transcribed text
+type Segment struct {
+	Start  time.Duration
+	End    time.Duration
+	Text   string
+	Tokens []Token // Text tokens only (special and timestamp tokens are dropped)
+}
+
+// Token is a single Whisper text token with its timing and probability
+type Token struct {
+	Start       time.Duration
+	End         time.Duration
+	Text        string
+	Probability float32
+}
+
+// Shift returns a copy of the segments moved by offset (e.g. chunk position in the session)
+func (o WhisperOutput) Shift(offset time.Duration) []Segment {
+	if len(o.Segments) == 0 {
+		return nil
+	}
+
+	shifted := make([]Segment, len(o.Segments))
+	for i, seg := range o.Segments {
+		tokens := make([]Token, len(seg.Tokens))
+		for j, tok := range seg.Tokens {
+			tok.Start += offset
+			tok.End += offset
+			tokens[j] = tok
+		}
+		shifted[i] = Segment{
+			Start:  seg.Start + offset,
+			End:    seg.End + offset,
+			Text:   seg.Text,
+			Tokens: tokens,
+		}
+	}
+	return shifted
+}
+
+// Transcribe processes a final chunk and returns the transcribed text with segment and token timings
+// Chunks shorter than the short-utterance threshold are scheduled ahead of longer ones
+func (w *WhisperTranscriberShared) Transcribe(audioSamples []float32) (WhisperOutput, error) {
+	priority := PriorityLong
+	if time.Duration(len(audioSamples))*time.Second/16000 < w.shortUtterance {
+		priority = PriorityShort
+	}
+	return w.transcribe(audioSamples, priority, nil)
+}
+
+// errPartialStale is returned for a partial whose chunk was cut while it waited in the queue
+var errPartialStale = errors.New("partial superseded by final")
+
+// TranscribePartial processes a snapshot of a chunk still being spoken (lowest priority)
+// If stale reports true by the time a worker picks the job up, Whisper is not run
+func (w *WhisperTranscriberShared) TranscribePartial(audioSamples []float32, stale func() bool) (WhisperOutput, error) {
+	return w.transcribe(audioSamples, PriorityPartial, stale)
+}
+
+// transcribe queues the audio on the inference scheduler and waits for the result
+func (w *WhisperTranscriberShared) transcribe(audioSamples []float32, priority JobPriority, stale func() bool) (WhisperOutput, error) {
 	if len(audioSamples) == 0 {
-		return "", fmt.Errorf("empty audio samples")
+		return WhisperOutput{}, fmt.Errorf("empty audio samples")
 	}
 
 	duration := float64(len(audioSamples)) / 16000.0
 	w.log.Debug("Processing %.2fs of audio", duration)
 
+	var output WhisperOutput
+	var processErr error
+	err := w.scheduler.Run(w.client, priority, func(model whisper.Model) {
+		if stale != nil && stale() {
+			processErr = errPartialStale
+			return
+		}
+		start := time.Now()
+		output, processErr = w.process(model, audioSamples)
+		if processErr == nil {
+			elapsed := time.Since(start).Seconds()
+			metrics.InferenceDuration.Observe(elapsed)
+			metrics.RealtimeFactor.Observe(elapsed / duration)
+		}
+	})
+	if err != nil {
+		return WhisperOutput{}, err
+	}
+	return output, processErr
+}
+
+// process runs Whisper on a worker's model
+func (w *WhisperTranscriberShared) process(model whisper.Model, audioSamples []float32) (WhisperOutput, error) {
+	ctx, err := w.newContext(model)
+	if err != nil {
+		return WhisperOutput{}, err
+	}
+
 	var fullText string
-	segments := []string{}
+	segments := []Segment{}
 
-	err := w.ctx.Process(audioSamples, nil, func(segment whisper.Segment) {
-		segments = append(segments, segment.Text)
+	err = ctx.Process(audioSamples, nil, func(segment whisper.Segment) {
+		seg := Segment{
+			Start: segment.Start,
+			End:   segment.End,
+			Text:  segment.Text,
+		}
+		for _, token := range segment.Tokens {
+			if !ctx.IsText(token) {
+				continue
+			}
+			seg.Tokens = append(seg.Tokens, Token{
+				Start:       token.Start,
+				End:         token.End,
+				Text:        token.Text,
+				Probability: token.P,
+			})
+		}
+		segments = append(segments, seg)
 	}, nil)
 
 	if err != nil {
-		return "", fmt.Errorf("failed to process audio: %w", err)
+		return WhisperOutput{}, fmt.Errorf("failed to process audio: %w", err)
 	}
 
 	// Join all segments
 	for i, seg := range segments {
-		if i > 0 && len(seg) > 0 {
+		if i > 0 && len(seg.Text) > 0 {
 			fullText += " "
 		}
-		fullText += seg
+		fullText += seg.Text
 	}
 
-	return fullText, nil
+	return WhisperOutput{
+		Text:       fullText,
+		Segments:   segments,
+		Confidence: tokenConfidence(segments),
+	}, nil
+}
+
+// tokenConfidence averages the probabilities of all text tokens in the segments
+// NOTE: whisper.cpp also computes a per-segment no-speech probability, but the Go
+// bindings do not expose it, so a chunk that produced no text tokens scores 0 instead
+func tokenConfidence(segments []Segment) float64 {
+	var sum float64
+	var count int
+	for _, seg := range segments {
+		for _, tok := range seg.Tokens {
+			sum += float64(tok.Probability)
+			count++
+		}
+	}
+
+	if count == 0 {
+		return 0
+	}
+	return sum / float64(count)
 }
 
-// Close releases the context (but not the shared model)
+// Close releases the transcriber (but not the shared model)
 func (w *WhisperTranscriberShared) Close() error {
-	// Context will be garbage collected
+	// Contexts are created per job and garbage collected
 	// The shared model stays alive
 	return nil
 }
@@ -0,0 +1,134 @@
+package transcription
+
+import (
+	"math"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestRollingContextBudget(t *testing.T) {
+	w := &WhisperTranscriberShared{basePrompt: "Base prompt.", contextTokens: 5}
+
+	if got := w.Prompt(); got != "Base prompt." {
+		t.Errorf("Expected base prompt with empty context, got %q", got)
+	}
+
+	w.AppendContext("one two three")
+	w.AppendContext("four five six seven")
+
+	context := strings.TrimPrefix(w.Prompt(), "Base prompt. ")
+	if estimateTokens(context) > 5 {
+		t.Errorf("Context %q exceeds token budget", context)
+	}
+	if !strings.HasSuffix(context, "seven") {
+		t.Errorf("Expected newest words to be kept, got %q", context)
+	}
+
+	w.ResetContext()
+	if got := w.Prompt(); got != "Base prompt." {
+		t.Errorf("Expected context cleared by reset, got %q", got)
+	}
+}
+
+func TestRollingContextDisabled(t *testing.T) {
+	w := &WhisperTranscriberShared{basePrompt: "Base prompt."}
+
+	w.AppendContext("should be ignored")
+	if got := w.Prompt(); got != "Base prompt." {
+		t.Errorf("Expected no context when disabled, got %q", got)
+	}
+}
+
+func TestContextBudget(t *testing.T) {
+	if got := contextBudget("Base prompt.", 50); got != 50 {
+		t.Errorf("Expected the requested budget when it fits, got %d", got)
+	}
+
+	// Base prompt and tail must fit in MaxContextTokens together
+	base := strings.Repeat("word ", 100) // ~125 tokens
+	got := contextBudget(base, MaxContextTokens)
+	if got+estimateTokens(base)+1 != MaxContextTokens {
+		t.Errorf("Expected budget to fill the remaining prompt space, got %d", got)
+	}
+
+	if got := contextBudget(strings.Repeat("x", 4*MaxContextTokens), 10); got != 0 {
+		t.Errorf("Expected no room for context after an oversized prompt, got %d", got)
+	}
+}
+
+func TestLoadWAVRoundTrip(t *testing.T) {
+	samples := []int16{0, 1, -1, 32767, -32768, 1234}
+	pcm := make([]byte, len(samples)*2)
+	for i, s := range samples {
+		pcm[i*2] = byte(s)
+		pcm[i*2+1] = byte(s >> 8)
+	}
+
+	path := filepath.Join(t.TempDir(), "chunk.wav")
+	if err := saveWAV(path, pcm, 16000, 1, 16); err != nil {
+		t.Fatalf("saveWAV failed: %v", err)
+	}
+
+	loaded, err := LoadWAV(path)
+	if err != nil {
+		t.Fatalf("LoadWAV failed: %v", err)
+	}
+	if len(loaded) != len(samples) {
+		t.Fatalf("Expected %d samples, got %d", len(samples), len(loaded))
+	}
+	for i := range samples {
+		if loaded[i] != samples[i] {
+			t.Errorf("Sample %d: expected %d, got %d", i, samples[i], loaded[i])
+		}
+	}
+}
+
+func TestWhisperOutputShift(t *testing.T) {
+	output := WhisperOutput{
+		Segments: []Segment{{
+			Start: 0,
+			End:   2 * time.Second,
+			Text:  "hello world",
+			Tokens: []Token{
+				{Start: 0, End: time.Second, Text: "hello"},
+				{Start: time.Second, End: 2 * time.Second, Text: " world"},
+			},
+		}},
+	}
+
+	shifted := output.Shift(90 * time.Second)
+	if len(shifted) != 1 {
+		t.Fatalf("Expected 1 segment, got %d", len(shifted))
+	}
+	if shifted[0].Start != 90*time.Second || shifted[0].End != 92*time.Second {
+		t.Errorf("Unexpected segment times %v-%v", shifted[0].Start, shifted[0].End)
+	}
+	if tok := shifted[0].Tokens[1]; tok.Start != 91*time.Second || tok.End != 92*time.Second {
+		t.Errorf("Unexpected token times %v-%v", tok.Start, tok.End)
+	}
+
+	// The original output is left untouched (partials and finals share segments)
+	if output.Segments[0].Start != 0 || output.Segments[0].Tokens[1].Start != time.Second {
+		t.Error("Shift modified the original segments")
+	}
+
+	if (WhisperOutput{}).Shift(time.Second) != nil {
+		t.Error("Expected nil segments for empty output")
+	}
+}
+
+func TestTokenConfidence(t *testing.T) {
+	segments := []Segment{
+		{Tokens: []Token{{Probability: 0.9}, {Probability: 0.5}}},
+		{Tokens: []Token{{Probability: 0.7}}},
+	}
+	if got := tokenConfidence(segments); math.Abs(got-0.7) > 1e-6 {
+		t.Errorf("Expected mean probability 0.7, got %f", got)
+	}
+
+	if got := tokenConfidence([]Segment{{Text: "no tokens"}}); got != 0 {
+		t.Errorf("Expected 0 without tokens, got %f", got)
+	}
+}
@@ -2,16 +2,28 @@ package webrtc
 
 import (
 	"encoding/json"
+	"errors"
 	"fmt"
+	"path/filepath"
+	"strconv"
+	"strings"
 	"sync"
+	"sync/atomic"
 	"time"
 
+	"github.com/google/uuid"
+	"github.com/lucianHymer/streaming-transcription/server/internal/metrics"
 	"github.com/lucianHymer/streaming-transcription/server/internal/transcription"
+	"github.com/lucianHymer/streaming-transcription/shared/audiocodec"
 	"github.com/lucianHymer/streaming-transcription/shared/logger"
 	"github.com/lucianHymer/streaming-transcription/shared/protocol"
 	"github.com/pion/webrtc/v4"
 )
 
+// previousSessionDrainTimeout bounds how long a replaced session may take to deliver
+// the chunks it was transcribing when the client started a new one
+const previousSessionDrainTimeout = 30 * time.Second
+
 // Manager handles WebRTC peer connections
 type Manager struct {
 	logger      *logger.ContextLogger
@@ -22,8 +34,32 @@ type Manager struct {
 	// Factory config for creating pipelines
 	sharedWhisperModel *transcription.SharedWhisperModel
 	whisperConfig      transcription.WhisperConfig
+	whisperLimits      transcription.WhisperLimits
+	vadDefaults        transcription.VADSettings
+	vadLimits          transcription.VADLimits
 	rnnoiseModelPath   string
 	enableDebugWAV     bool
+	partialInterval    time.Duration
+	partialMax         time.Duration
+	minConfidence      float64
+	dropLowConfidence  bool
+	filterConfig       transcription.FilterConfig
+	outboxConfig       transcription.OutboxConfig
+	lastSession        atomic.Uint64 // Last server-assigned session ID
+
+	// Sessions of disconnected peers, by resume token (guarded by peerConnsMu)
+	resumeGrace time.Duration
+	detached    map[string]*detachedSession
+}
+
+// detachedSession is the pipeline of a disconnected peer, waiting for the client to resume it
+type detachedSession struct {
+	pipeline    *transcription.TranscriptionPipeline
+	opusDecoder *audiocodec.OpusDecoder
+	expiry      *time.Timer
+
+	// Only the user who started the session may resume it
+	user string
 }
 
 // PeerConnection represents a single WebRTC peer connection
@@ -34,14 +70,45 @@ type PeerConnection struct {
 	pipeline    *transcription.TranscriptionPipeline // Each peer has their own
 	logger      *logger.ContextLogger
 	onMessage   func(msg *protocol.Message)
+	onAudio     func(chunk protocol.AudioChunkData, timestamp int64) // Binary audio frames
+	send        func(data []byte) error                              // WebSocket peers: writes a message to the socket
+	resumeToken string                                               // Presented by the client on reconnect to resume the session
+	ready       chan struct{}                                        // Closed once messages can be sent
+	readyOnce   sync.Once
+	done        chan struct{} // Closed when the peer is removed
+
+	trackTransport atomic.Bool                        // Audio arrives on an Opus media track instead of the DataChannel
+	hello          atomic.Pointer[protocol.HelloData] // Client's handshake (nil if it skipped it)
+
+	// Shown by the admin API
+	connectedAt time.Time
+	remoteAddr  atomic.Pointer[string]
+	user        atomic.Pointer[string]                      // Authenticated user
+	settings    atomic.Pointer[protocol.ControlStartedData] // Effective settings of the current session
+	audioBytes  atomic.Uint64                               // Audio payload bytes received (as sent, before decoding)
+	audioChunks atomic.Uint64
+
+	// Opus decoding state of the current session
+	decoderMu   sync.Mutex
+	opusDecoder *audiocodec.OpusDecoder // Created on the session's first Opus chunk
 }
 
 // ManagerConfig contains configuration for creating pipelines
 type ManagerConfig struct {
 	SharedWhisperModel *transcription.SharedWhisperModel
 	WhisperConfig      transcription.WhisperConfig
+	WhisperLimits      transcription.WhisperLimits // Bounds for per-session Whisper settings
+	VADDefaults        transcription.VADSettings   // VAD settings for sessions that leave them unset
+	VADLimits          transcription.VADLimits     // Bounds for per-session VAD settings
 	RNNoiseModelPath   string
 	EnableDebugWAV     bool
+	PartialInterval    time.Duration // New speech between partial transcripts (0 = disabled)
+	PartialMax         time.Duration // No partials once the chunk is longer than this (0 = no limit)
+	MinConfidence      float64       // Final results below this confidence are flagged or dropped (0 = disabled)
+	DropLowConfidence  bool          // Drop low-confidence results instead of flagging them
+	Filter             transcription.FilterConfig
+	Outbox             transcription.OutboxConfig // Per-session result delivery (memory queue, spill, re-delivery)
+	ResumeGracePeriod  time.Duration              // How long a disconnected peer's session waits to be resumed (0 = closed at once)
 }
 
 // New creates a new WebRTC manager
@@ -56,45 +123,373 @@ func New(log *logger.Logger, iceServers []webrtc.ICEServer, config ManagerConfig
 		config:             webrtcConfig,
 		sharedWhisperModel: config.SharedWhisperModel,
 		whisperConfig:      config.WhisperConfig,
+		whisperLimits:      config.WhisperLimits,
+		vadDefaults:        config.VADDefaults,
+		vadLimits:          config.VADLimits,
 		rnnoiseModelPath:   config.RNNoiseModelPath,
 		enableDebugWAV:     config.EnableDebugWAV,
+		partialInterval:    config.PartialInterval,
+		partialMax:         config.PartialMax,
+		minConfidence:      config.MinConfidence,
+		dropLowConfidence:  config.DropLowConfidence,
+		filterConfig:       config.Filter,
+		outboxConfig:       config.Outbox,
+		resumeGrace:        config.ResumeGracePeriod,
+		detached:           make(map[string]*detachedSession),
+	}
+}
+
+// newPeer creates the state shared by WebRTC and WebSocket peers
+func (m *Manager) newPeer(id string, onMessage func(msg *protocol.Message), onAudio func(chunk protocol.AudioChunkData, timestamp int64)) *PeerConnection {
+	return &PeerConnection{
+		ID:          id,
+		logger:      m.logger,
+		onMessage:   onMessage,
+		onAudio:     onAudio,
+		resumeToken: uuid.NewString(),
+		ready:       make(chan struct{}),
+		done:        make(chan struct{}),
+		connectedAt: time.Now(),
 	}
 }
 
 // CreatePipelineForPeer creates a new pipeline with client-provided settings
-func (m *Manager) CreatePipelineForPeer(peerID string, settings *protocol.ControlStartData) (*transcription.TranscriptionPipeline, error) {
+// Returns the session's effective settings, to be echoed to the client
+func (m *Manager) CreatePipelineForPeer(peerID string, settings *protocol.ControlStartData) (*transcription.TranscriptionPipeline, *protocol.ControlStartedData, error) {
 	m.peerConnsMu.RLock()
 	peer, exists := m.peerConns[peerID]
 	m.peerConnsMu.RUnlock()
 
 	if !exists {
-		return nil, fmt.Errorf("peer %s not found", peerID)
+		return nil, nil, fmt.Errorf("peer %s not found", peerID)
+	}
+
+	// Apply client Whisper and VAD settings on top of server defaults
+	whisperConfig, err := m.whisperConfigForSession(settings)
+	if err != nil {
+		return nil, nil, fmt.Errorf("%w: %v", ErrInvalidSettings, err)
+	}
+	vad, adjusted, err := m.vadSettingsForSession(settings)
+	if err != nil {
+		return nil, nil, fmt.Errorf("%w: %v", ErrInvalidSettings, err)
+	}
+	if len(adjusted) > 0 {
+		m.logger.Warn("Clamped VAD settings %v of peer %s to the server limits", adjusted, peerID)
+	}
+
+	sessionID := settings.SessionID
+	if sessionID == 0 {
+		sessionID = m.lastSession.Add(1)
 	}
 
 	// Create pipeline config with client settings
 	config := transcription.PipelineConfig{
 		SharedWhisperModel:     m.sharedWhisperModel,
-		WhisperConfig:          m.whisperConfig,
+		WhisperConfig:          whisperConfig,
 		RNNoiseModelPath:       m.rnnoiseModelPath,
-		VADEnergyThreshold:     settings.VADEnergyThreshold,
-		SilenceThreshold:       time.Duration(settings.SilenceThresholdMs) * time.Millisecond,
-		MinChunkDuration:       time.Duration(settings.MinChunkDurationMs) * time.Millisecond,
-		MaxChunkDuration:       time.Duration(settings.MaxChunkDurationMs) * time.Millisecond,
-		SpeechDensityThreshold: settings.SpeechDensityThreshold,
+		VADEnergyThreshold:     vad.EnergyThreshold,
+		SilenceThreshold:       vad.SilenceThreshold,
+		MinChunkDuration:       vad.MinChunkDuration,
+		MaxChunkDuration:       vad.MaxChunkDuration,
+		SpeechDensityThreshold: vad.SpeechDensityThreshold,
+		PartialInterval:        m.partialInterval,
+		PartialMaxDuration:     m.partialMax,
+		MinConfidence:          m.minConfidence,
+		DropLowConfidence:      m.dropLowConfidence,
+		Filter:                 m.filterConfig,
+		Outbox:                 m.outboxConfig,
 		EnableDebugWAV:         m.enableDebugWAV,
+		SessionID:              sessionID,
 	}
 
 	// Create pipeline (will use shared model instead of loading new one)
 	pipeline, err := transcription.NewTranscriptionPipeline(config)
 	if err != nil {
-		return nil, fmt.Errorf("failed to create pipeline: %w", err)
+		return nil, nil, fmt.Errorf("failed to create pipeline: %w", err)
 	}
 
-	// Store in peer connection
+	// Swap the pipeline in, unless the peer went away while it was built
+	m.peerConnsMu.Lock()
+	if m.peerConns[peerID] != peer {
+		m.peerConnsMu.Unlock()
+		pipeline.Close()
+		return nil, nil, fmt.Errorf("peer %s not found", peerID)
+	}
+	previous := peer.pipeline
 	peer.pipeline = pipeline
-	m.logger.Info("Created pipeline for peer %s with VAD threshold %.0f", peerID, settings.VADEnergyThreshold)
+	m.peerConnsMu.Unlock()
+
+	// Clients restart their encoder with every session
+	peer.resetAudioDecoder()
+
+	// The previous session's last chunks are still being transcribed; its result
+	// sender keeps delivering them under its own session ID until it is closed
+	if previous != nil {
+		go m.retirePipeline(peer, previous)
+	}
+
+	m.logger.Info("Created pipeline for peer %s (session %d) with VAD threshold %.0f, language %q", peerID, sessionID, vad.EnergyThreshold, whisperConfig.Language)
+
+	started := &protocol.ControlStartedData{
+		SessionID:              sessionID,
+		VADEnergyThreshold:     vad.EnergyThreshold,
+		SilenceThresholdMs:     int(vad.SilenceThreshold.Milliseconds()),
+		MinChunkDurationMs:     int(vad.MinChunkDuration.Milliseconds()),
+		MaxChunkDurationMs:     int(vad.MaxChunkDuration.Milliseconds()),
+		SpeechDensityThreshold: vad.SpeechDensityThreshold,
+		Language:               whisperConfig.Language,
+		Translate:              whisperConfig.Translate,
+		Threads:                int(whisperConfig.Threads),
+		Temperature:            float64(whisperConfig.Temperature),
+		Adjusted:               adjusted,
+	}
+	peer.settings.Store(started)
+	return pipeline, started, nil
+}
+
+// retirePipeline stops a peer's replaced pipeline and closes it once its in-flight
+// chunks are delivered (or after previousSessionDrainTimeout, or when the peer is removed)
+func (m *Manager) retirePipeline(peer *PeerConnection, pipeline *transcription.TranscriptionPipeline) {
+	if pipeline.IsActive() {
+		pipeline.Stop()
+	}
+	if !pipeline.Drain(previousSessionDrainTimeout, peer.Done()) {
+		m.logger.Warn("Closing previous pipeline (session %d) of peer %s with results undelivered", pipeline.SessionID(), peer.ID)
+	}
+	pipeline.Close()
+	m.logger.Info("Closed previous pipeline (session %d) for peer %s", pipeline.SessionID(), peer.ID)
+}
+
+// vadSettingsForSession merges client-requested VAD settings over the server defaults
+// and bounds them by the server limits, returning the names of clamped settings
+func (m *Manager) vadSettingsForSession(settings *protocol.ControlStartData) (transcription.VADSettings, []string, error) {
+	vad := m.vadDefaults
+	if settings.VADEnergyThreshold > 0 {
+		vad.EnergyThreshold = settings.VADEnergyThreshold
+	}
+	if settings.SilenceThresholdMs > 0 {
+		vad.SilenceThreshold = time.Duration(settings.SilenceThresholdMs) * time.Millisecond
+	}
+	if settings.MinChunkDurationMs > 0 {
+		vad.MinChunkDuration = time.Duration(settings.MinChunkDurationMs) * time.Millisecond
+	}
+	if settings.MaxChunkDurationMs > 0 {
+		vad.MaxChunkDuration = time.Duration(settings.MaxChunkDurationMs) * time.Millisecond
+	}
+	if settings.SpeechDensityThreshold > 0 {
+		vad.SpeechDensityThreshold = settings.SpeechDensityThreshold
+	}
+
+	limits := m.vadLimits
+	var adjusted []string
+	var err error
+	bound := func(name string, value *float64, lo, hi float64) {
+		if err != nil {
+			return
+		}
+		bounded := *value
+		if lo > 0 && bounded < lo {
+			bounded = lo
+		}
+		if hi > 0 && bounded > hi {
+			bounded = hi
+		}
+		if bounded == *value {
+			return
+		}
+		if limits.Reject {
+			format := func(v float64) string { return strconv.FormatFloat(v, 'f', -1, 64) }
+			err = fmt.Errorf("%s %s is outside the server range [%s, %s]", name, format(*value), format(lo), format(hi))
+			return
+		}
+		*value = bounded
+		adjusted = append(adjusted, name)
+	}
+	boundDuration := func(name string, value *time.Duration, lo, hi time.Duration) {
+		ms := float64(value.Milliseconds())
+		bound(name, &ms, float64(lo.Milliseconds()), float64(hi.Milliseconds()))
+		*value = time.Duration(ms) * time.Millisecond
+	}
+
+	bound("vad_energy_threshold", &vad.EnergyThreshold, limits.Min.EnergyThreshold, limits.Max.EnergyThreshold)
+	boundDuration("silence_threshold_ms", &vad.SilenceThreshold, limits.Min.SilenceThreshold, limits.Max.SilenceThreshold)
+	boundDuration("min_chunk_duration_ms", &vad.MinChunkDuration, limits.Min.MinChunkDuration, limits.Max.MinChunkDuration)
+	boundDuration("max_chunk_duration_ms", &vad.MaxChunkDuration, limits.Min.MaxChunkDuration, limits.Max.MaxChunkDuration)
+	bound("speech_density_threshold", &vad.SpeechDensityThreshold, limits.Min.SpeechDensityThreshold, limits.Max.SpeechDensityThreshold)
+	if err != nil {
+		return vad, nil, err
+	}
+
+	// Chunks cannot be required to be longer than they may get
+	if vad.MinChunkDuration > vad.MaxChunkDuration {
+		if limits.Reject {
+			return vad, nil, fmt.Errorf("min_chunk_duration_ms %d exceeds max_chunk_duration_ms %d",
+				vad.MinChunkDuration.Milliseconds(), vad.MaxChunkDuration.Milliseconds())
+		}
+		vad.MinChunkDuration = vad.MaxChunkDuration
+		adjusted = append(adjusted, "min_chunk_duration_ms")
+	}
+	return vad, adjusted, nil
+}
+
+// ErrInvalidSettings is returned when client settings are rejected
+var ErrInvalidSettings = errors.New("invalid settings")
+
+// whisperConfigForSession validates client-requested Whisper settings against the
+// server limits and merges them over the server defaults
+func (m *Manager) whisperConfigForSession(settings *protocol.ControlStartData) (transcription.WhisperConfig, error) {
+	config := m.whisperConfig
+	limits := m.whisperLimits
+
+	if settings.Language != "" {
+		if err := m.validateLanguage(settings.Language); err != nil {
+			return config, err
+		}
+		config.Language = settings.Language
+	}
+
+	if settings.Translate {
+		if !m.sharedWhisperModel.IsMultilingual() {
+			return config, fmt.Errorf("translation requires a multilingual model")
+		}
+		config.Translate = true
+	}
+
+	if settings.InitialPrompt != "" || len(settings.Vocabulary) > 0 {
+		if settings.InitialPrompt != "" {
+			config.InitialPrompt = settings.InitialPrompt
+		}
+		if len(settings.Vocabulary) > 0 {
+			prompt := config.InitialPrompt
+			if prompt == "" {
+				prompt = transcription.DefaultInitialPrompt
+			}
+			config.InitialPrompt = prompt + " " + strings.Join(settings.Vocabulary, ", ") + "."
+		}
+		if len(config.InitialPrompt) > limits.MaxPromptChars {
+			return config, fmt.Errorf("initial prompt is %d characters (max %d)", len(config.InitialPrompt), limits.MaxPromptChars)
+		}
+	}
+
+	if settings.Threads < 0 {
+		return config, fmt.Errorf("threads must not be negative")
+	}
+	if settings.Threads > 0 {
+		if uint(settings.Threads) > limits.MaxThreads {
+			return config, fmt.Errorf("threads %d exceeds server limit %d", settings.Threads, limits.MaxThreads)
+		}
+		config.Threads = uint(settings.Threads)
+	}
+
+	if settings.Temperature < 0 {
+		return config, fmt.Errorf("temperature must not be negative")
+	}
+	if settings.Temperature > 0 {
+		if float32(settings.Temperature) > limits.MaxTemperature {
+			return config, fmt.Errorf("temperature %.2f exceeds server limit %.2f", settings.Temperature, limits.MaxTemperature)
+		}
+		config.Temperature = float32(settings.Temperature)
+	}
+
+	return config, nil
+}
 
-	return pipeline, nil
+// validateLanguage checks a requested language against the model and server allow-list
+func (m *Manager) validateLanguage(language string) error {
+	if language == "auto" {
+		return nil
+	}
+
+	if len(m.whisperLimits.AllowedLanguages) > 0 {
+		allowed := false
+		for _, lang := range m.whisperLimits.AllowedLanguages {
+			if lang == language {
+				allowed = true
+				break
+			}
+		}
+		if !allowed {
+			return fmt.Errorf("language %q is not allowed on this server", language)
+		}
+	}
+
+	for _, lang := range m.sharedWhisperModel.Languages() {
+		if lang == language {
+			return nil
+		}
+	}
+	return fmt.Errorf("language %q is not supported by the model", language)
+}
+
+// Welcome describes the server for the control.welcome handshake message
+// The caller adds the transport features and the negotiated protocol version
+func (m *Manager) Welcome() protocol.WelcomeData {
+	welcome := protocol.WelcomeData{
+		ProtocolVersion: protocol.ProtocolVersion,
+		Model:           filepath.Base(m.sharedWhisperModel.GetPath()),
+		Multilingual:    m.sharedWhisperModel.IsMultilingual(),
+		AudioEncodings:  []string{protocol.AudioEncodingPCM16},
+		Features:        []string{protocol.FeatureTimestamps, protocol.FeatureAudioStats},
+		Limits: protocol.SessionLimits{
+			MaxThreads:     int(m.whisperLimits.MaxThreads),
+			MaxTemperature: float64(m.whisperLimits.MaxTemperature),
+			MaxPromptChars: m.whisperLimits.MaxPromptChars,
+		},
+	}
+	if audiocodec.Available {
+		welcome.AudioEncodings = append(welcome.AudioEncodings, protocol.AudioEncodingOpus)
+	}
+	if m.partialInterval > 0 {
+		welcome.Features = append(welcome.Features, protocol.FeaturePartials)
+	}
+	if transcription.RNNoiseAvailable {
+		welcome.Features = append(welcome.Features, protocol.FeatureNoiseSuppression)
+	}
+	if m.resumeGrace > 0 {
+		welcome.Features = append(welcome.Features, protocol.FeatureSessionResume)
+	}
+
+	// Languages the model supports and the server allows
+	for _, lang := range m.sharedWhisperModel.Languages() {
+		if m.validateLanguage(lang) == nil {
+			welcome.Languages = append(welcome.Languages, lang)
+		}
+	}
+	return welcome
+}
+
+// ManagerStats counts the manager's peers and sessions
+type ManagerStats struct {
+	Peers     int // Connected peers
+	Pipelines int // Active pipelines, including detached ones
+	Detached  int // Sessions of disconnected peers waiting to be resumed
+}
+
+// Stats returns the current peer and session counts
+func (m *Manager) Stats() ManagerStats {
+	m.peerConnsMu.RLock()
+	defer m.peerConnsMu.RUnlock()
+
+	stats := ManagerStats{
+		Peers:    len(m.peerConns),
+		Detached: len(m.detached),
+	}
+	for _, peer := range m.peerConns {
+		if peer.pipeline != nil && peer.pipeline.IsActive() {
+			stats.Pipelines++
+		}
+	}
+	for _, session := range m.detached {
+		if session.pipeline.IsActive() {
+			stats.Pipelines++
+		}
+	}
+	return stats
+}
+
+// SchedulerStats returns the server-wide transcription scheduler statistics
+func (m *Manager) SchedulerStats() transcription.SchedulerStats {
+	return m.sharedWhisperModel.SchedulerStats()
 }
 
 // GetPeerPipeline returns the pipeline for a specific peer
@@ -109,7 +504,8 @@ func (m *Manager) GetPeerPipeline(peerID string) *transcription.TranscriptionPip
 }
 
 // CreatePeerConnection creates a new peer connection
-func (m *Manager) CreatePeerConnection(id string, onMessage func(msg *protocol.Message)) (*PeerConnection, error) {
+// JSON messages go to onMessage, binary audio frames to onAudio
+func (m *Manager) CreatePeerConnection(id string, onMessage func(msg *protocol.Message), onAudio func(chunk protocol.AudioChunkData, timestamp int64)) (*PeerConnection, error) {
 	m.peerConnsMu.Lock()
 	defer m.peerConnsMu.Unlock()
 
@@ -123,12 +519,8 @@ func (m *Manager) CreatePeerConnection(id string, onMessage func(msg *protocol.M
 		return nil, fmt.Errorf("failed to create peer connection: %w", err)
 	}
 
-	peer := &PeerConnection{
-		ID:        id,
-		pc:        pc,
-		logger:    m.logger,
-		onMessage: onMessage,
-	}
+	peer := m.newPeer(id, onMessage, onAudio)
+	peer.pc = pc
 
 	// Set up connection state handler
 	pc.OnConnectionStateChange(func(state webrtc.PeerConnectionState) {
@@ -145,6 +537,21 @@ func (m *Manager) CreatePeerConnection(id string, onMessage func(msg *protocol.M
 		peer.logger.Debug("Peer %s ICE state: %s", id, state.String())
 	})
 
+	// Audio tracks are only read when the media track transport was negotiated
+	pc.OnTrack(func(track *webrtc.TrackRemote, receiver *webrtc.RTPReceiver) {
+		if track.Kind() != webrtc.RTPCodecTypeAudio || !peer.trackTransport.Load() {
+			peer.logger.Warn("Ignoring %s track from peer %s", track.Kind(), id)
+			return
+		}
+		if !strings.EqualFold(track.Codec().MimeType, webrtc.MimeTypeOpus) {
+			peer.logger.Warn("Ignoring %s audio track from peer %s (expected Opus)", track.Codec().MimeType, id)
+			return
+		}
+
+		peer.logger.Info("Receiving Opus audio track from peer %s", id)
+		peer.readTrack(track)
+	})
+
 	// Set up DataChannel handler (client will create the channel)
 	pc.OnDataChannel(func(dc *webrtc.DataChannel) {
 		peer.logger.Info("DataChannel '%s' opened by peer %s", dc.Label(), id)
@@ -157,6 +564,7 @@ func (m *Manager) CreatePeerConnection(id string, onMessage func(msg *protocol.M
 
 		dc.OnOpen(func() {
 			peer.logger.Info("DataChannel '%s' is open", dc.Label())
+			peer.readyOnce.Do(func() { close(peer.ready) })
 		})
 
 		dc.OnClose(func() {
@@ -174,14 +582,45 @@ func (m *Manager) CreatePeerConnection(id string, onMessage func(msg *protocol.M
 	return peer, nil
 }
 
+// CreateWebSocketPeer registers a peer that streams over a plain WebSocket instead of WebRTC
+// Messages read from the socket go to Receive; send writes a message to the socket
+func (m *Manager) CreateWebSocketPeer(id string, send func(data []byte) error, onMessage func(msg *protocol.Message), onAudio func(chunk protocol.AudioChunkData, timestamp int64)) (*PeerConnection, error) {
+	m.peerConnsMu.Lock()
+	defer m.peerConnsMu.Unlock()
+
+	if _, exists := m.peerConns[id]; exists {
+		return nil, fmt.Errorf("peer connection %s already exists", id)
+	}
+
+	peer := m.newPeer(id, onMessage, onAudio)
+	peer.send = send
+	close(peer.ready)
+
+	m.peerConns[id] = peer
+	m.logger.Info("Created WebSocket peer %s", id)
+
+	return peer, nil
+}
+
 // RemovePeerConnection removes a peer connection
 func (m *Manager) RemovePeerConnection(id string) {
+	m.removePeer(id, true)
+}
+
+// removePeer removes a peer, keeping its session for the client to resume if
+// resumable and resume is enabled; returns false if the peer does not exist
+func (m *Manager) removePeer(id string, resumable bool) bool {
 	m.peerConnsMu.Lock()
 	defer m.peerConnsMu.Unlock()
 
-	if peer, exists := m.peerConns[id]; exists {
-		// Clean up pipeline if it exists
-		if peer.pipeline != nil {
+	peer, exists := m.peerConns[id]
+	if exists {
+		close(peer.done)
+
+		// Keep the pipeline for the client to resume it, or clean it up
+		if peer.pipeline != nil && resumable && m.resumeGrace > 0 {
+			m.detachSession(peer)
+		} else if peer.pipeline != nil {
 			peer.pipeline.Stop()
 			peer.pipeline.Close()
 			m.logger.Info("Closed pipeline for peer %s", id)
@@ -191,8 +630,116 @@ func (m *Manager) RemovePeerConnection(id string) {
 			peer.pc.Close()
 		}
 		delete(m.peerConns, id)
+		metrics.AudioBytesReceived.Delete(id)
 		m.logger.Info("Removed peer connection %s", id)
 	}
+	return exists
+}
+
+// detachSession keeps a removed peer's pipeline for the resume grace period (caller holds peerConnsMu)
+// The pipeline keeps running, so buffered audio and results in flight are not lost
+func (m *Manager) detachSession(peer *PeerConnection) {
+	token := peer.resumeToken
+
+	peer.decoderMu.Lock()
+	decoder := peer.opusDecoder
+	peer.decoderMu.Unlock()
+
+	m.detached[token] = &detachedSession{
+		pipeline:    peer.pipeline,
+		opusDecoder: decoder,
+		expiry:      time.AfterFunc(m.resumeGrace, func() { m.expireSession(token) }),
+		user:        peer.User(),
+	}
+	m.logger.Info("Keeping session %d of peer %s for %v to be resumed", peer.pipeline.SessionID(), peer.ID, m.resumeGrace)
+	peer.pipeline = nil
+}
+
+// expireSession closes a detached session that was not resumed in time
+func (m *Manager) expireSession(token string) {
+	m.peerConnsMu.Lock()
+	session, exists := m.detached[token]
+	delete(m.detached, token)
+	m.peerConnsMu.Unlock()
+
+	if !exists {
+		return
+	}
+	if session.pipeline.IsActive() {
+		session.pipeline.Stop()
+	}
+	session.pipeline.Close()
+	m.logger.Info("Closed session %d, not resumed within %v", session.pipeline.SessionID(), m.resumeGrace)
+}
+
+// ResumeSession reattaches the session detached under token to a new peer
+// Returns the resumed pipeline, or nil if the token is unknown or expired, or the
+// session belongs to another user (set the peer's user first)
+// (the peer then keeps its own fresh token)
+func (m *Manager) ResumeSession(peerID, token string) *transcription.TranscriptionPipeline {
+	if token == "" {
+		return nil
+	}
+
+	m.peerConnsMu.Lock()
+	defer m.peerConnsMu.Unlock()
+
+	peer, exists := m.peerConns[peerID]
+	session, detached := m.detached[token]
+	if !exists || !detached || peer.pipeline != nil {
+		metrics.SessionResumes.With("expired").Inc()
+		return nil
+	}
+	// The session stays detached for its own user to resume
+	if session.user != peer.User() {
+		metrics.SessionResumes.With("wrong_user").Inc()
+		m.logger.Warn("Peer %s (user %s) presented the resume token of another user's session", peerID, peer.User())
+		return nil
+	}
+	delete(m.detached, token)
+	session.expiry.Stop()
+
+	peer.pipeline = session.pipeline
+	peer.resumeToken = token
+
+	// The client's encoder carried on across the reconnect
+	peer.decoderMu.Lock()
+	peer.opusDecoder = session.opusDecoder
+	peer.decoderMu.Unlock()
+
+	metrics.SessionResumes.With("resumed").Inc()
+	m.logger.Info("Peer %s resumed session %d", peerID, session.pipeline.SessionID())
+	return session.pipeline
+}
+
+// SetHello records the client's control.hello
+func (p *PeerConnection) SetHello(hello *protocol.HelloData) {
+	p.hello.Store(hello)
+}
+
+// ClientFeature reports whether the client announced feature in control.hello
+// For clients that skipped the handshake it returns legacy, the behaviour before it existed
+func (p *PeerConnection) ClientFeature(feature string, legacy bool) bool {
+	hello := p.hello.Load()
+	if hello == nil {
+		return legacy
+	}
+	return protocol.HasFeature(hello.Features, feature)
+}
+
+// ResumeToken returns the token the client presents to resume this peer's session
+func (p *PeerConnection) ResumeToken() string {
+	return p.resumeToken
+}
+
+// Ready is closed once messages can be sent to the peer
+func (p *PeerConnection) Ready() <-chan struct{} {
+	return p.ready
+}
+
+// Done is closed when the peer is removed
+func (p *PeerConnection) Done() <-chan struct{} {
+	return p.done
 }
 
 // GetPeerConnection returns a peer connection by ID
@@ -266,7 +813,7 @@ func (p *PeerConnection) AddICECandidate(candidateJSON string) error {
 
 // SendMessage sends a message over the DataChannel
 func (p *PeerConnection) SendMessage(msg *protocol.Message) error {
-	if p.dataChannel == nil {
+	if p.send == nil && p.dataChannel == nil {
 		return fmt.Errorf("data channel not ready")
 	}
 
@@ -275,11 +822,31 @@ func (p *PeerConnection) SendMessage(msg *protocol.Message) error {
 		return fmt.Errorf("failed to marshal message: %w", err)
 	}
 
+	if p.send != nil {
+		return p.send(data)
+	}
 	return p.dataChannel.Send(data)
 }
 
+// Receive handles a message read from a WebSocket peer
+func (p *PeerConnection) Receive(data []byte) {
+	p.handleMessage(data)
+}
+
 // handleMessage handles incoming DataChannel messages
 func (p *PeerConnection) handleMessage(data []byte) {
+	if protocol.IsAudioFrame(data) {
+		chunk, timestamp, err := protocol.DecodeAudioFrame(data)
+		if err != nil {
+			p.logger.Error("Failed to decode audio frame: %v", err)
+			return
+		}
+		if p.onAudio != nil {
+			p.onAudio(chunk, timestamp)
+		}
+		return
+	}
+
 	var msg protocol.Message
 	if err := json.Unmarshal(data, &msg); err != nil {
 		p.logger.Error("Failed to unmarshal message: %v", err)
@@ -293,6 +860,67 @@ func (p *PeerConnection) handleMessage(data []byte) {
 	}
 }
 
+// NegotiateTransport chooses the audio transport for the one a client requested
+// The media track needs Opus support; otherwise the client falls back to the DataChannel
+func (p *PeerConnection) NegotiateTransport(requested string) string {
+	if requested == protocol.TransportTrack && audiocodec.Available {
+		p.trackTransport.Store(true)
+		return protocol.TransportTrack
+	}
+	p.trackTransport.Store(false)
+	return protocol.TransportDataChannel
+}
+
+// TrackTransport returns whether the peer's audio was negotiated onto the media track
+func (p *PeerConnection) TrackTransport() bool {
+	return p.trackTransport.Load()
+}
+
+// ErrUnsupportedEncoding is returned for audio in an encoding the server cannot decode
+var ErrUnsupportedEncoding = errors.New("unsupported audio encoding")
+
+// DecodeAudio returns a chunk as 16-bit PCM, decoding Opus with the session's decoder
+func (p *PeerConnection) DecodeAudio(chunk protocol.AudioChunkData) (protocol.AudioChunkData, error) {
+	switch chunk.Encoding {
+	case "", protocol.AudioEncodingPCM16:
+		return chunk, nil
+	case protocol.AudioEncodingOpus:
+	default:
+		return chunk, fmt.Errorf("%w: %q", ErrUnsupportedEncoding, chunk.Encoding)
+	}
+
+	if chunk.SampleRate != audiocodec.SampleRate || chunk.Channels != audiocodec.Channels {
+		return chunk, fmt.Errorf("%w: opus audio must be %dHz mono, got %dHz, %d channels",
+			ErrUnsupportedEncoding, audiocodec.SampleRate, chunk.SampleRate, chunk.Channels)
+	}
+
+	p.decoderMu.Lock()
+	defer p.decoderMu.Unlock()
+
+	if p.opusDecoder == nil {
+		decoder, err := audiocodec.NewOpusDecoder()
+		if err != nil {
+			return chunk, fmt.Errorf("%w: %v", ErrUnsupportedEncoding, err)
+		}
+		p.opusDecoder = decoder
+	}
+
+	pcm, err := p.opusDecoder.DecodeChunk(chunk.Data)
+	if err != nil {
+		return chunk, err
+	}
+	chunk.Data = pcm
+	chunk.Encoding = protocol.AudioEncodingPCM16
+	return chunk, nil
+}
+
+// resetAudioDecoder discards the decoder state of the previous session
+func (p *PeerConnection) resetAudioDecoder() {
+	p.decoderMu.Lock()
+	p.opusDecoder = nil
+	p.decoderMu.Unlock()
+}
+
 // GatherICECandidates sets up ICE candidate gathering
 func (p *PeerConnection) GatherICECandidates(onCandidate func(string)) {
 	p.pc.OnICECandidate(func(candidate *webrtc.ICECandidate) {
@@ -0,0 +1,560 @@
+package webrtc
+
+import (
+	"errors"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/ggerganov/whisper.cpp/bindings/go/pkg/whisper"
+	"github.com/lucianHymer/streaming-transcription/server/internal/transcription"
+	"github.com/lucianHymer/streaming-transcription/shared/audiocodec"
+	"github.com/lucianHymer/streaming-transcription/shared/logger"
+	"github.com/lucianHymer/streaming-transcription/shared/protocol"
+)
+
+// fakeModel reports model capabilities without loading weights
+type fakeModel struct {
+	multilingual bool
+}
+
+func (f fakeModel) Close() error                         { return nil }
+func (f fakeModel) NewContext() (whisper.Context, error) { return nil, nil }
+func (f fakeModel) IsMultilingual() bool                 { return f.multilingual }
+func (f fakeModel) Languages() []string                  { return []string{"en", "de", "fr"} }
+
+func newTestManager(t *testing.T, multilingual bool, limits transcription.WhisperLimits) *Manager {
+	t.Helper()
+	log := logger.New(false)
+	model := transcription.NewSharedWhisperModel([]whisper.Model{fakeModel{multilingual: multilingual}}, "test", transcription.SchedulerConfig{Workers: 1}, log)
+	t.Cleanup(func() { model.Close() })
+
+	return New(log, nil, ManagerConfig{
+		SharedWhisperModel: model,
+		WhisperConfig: transcription.WhisperConfig{
+			Language:    "en",
+			Threads:     4,
+			Temperature: 0,
+			Logger:      log,
+		},
+		WhisperLimits: limits,
+	})
+}
+
+func TestWhisperConfigForSession(t *testing.T) {
+	limits := transcription.WhisperLimits{
+		MaxThreads:       8,
+		MaxTemperature:   0.5,
+		MaxPromptChars:   100,
+		AllowedLanguages: []string{"en", "de"},
+	}
+
+	tests := []struct {
+		name         string
+		multilingual bool
+		limits       transcription.WhisperLimits
+		settings     protocol.ControlStartData
+		wantErr      string
+		check        func(t *testing.T, config transcription.WhisperConfig)
+	}{
+		{
+			name:         "empty settings keep server defaults",
+			multilingual: true,
+			limits:       limits,
+			check: func(t *testing.T, config transcription.WhisperConfig) {
+				if config.Language != "en" || config.Threads != 4 || config.InitialPrompt != "" {
+					t.Errorf("Expected server defaults, got %+v", config)
+				}
+			},
+		},
+		{
+			name:         "settings within limits are applied",
+			multilingual: true,
+			limits:       limits,
+			settings: protocol.ControlStartData{
+				Language:      "de",
+				Translate:     true,
+				InitialPrompt: "Meeting notes.",
+				Vocabulary:    []string{"Kubernetes", "Pion"},
+				Threads:       8,
+				Temperature:   0.5,
+			},
+			check: func(t *testing.T, config transcription.WhisperConfig) {
+				if config.Language != "de" || !config.Translate || config.Threads != 8 || config.Temperature != 0.5 {
+					t.Errorf("Expected client settings, got %+v", config)
+				}
+				if config.InitialPrompt != "Meeting notes. Kubernetes, Pion." {
+					t.Errorf("Unexpected prompt %q", config.InitialPrompt)
+				}
+			},
+		},
+		{
+			name:         "auto language is always allowed",
+			multilingual: true,
+			limits:       limits,
+			settings:     protocol.ControlStartData{Language: "auto"},
+			check: func(t *testing.T, config transcription.WhisperConfig) {
+				if config.Language != "auto" {
+					t.Errorf("Expected auto, got %q", config.Language)
+				}
+			},
+		},
+		{
+			name:         "language outside the allow-list",
+			multilingual: true,
+			limits:       limits,
+			settings:     protocol.ControlStartData{Language: "fr"},
+			wantErr:      "not allowed",
+		},
+		{
+			name:         "language unknown to the model",
+			multilingual: true,
+			limits:       transcription.WhisperLimits{},
+			settings:     protocol.ControlStartData{Language: "xx"},
+			wantErr:      "not supported",
+		},
+		{
+			name:     "translation needs a multilingual model",
+			limits:   limits,
+			settings: protocol.ControlStartData{Translate: true},
+			wantErr:  "multilingual",
+		},
+		{
+			name:     "threads above the limit",
+			limits:   limits,
+			settings: protocol.ControlStartData{Threads: 9},
+			wantErr:  "exceeds server limit",
+		},
+		{
+			name:     "negative threads",
+			limits:   limits,
+			settings: protocol.ControlStartData{Threads: -1},
+			wantErr:  "negative",
+		},
+		{
+			name:     "temperature above the limit",
+			limits:   limits,
+			settings: protocol.ControlStartData{Temperature: 0.8},
+			wantErr:  "exceeds server limit",
+		},
+		{
+			name:     "prompt above the limit",
+			limits:   limits,
+			settings: protocol.ControlStartData{InitialPrompt: strings.Repeat("a", 101)},
+			wantErr:  "characters",
+		},
+		{
+			name:     "vocabulary counts towards the prompt limit",
+			limits:   limits,
+			settings: protocol.ControlStartData{Vocabulary: []string{"Kubernetes"}},
+			wantErr:  "characters",
+		},
+		{
+			name:     "zero thread limit forbids threads",
+			limits:   transcription.WhisperLimits{MaxTemperature: 1, MaxPromptChars: 100},
+			settings: protocol.ControlStartData{Threads: 1},
+			wantErr:  "exceeds server limit 0",
+		},
+		{
+			name:     "zero temperature limit forbids temperature",
+			limits:   transcription.WhisperLimits{MaxThreads: 8, MaxPromptChars: 100},
+			settings: protocol.ControlStartData{Temperature: 0.1},
+			wantErr:  "exceeds server limit",
+		},
+		{
+			name:     "zero prompt limit forbids prompts",
+			limits:   transcription.WhisperLimits{MaxThreads: 8, MaxTemperature: 1},
+			settings: protocol.ControlStartData{InitialPrompt: "Hi."},
+			wantErr:  "max 0",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			m := newTestManager(t, tt.multilingual, tt.limits)
+			config, err := m.whisperConfigForSession(&tt.settings)
+
+			if tt.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+					t.Fatalf("Expected error containing %q, got %v", tt.wantErr, err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("Unexpected error: %v", err)
+			}
+			tt.check(t, config)
+		})
+	}
+}
+
+func TestVADSettingsForSession(t *testing.T) {
+	defaults := transcription.VADSettings{
+		EnergyThreshold:        500,
+		SilenceThreshold:       time.Second,
+		MinChunkDuration:       500 * time.Millisecond,
+		MaxChunkDuration:       30 * time.Second,
+		SpeechDensityThreshold: 0.6,
+	}
+	limits := transcription.VADLimits{
+		Min: transcription.VADSettings{SilenceThreshold: 200 * time.Millisecond, MaxChunkDuration: time.Second},
+		Max: transcription.VADSettings{MaxChunkDuration: 30 * time.Second, SpeechDensityThreshold: 1},
+	}
+
+	tests := []struct {
+		name         string
+		reject       bool
+		settings     protocol.ControlStartData
+		wantErr      string
+		wantAdjusted []string
+		check        func(t *testing.T, vad transcription.VADSettings)
+	}{
+		{
+			name:     "unset settings use server defaults",
+			settings: protocol.ControlStartData{},
+			check: func(t *testing.T, vad transcription.VADSettings) {
+				if vad != defaults {
+					t.Errorf("Expected server defaults, got %+v", vad)
+				}
+			},
+		},
+		{
+			name:     "settings within limits are kept",
+			settings: protocol.ControlStartData{VADEnergyThreshold: 80, SilenceThresholdMs: 600, MaxChunkDurationMs: 10000},
+			check: func(t *testing.T, vad transcription.VADSettings) {
+				if vad.EnergyThreshold != 80 || vad.SilenceThreshold != 600*time.Millisecond || vad.MaxChunkDuration != 10*time.Second {
+					t.Errorf("Expected client settings, got %+v", vad)
+				}
+			},
+		},
+		{
+			name:         "out-of-range settings are clamped",
+			settings:     protocol.ControlStartData{SilenceThresholdMs: 50, MaxChunkDurationMs: 1800000, SpeechDensityThreshold: 2},
+			wantAdjusted: []string{"silence_threshold_ms", "max_chunk_duration_ms", "speech_density_threshold"},
+			check: func(t *testing.T, vad transcription.VADSettings) {
+				if vad.SilenceThreshold != 200*time.Millisecond || vad.MaxChunkDuration != 30*time.Second || vad.SpeechDensityThreshold != 1 {
+					t.Errorf("Expected settings clamped to the limits, got %+v", vad)
+				}
+			},
+		},
+		{
+			name:         "min chunk is clamped to max chunk",
+			settings:     protocol.ControlStartData{MinChunkDurationMs: 5000, MaxChunkDurationMs: 2000},
+			wantAdjusted: []string{"min_chunk_duration_ms"},
+			check: func(t *testing.T, vad transcription.VADSettings) {
+				if vad.MinChunkDuration != 2*time.Second {
+					t.Errorf("Expected min chunk of 2s, got %v", vad.MinChunkDuration)
+				}
+			},
+		},
+		{
+			name:     "out-of-range settings are rejected",
+			reject:   true,
+			settings: protocol.ControlStartData{MaxChunkDurationMs: 1800000},
+			wantErr:  "max_chunk_duration_ms 1800000 is outside the server range [1000, 30000]",
+		},
+		{
+			name:     "inverted chunk durations are rejected",
+			reject:   true,
+			settings: protocol.ControlStartData{MinChunkDurationMs: 5000, MaxChunkDurationMs: 2000},
+			wantErr:  "exceeds max_chunk_duration_ms",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			m := newTestManager(t, false, transcription.WhisperLimits{})
+			m.vadDefaults = defaults
+			m.vadLimits = limits
+			m.vadLimits.Reject = tt.reject
+			vad, adjusted, err := m.vadSettingsForSession(&tt.settings)
+
+			if tt.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+					t.Fatalf("Expected error containing %q, got %v", tt.wantErr, err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("Unexpected error: %v", err)
+			}
+			if strings.Join(adjusted, ",") != strings.Join(tt.wantAdjusted, ",") {
+				t.Errorf("Expected adjusted %v, got %v", tt.wantAdjusted, adjusted)
+			}
+			tt.check(t, vad)
+		})
+	}
+}
+
+func TestDecodeAudio(t *testing.T) {
+	peer := &PeerConnection{}
+
+	pcm := protocol.AudioChunkData{SampleRate: 16000, Channels: 1, Data: []byte{0x01, 0x02}}
+	decoded, err := peer.DecodeAudio(pcm)
+	if err != nil || string(decoded.Data) != string(pcm.Data) {
+		t.Errorf("Expected PCM to pass through unchanged, got %v, %v", decoded.Data, err)
+	}
+
+	pcm.Encoding = protocol.AudioEncodingPCM16
+	if _, err := peer.DecodeAudio(pcm); err != nil {
+		t.Errorf("Expected explicit pcm16 to be accepted, got %v", err)
+	}
+
+	if _, err := peer.DecodeAudio(protocol.AudioChunkData{Encoding: "mp3"}); !errors.Is(err, ErrUnsupportedEncoding) {
+		t.Errorf("Expected ErrUnsupportedEncoding for mp3, got %v", err)
+	}
+
+	_, err = peer.DecodeAudio(protocol.AudioChunkData{Encoding: protocol.AudioEncodingOpus})
+	if !audiocodec.Available && !errors.Is(err, ErrUnsupportedEncoding) {
+		t.Errorf("Expected ErrUnsupportedEncoding for Opus without the opus build tag, got %v", err)
+	}
+}
+
+func TestWebSocketPeer(t *testing.T) {
+	manager := newTestManager(t, false, transcription.WhisperLimits{})
+
+	var sent [][]byte
+	var received []protocol.MessageType
+	var audio []protocol.AudioChunkData
+	peer, err := manager.CreateWebSocketPeer("ws-peer", func(data []byte) error {
+		sent = append(sent, data)
+		return nil
+	}, func(msg *protocol.Message) {
+		received = append(received, msg.Type)
+	}, func(chunk protocol.AudioChunkData, timestamp int64) {
+		audio = append(audio, chunk)
+	})
+	if err != nil {
+		t.Fatalf("CreateWebSocketPeer failed: %v", err)
+	}
+	if _, err := manager.CreateWebSocketPeer("ws-peer", nil, nil, nil); err == nil {
+		t.Error("Expected an error for a duplicate peer ID")
+	}
+
+	if err := peer.SendMessage(&protocol.Message{Type: protocol.MessageTypeControlPong}); err != nil {
+		t.Fatalf("SendMessage failed: %v", err)
+	}
+	if len(sent) != 1 || !strings.Contains(string(sent[0]), string(protocol.MessageTypeControlPong)) {
+		t.Errorf("Expected the pong to be written to the socket, got %q", sent)
+	}
+
+	peer.Receive([]byte(`{"type":"control.ping"}`))
+	peer.Receive(protocol.EncodeAudioFrame(protocol.AudioChunkData{SampleRate: 16000, Channels: 1, Data: []byte{0x01, 0x02}, SequenceID: 7}, 0))
+	if len(received) != 1 || received[0] != protocol.MessageTypeControlPing {
+		t.Errorf("Expected one ping message, got %v", received)
+	}
+	if len(audio) != 1 || audio[0].SequenceID != 7 {
+		t.Errorf("Expected one audio chunk with sequence ID 7, got %+v", audio)
+	}
+
+	manager.RemovePeerConnection("ws-peer")
+	if _, exists := manager.GetPeerConnection("ws-peer"); exists {
+		t.Error("Expected the peer to be removed")
+	}
+}
+
+func TestResumeSession(t *testing.T) {
+	manager := newTestManager(t, false, transcription.WhisperLimits{})
+	manager.resumeGrace = time.Minute
+
+	first, err := manager.CreateWebSocketPeer("first", nil, nil, nil)
+	if err != nil {
+		t.Fatalf("CreateWebSocketPeer failed: %v", err)
+	}
+	first.SetUser("alice")
+	pipeline, _, err := manager.CreatePipelineForPeer("first", &protocol.ControlStartData{})
+	if err != nil {
+		t.Fatalf("CreatePipelineForPeer failed: %v", err)
+	}
+	token := first.ResumeToken()
+
+	manager.RemovePeerConnection("first")
+	select {
+	case <-first.Done():
+	default:
+		t.Error("Expected the removed peer to be done")
+	}
+
+	// Another user cannot take over the session with its token
+	intruder, _ := manager.CreateWebSocketPeer("intruder", nil, nil, nil)
+	intruder.SetUser("mallory")
+	if resumed := manager.ResumeSession("intruder", token); resumed != nil {
+		t.Error("Expected another user's token not to resume the session")
+	}
+	manager.RemovePeerConnection(intruder.ID)
+
+	second, _ := manager.CreateWebSocketPeer("second", nil, nil, nil)
+	second.SetUser("alice")
+	if resumed := manager.ResumeSession("second", "unknown"); resumed != nil {
+		t.Error("Expected an unknown token not to resume a session")
+	}
+	if second.ResumeToken() == token {
+		t.Error("Expected a new peer to get its own token")
+	}
+
+	if resumed := manager.ResumeSession("second", token); resumed != pipeline {
+		t.Fatal("Expected the detached pipeline to be resumed")
+	}
+	if manager.GetPeerPipeline("second") != pipeline || second.ResumeToken() != token {
+		t.Error("Expected the resumed pipeline and token to move to the new peer")
+	}
+	if resumed := manager.ResumeSession("second", token); resumed != nil {
+		t.Error("Expected a session to be resumed only once")
+	}
+
+	// Without a grace period the pipeline is closed with the peer
+	manager.resumeGrace = 0
+	manager.RemovePeerConnection("second")
+	third, _ := manager.CreateWebSocketPeer("third", nil, nil, nil)
+	if resumed := manager.ResumeSession("third", token); resumed != nil {
+		t.Error("Expected no session to resume without a grace period")
+	}
+	manager.RemovePeerConnection(third.ID)
+}
+
+func TestDetachedSessionExpires(t *testing.T) {
+	manager := newTestManager(t, false, transcription.WhisperLimits{})
+	manager.resumeGrace = 10 * time.Millisecond
+
+	peer, _ := manager.CreateWebSocketPeer("peer", nil, nil, nil)
+	if _, _, err := manager.CreatePipelineForPeer("peer", &protocol.ControlStartData{}); err != nil {
+		t.Fatalf("CreatePipelineForPeer failed: %v", err)
+	}
+	manager.RemovePeerConnection("peer")
+
+	time.Sleep(50 * time.Millisecond)
+	next, _ := manager.CreateWebSocketPeer("next", nil, nil, nil)
+	if resumed := manager.ResumeSession(next.ID, peer.ResumeToken()); resumed != nil {
+		t.Error("Expected an expired session not to resume")
+	}
+}
+
+func TestWelcome(t *testing.T) {
+	manager := newTestManager(t, true, transcription.WhisperLimits{
+		MaxThreads:       8,
+		MaxTemperature:   0.5,
+		MaxPromptChars:   500,
+		AllowedLanguages: []string{"en", "fr"},
+	})
+
+	welcome := manager.Welcome()
+	if welcome.Model != "test" || !welcome.Multilingual {
+		t.Errorf("Unexpected model in welcome: %q multilingual=%v", welcome.Model, welcome.Multilingual)
+	}
+	if len(welcome.Languages) != 2 || welcome.Languages[0] != "en" || welcome.Languages[1] != "fr" {
+		t.Errorf("Expected only the allowed languages, got %v", welcome.Languages)
+	}
+	if welcome.Limits.MaxThreads != 8 || welcome.Limits.MaxTemperature != 0.5 || welcome.Limits.MaxPromptChars != 500 {
+		t.Errorf("Unexpected limits: %+v", welcome.Limits)
+	}
+	if protocol.HasFeature(welcome.Features, protocol.FeatureSessionResume) {
+		t.Error("Session resume announced with resume disabled")
+	}
+}
+
+func TestClientFeature(t *testing.T) {
+	manager := newTestManager(t, false, transcription.WhisperLimits{})
+	peer, err := manager.CreateWebSocketPeer("hello-peer", func([]byte) error { return nil }, nil, nil)
+	if err != nil {
+		t.Fatalf("CreateWebSocketPeer failed: %v", err)
+	}
+
+	if !peer.ClientFeature(protocol.FeaturePartials, true) || peer.ClientFeature(protocol.FeatureAudioStats, false) {
+		t.Error("Expected legacy behaviour before the handshake")
+	}
+
+	peer.SetHello(&protocol.HelloData{ProtocolVersion: protocol.ProtocolVersion, Features: []string{protocol.FeatureAudioStats}})
+	if peer.ClientFeature(protocol.FeaturePartials, true) {
+		t.Error("Partials enabled although the client did not announce them")
+	}
+	if !peer.ClientFeature(protocol.FeatureAudioStats, false) {
+		t.Error("Audio stats disabled although the client announced them")
+	}
+}
+
+func TestPeersAndDisconnect(t *testing.T) {
+	manager := newTestManager(t, false, transcription.WhisperLimits{})
+	manager.resumeGrace = time.Minute
+
+	first, _ := manager.CreateWebSocketPeer("first", func([]byte) error { return nil }, nil, nil)
+	first.SetRemoteAddr("192.0.2.1:5000")
+	first.RecordAudio(640)
+	first.RecordAudio(640)
+	time.Sleep(time.Millisecond)
+	manager.CreateWebSocketPeer("second", func([]byte) error { return nil }, nil, nil)
+	if _, _, err := manager.CreatePipelineForPeer("second", &protocol.ControlStartData{}); err != nil {
+		t.Fatalf("CreatePipelineForPeer failed: %v", err)
+	}
+
+	peers := manager.Peers()
+	if len(peers) != 2 || peers[0].ID != "first" || peers[1].ID != "second" {
+		t.Fatalf("Expected both peers oldest first, got %+v", peers)
+	}
+	if peers[0].Kind != "websocket" || peers[0].RemoteAddr != "192.0.2.1:5000" || peers[0].AudioBytes != 1280 || peers[0].AudioChunks != 2 {
+		t.Errorf("Unexpected peer info: %+v", peers[0])
+	}
+	if peers[0].Pipeline != nil || peers[0].Settings != nil {
+		t.Error("Expected no session before control.start")
+	}
+
+	second, ok := manager.Peer("second")
+	if !ok || second.Pipeline == nil || second.Settings == nil || second.SessionID != second.Settings.SessionID {
+		t.Errorf("Expected the session of the second peer, got %+v", second)
+	}
+	if _, ok := manager.Peer("unknown"); ok {
+		t.Error("Expected an unknown peer not to be found")
+	}
+
+	if manager.StopPeerSession("first") {
+		t.Error("Expected stopping a peer without a session to fail")
+	}
+
+	// A disconnected session is closed rather than detached for resumption
+	token := manager.peerConns["second"].ResumeToken()
+	if !manager.DisconnectPeer("second") {
+		t.Fatal("Expected the peer to be disconnected")
+	}
+	if manager.DisconnectPeer("second") {
+		t.Error("Expected a second disconnect to fail")
+	}
+	next, _ := manager.CreateWebSocketPeer("next", nil, nil, nil)
+	if resumed := manager.ResumeSession(next.ID, token); resumed != nil {
+		t.Error("Expected a disconnected session not to resume")
+	}
+	if stats := manager.Stats(); stats.Peers != 2 || stats.Detached != 0 {
+		t.Errorf("Unexpected manager stats: %+v", stats)
+	}
+}
+
+func TestNewSessionRetiresPreviousPipeline(t *testing.T) {
+	manager := newTestManager(t, false, transcription.WhisperLimits{})
+	manager.CreateWebSocketPeer("peer", func([]byte) error { return nil }, nil, nil)
+
+	first, _, err := manager.CreatePipelineForPeer("peer", &protocol.ControlStartData{SessionID: 1})
+	if err != nil {
+		t.Fatalf("CreatePipelineForPeer failed: %v", err)
+	}
+	first.Start()
+	second, _, err := manager.CreatePipelineForPeer("peer", &protocol.ControlStartData{SessionID: 2})
+	if err != nil {
+		t.Fatalf("CreatePipelineForPeer failed: %v", err)
+	}
+	if manager.GetPeerPipeline("peer") != second {
+		t.Fatal("Expected the new pipeline to replace the previous one")
+	}
+
+	// With nothing in flight the previous pipeline is closed right away
+	select {
+	case _, ok := <-first.Results():
+		if ok {
+			t.Error("Expected no results from the previous pipeline")
+		}
+	case <-time.After(time.Second):
+		t.Error("Expected the previous pipeline to be closed once drained")
+	}
+
+	manager.RemovePeerConnection("peer")
+	if _, _, err := manager.CreatePipelineForPeer("peer", &protocol.ControlStartData{}); err == nil {
+		t.Error("Expected an error for a removed peer")
+	}
+}
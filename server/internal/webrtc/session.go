@@ -0,0 +1,132 @@
+package webrtc
+
+import (
+	"sort"
+	"time"
+
+	"github.com/lucianHymer/streaming-transcription/server/internal/transcription"
+	"github.com/lucianHymer/streaming-transcription/shared/protocol"
+)
+
+// PeerInfo is a snapshot of a peer and its session, for the admin API
+type PeerInfo struct {
+	ID             string
+	User           string // Authenticated user ("anonymous" without authentication)
+	Kind           string // "webrtc" or "websocket"
+	RemoteAddr     string
+	ConnectedAt    time.Time
+	AudioTransport string                       // protocol.TransportDataChannel or protocol.TransportTrack
+	Hello          *protocol.HelloData          // Client's handshake (nil if it skipped it)
+	Settings       *protocol.ControlStartedData // Effective settings of the current session (nil before control.start)
+	AudioBytes     uint64                       // Audio payload bytes received (as sent, before decoding)
+	AudioChunks    uint64
+	SessionID      uint64                       // 0 without a session
+	Pipeline       *transcription.PipelineStats // nil without a session
+}
+
+// SetRemoteAddr records the client's address
+func (p *PeerConnection) SetRemoteAddr(addr string) {
+	p.remoteAddr.Store(&addr)
+}
+
+// SetUser records the user the client authenticated as
+func (p *PeerConnection) SetUser(user string) {
+	p.user.Store(&user)
+}
+
+// User returns the user the client authenticated as (empty if not set)
+func (p *PeerConnection) User() string {
+	if user := p.user.Load(); user != nil {
+		return *user
+	}
+	return ""
+}
+
+// RecordAudio counts an audio chunk received from the client
+func (p *PeerConnection) RecordAudio(bytes int) {
+	p.audioBytes.Add(uint64(bytes))
+	p.audioChunks.Add(1)
+}
+
+// info takes a snapshot of the peer (caller holds peerConnsMu)
+func (p *PeerConnection) info() PeerInfo {
+	info := PeerInfo{
+		ID:             p.ID,
+		Kind:           "webrtc",
+		ConnectedAt:    p.connectedAt,
+		AudioTransport: protocol.TransportDataChannel,
+		Hello:          p.hello.Load(),
+		Settings:       p.settings.Load(),
+		AudioBytes:     p.audioBytes.Load(),
+		AudioChunks:    p.audioChunks.Load(),
+	}
+	if p.send != nil {
+		info.Kind = "websocket"
+	}
+	if addr := p.remoteAddr.Load(); addr != nil {
+		info.RemoteAddr = *addr
+	}
+	info.User = p.User()
+	if p.trackTransport.Load() {
+		info.AudioTransport = protocol.TransportTrack
+	}
+	if p.pipeline != nil {
+		stats := p.pipeline.GetStats()
+		info.SessionID = p.pipeline.SessionID()
+		info.Pipeline = &stats
+	}
+	return info
+}
+
+// Peers returns a snapshot of all connected peers, oldest first
+func (m *Manager) Peers() []PeerInfo {
+	m.peerConnsMu.RLock()
+	defer m.peerConnsMu.RUnlock()
+
+	peers := make([]PeerInfo, 0, len(m.peerConns))
+	for _, peer := range m.peerConns {
+		peers = append(peers, peer.info())
+	}
+	sort.Slice(peers, func(i, j int) bool {
+		return peers[i].ConnectedAt.Before(peers[j].ConnectedAt)
+	})
+	return peers
+}
+
+// Peer returns a snapshot of one peer
+func (m *Manager) Peer(id string) (PeerInfo, bool) {
+	m.peerConnsMu.RLock()
+	defer m.peerConnsMu.RUnlock()
+
+	peer, exists := m.peerConns[id]
+	if !exists {
+		return PeerInfo{}, false
+	}
+	return peer.info(), true
+}
+
+// StopPeerSession stops the transcription of a peer's current session, like control.stop
+// Returns false if the peer does not exist or has no active session
+func (m *Manager) StopPeerSession(id string) bool {
+	pipeline := m.GetPeerPipeline(id)
+	if pipeline == nil || !pipeline.IsActive() {
+		return false
+	}
+	if err := pipeline.Stop(); err != nil {
+		m.logger.Error("Failed to stop pipeline of peer %s: %v", id, err)
+		return false
+	}
+	m.logger.Info("Stopped session %d of peer %s", pipeline.SessionID(), id)
+	return true
+}
+
+// DisconnectPeer removes a peer and closes its session, which cannot be resumed
+// The connection handler closes the client's connection once the peer is done
+// Returns false if the peer does not exist
+func (m *Manager) DisconnectPeer(id string) bool {
+	if !m.removePeer(id, false) {
+		return false
+	}
+	m.logger.Info("Disconnected peer %s", id)
+	return true
+}
@@ -0,0 +1,161 @@
+package webrtc
+
+import (
+	"time"
+
+	"github.com/lucianHymer/streaming-transcription/shared/audiocodec"
+	"github.com/lucianHymer/streaming-transcription/shared/protocol"
+	"github.com/pion/rtp"
+	"github.com/pion/webrtc/v4"
+)
+
+const (
+	// jitterDepth is the number of packets held to reorder late arrivals (60ms of 20ms frames)
+	jitterDepth = 3
+	// jitterIdleFlush releases held packets once the track goes quiet (e.g. recording stopped)
+	jitterIdleFlush = 100 * time.Millisecond
+)
+
+// jitterBuffer reorders the RTP packets of an audio track
+// Packets are released in sequence order; a packet still missing once jitterDepth
+// later ones have arrived is declared lost and released as nil
+type jitterBuffer struct {
+	depth   int
+	held    map[uint16]*rtp.Packet
+	next    uint16 // Sequence number of the next packet to release
+	started bool
+
+	received uint64
+	lost     uint64
+	late     uint64 // Duplicates and packets arriving after their slot was released
+}
+
+// newJitterBuffer creates a jitter buffer holding up to depth packets
+func newJitterBuffer(depth int) *jitterBuffer {
+	return &jitterBuffer{
+		depth: depth,
+		held:  make(map[uint16]*rtp.Packet),
+	}
+}
+
+// Push adds a packet and returns the packets now ready for playout, nil for lost ones
+func (j *jitterBuffer) Push(packet *rtp.Packet) []*rtp.Packet {
+	seq := packet.SequenceNumber
+	if !j.started {
+		j.next = seq
+		j.started = true
+	}
+
+	// Sequence numbers wrap around, so compare by signed distance
+	if int16(seq-j.next) < 0 || j.held[seq] != nil {
+		j.late++
+		return nil
+	}
+	j.held[seq] = packet
+	j.received++
+
+	var ready []*rtp.Packet
+	for {
+		if p, ok := j.held[j.next]; ok {
+			delete(j.held, j.next)
+			ready = append(ready, p)
+		} else if len(j.held) > j.depth {
+			ready = append(ready, nil)
+			j.lost++
+		} else {
+			return ready
+		}
+		j.next++
+	}
+}
+
+// Flush releases all held packets in order, nil for the gaps between them
+func (j *jitterBuffer) Flush() []*rtp.Packet {
+	var ready []*rtp.Packet
+	for len(j.held) > 0 {
+		if p, ok := j.held[j.next]; ok {
+			delete(j.held, j.next)
+			ready = append(ready, p)
+		} else {
+			ready = append(ready, nil)
+			j.lost++
+		}
+		j.next++
+	}
+	return ready
+}
+
+// readTrack depacketizes and decodes an Opus audio track, passing each 20ms frame to onAudio
+func (p *PeerConnection) readTrack(track *webrtc.TrackRemote) {
+	decoder, err := audiocodec.NewOpusDecoder()
+	if err != nil {
+		p.logger.Error("Cannot decode audio track from peer %s: %v", p.ID, err)
+		return
+	}
+
+	// Read in the background so held packets can be flushed while the track is quiet
+	packets := make(chan *rtp.Packet, 50)
+	go func() {
+		defer close(packets)
+		for {
+			packet, _, err := track.ReadRTP()
+			if err != nil {
+				p.logger.Debug("Audio track from peer %s ended: %v", p.ID, err)
+				return
+			}
+			packets <- packet
+		}
+	}()
+
+	jitter := newJitterBuffer(jitterDepth)
+
+	// Frames are numbered per track for logging only: the jitter buffer already
+	// handled their order and losses, so the pipeline does not check them
+	var sequenceID uint64
+	play := func(ready []*rtp.Packet) {
+		for _, packet := range ready {
+			var pcm []byte
+			var err error
+			if packet == nil {
+				pcm, err = decoder.Conceal()
+			} else if len(packet.Payload) > 0 {
+				pcm, err = decoder.DecodePacket(packet.Payload)
+			}
+			if err != nil {
+				p.logger.Warn("Failed to decode audio track packet from peer %s: %v", p.ID, err)
+				continue
+			}
+			if len(pcm) == 0 || p.onAudio == nil {
+				continue
+			}
+
+			p.onAudio(protocol.AudioChunkData{
+				SampleRate: audiocodec.SampleRate,
+				Channels:   audiocodec.Channels,
+				Data:       pcm,
+				SequenceID: sequenceID,
+			}, time.Now().UnixMilli())
+			sequenceID++
+		}
+	}
+
+	idle := time.NewTimer(jitterIdleFlush)
+	defer idle.Stop()
+	for {
+		select {
+		case packet, ok := <-packets:
+			if !ok {
+				play(jitter.Flush())
+				p.logger.Info("Audio track from peer %s closed: %d packets, %d lost, %d late",
+					p.ID, jitter.received, jitter.lost, jitter.late)
+				return
+			}
+			play(jitter.Push(packet))
+			idle.Reset(jitterIdleFlush)
+
+		case <-idle.C:
+			play(jitter.Flush())
+			idle.Reset(jitterIdleFlush)
+		}
+	}
+}
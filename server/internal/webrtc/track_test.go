@@ -0,0 +1,100 @@
+package webrtc
+
+import (
+	"testing"
+
+	"github.com/pion/rtp"
+)
+
+// packet returns an RTP packet with the given sequence number
+func packet(seq uint16) *rtp.Packet {
+	return &rtp.Packet{Header: rtp.Header{SequenceNumber: seq}, Payload: []byte{byte(seq)}}
+}
+
+// sequence returns the sequence numbers of released packets, -1 for lost ones
+func sequence(packets []*rtp.Packet) []int {
+	seqs := make([]int, len(packets))
+	for i, p := range packets {
+		seqs[i] = -1
+		if p != nil {
+			seqs[i] = int(p.SequenceNumber)
+		}
+	}
+	return seqs
+}
+
+func expectSequence(t *testing.T, got []*rtp.Packet, want ...int) {
+	t.Helper()
+	seqs := sequence(got)
+	if len(seqs) != len(want) {
+		t.Fatalf("Expected %v, got %v", want, seqs)
+	}
+	for i := range want {
+		if seqs[i] != want[i] {
+			t.Fatalf("Expected %v, got %v", want, seqs)
+		}
+	}
+}
+
+func TestJitterBufferReordersPackets(t *testing.T) {
+	j := newJitterBuffer(3)
+
+	expectSequence(t, j.Push(packet(10)), 10)
+	expectSequence(t, j.Push(packet(12)))
+	expectSequence(t, j.Push(packet(13)))
+	expectSequence(t, j.Push(packet(11)), 11, 12, 13)
+
+	if j.lost != 0 || j.late != 0 {
+		t.Errorf("Expected no loss, got %d lost, %d late", j.lost, j.late)
+	}
+}
+
+func TestJitterBufferDeclaresLossAfterDepth(t *testing.T) {
+	j := newJitterBuffer(2)
+
+	expectSequence(t, j.Push(packet(1)), 1)
+	expectSequence(t, j.Push(packet(3)))
+	expectSequence(t, j.Push(packet(4)))
+	// A third packet past the gap gives up on packet 2
+	expectSequence(t, j.Push(packet(5)), -1, 3, 4, 5)
+
+	// Packet 2 arriving now is too late
+	expectSequence(t, j.Push(packet(2)))
+	if j.lost != 1 || j.late != 1 {
+		t.Errorf("Expected 1 lost and 1 late, got %d lost, %d late", j.lost, j.late)
+	}
+}
+
+func TestJitterBufferDropsDuplicates(t *testing.T) {
+	j := newJitterBuffer(3)
+
+	j.Push(packet(1))
+	expectSequence(t, j.Push(packet(1)))
+	j.Push(packet(3))
+	expectSequence(t, j.Push(packet(3)))
+
+	if j.late != 2 || j.received != 2 {
+		t.Errorf("Expected 2 received and 2 late, got %d received, %d late", j.received, j.late)
+	}
+}
+
+func TestJitterBufferHandlesWraparound(t *testing.T) {
+	j := newJitterBuffer(3)
+
+	expectSequence(t, j.Push(packet(65534)), 65534)
+	expectSequence(t, j.Push(packet(0)))
+	expectSequence(t, j.Push(packet(65535)), 65535, 0)
+	expectSequence(t, j.Push(packet(1)), 1)
+}
+
+func TestJitterBufferFlushFillsGaps(t *testing.T) {
+	j := newJitterBuffer(3)
+
+	j.Push(packet(1))
+	j.Push(packet(3))
+	j.Push(packet(5))
+	expectSequence(t, j.Flush(), -1, 3, -1, 5)
+
+	// Playout continues after the flushed packets
+	expectSequence(t, j.Push(packet(6)), 6)
+}
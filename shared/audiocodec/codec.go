@@ -0,0 +1,77 @@
+// Package audiocodec compresses audio chunks for upload to the server
+//
+// Opus support needs libopus and is compiled in with the opus build tag.
+// Without it, Available is false and the constructors return ErrOpusUnavailable.
+package audiocodec
+
+import (
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"time"
+)
+
+const (
+	// SampleRate is the rate of the PCM audio passed to and returned from the codec
+	SampleRate = 16000
+	// Channels is the channel count of the PCM audio (mono)
+	Channels = 1
+	// FrameSize is the number of samples per Opus frame (20ms at 16kHz)
+	FrameSize = SampleRate * 20 / 1000
+	// FrameDuration is the duration of one Opus frame
+	FrameDuration = 20 * time.Millisecond
+	// DefaultBitrate is the Opus bitrate used when none is configured (bits per second)
+	DefaultBitrate = 24000
+
+	// maxPacketSize bounds a single encoded Opus packet
+	maxPacketSize = 4000
+)
+
+// ErrOpusUnavailable is returned when the binary was built without the opus build tag
+var ErrOpusUnavailable = errors.New("opus support not compiled in (build with -tags opus)")
+
+// ErrInvalidPayload is returned for Opus chunk payloads that cannot be split into packets
+var ErrInvalidPayload = errors.New("invalid opus payload")
+
+// appendPacket appends an encoded packet to a chunk payload, prefixed with its length
+func appendPacket(payload, packet []byte) []byte {
+	payload = binary.BigEndian.AppendUint16(payload, uint16(len(packet)))
+	return append(payload, packet...)
+}
+
+// splitPackets splits a chunk payload into its length-prefixed packets
+func splitPackets(payload []byte) ([][]byte, error) {
+	var packets [][]byte
+	for len(payload) > 0 {
+		if len(payload) < 2 {
+			return nil, fmt.Errorf("%w: truncated packet length", ErrInvalidPayload)
+		}
+		size := int(binary.BigEndian.Uint16(payload))
+		payload = payload[2:]
+		if size == 0 || size > len(payload) {
+			return nil, fmt.Errorf("%w: packet of %d bytes with %d remaining", ErrInvalidPayload, size, len(payload))
+		}
+		packets = append(packets, payload[:size])
+		payload = payload[size:]
+	}
+	return packets, nil
+}
+
+// bytesToSamples converts 16-bit little-endian PCM to samples
+func bytesToSamples(pcm []byte) []int16 {
+	samples := make([]int16, len(pcm)/2)
+	for i := range samples {
+		samples[i] = int16(pcm[i*2]) | int16(pcm[i*2+1])<<8
+	}
+	return samples
+}
+
+// samplesToBytes converts samples to 16-bit little-endian PCM
+func samplesToBytes(samples []int16) []byte {
+	pcm := make([]byte, len(samples)*2)
+	for i, s := range samples {
+		pcm[i*2] = byte(s)
+		pcm[i*2+1] = byte(s >> 8)
+	}
+	return pcm
+}
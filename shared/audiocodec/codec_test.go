@@ -0,0 +1,58 @@
+package audiocodec
+
+import (
+	"bytes"
+	"errors"
+	"testing"
+)
+
+func TestPacketFramingRoundTrip(t *testing.T) {
+	packets := [][]byte{{0x01}, bytes.Repeat([]byte{0xab}, 300), {0x02, 0x03}}
+
+	var payload []byte
+	for _, packet := range packets {
+		payload = appendPacket(payload, packet)
+	}
+
+	split, err := splitPackets(payload)
+	if err != nil {
+		t.Fatalf("Split failed: %v", err)
+	}
+	if len(split) != len(packets) {
+		t.Fatalf("Expected %d packets, got %d", len(packets), len(split))
+	}
+	for i := range packets {
+		if !bytes.Equal(split[i], packets[i]) {
+			t.Errorf("Packet %d: expected %v, got %v", i, packets[i], split[i])
+		}
+	}
+}
+
+func TestSplitPacketsRejectsTruncatedPayloads(t *testing.T) {
+	payload := appendPacket(nil, []byte{0x01, 0x02, 0x03})
+
+	for _, bad := range [][]byte{payload[:1], payload[:4], {0x00, 0x00}} {
+		if _, err := splitPackets(bad); !errors.Is(err, ErrInvalidPayload) {
+			t.Errorf("Expected ErrInvalidPayload for %v, got %v", bad, err)
+		}
+	}
+
+	if packets, err := splitPackets(nil); err != nil || len(packets) != 0 {
+		t.Errorf("Expected no packets for an empty payload, got %v, %v", packets, err)
+	}
+}
+
+func TestSampleConversionRoundTrip(t *testing.T) {
+	samples := []int16{0, 1, -1, 32767, -32768, 12345}
+	pcm := samplesToBytes(samples)
+	if !bytes.Equal(pcm[:4], []byte{0x00, 0x00, 0x01, 0x00}) {
+		t.Errorf("Expected little-endian samples, got %v", pcm[:4])
+	}
+
+	back := bytesToSamples(pcm)
+	for i := range samples {
+		if back[i] != samples[i] {
+			t.Errorf("Sample %d: expected %d, got %d", i, samples[i], back[i])
+		}
+	}
+}
@@ -0,0 +1,136 @@
+//go:build opus
+// +build opus
+
+package audiocodec
+
+import (
+	"fmt"
+
+	"gopkg.in/hraban/opus.v2"
+)
+
+// Available reports whether Opus support is compiled in
+const Available = true
+
+// OpusEncoder encodes 16kHz mono PCM chunks into Opus packets
+// It is stateful: chunks of one stream must go through the same encoder in order
+type OpusEncoder struct {
+	encoder *opus.Encoder
+	bitrate int
+	pending []int16 // Samples not yet filling a whole frame
+}
+
+// NewOpusEncoder creates an encoder tuned for speech at the given bitrate (0 = DefaultBitrate)
+func NewOpusEncoder(bitrate int) (*OpusEncoder, error) {
+	if bitrate <= 0 {
+		bitrate = DefaultBitrate
+	}
+	e := &OpusEncoder{bitrate: bitrate}
+	if err := e.Reset(); err != nil {
+		return nil, err
+	}
+	return e, nil
+}
+
+// Reset discards the encoder state and pending samples, for the start of a new stream
+func (e *OpusEncoder) Reset() error {
+	encoder, err := opus.NewEncoder(SampleRate, Channels, opus.AppVoIP)
+	if err != nil {
+		return fmt.Errorf("failed to create opus encoder: %w", err)
+	}
+	if err := encoder.SetBitrate(e.bitrate); err != nil {
+		return fmt.Errorf("failed to set opus bitrate %d: %w", e.bitrate, err)
+	}
+	e.encoder = encoder
+	e.pending = nil
+	return nil
+}
+
+// EncodeChunk encodes 16-bit little-endian PCM into a chunk payload of length-prefixed packets
+// Samples that do not fill a whole frame are kept and encoded with the next chunk
+func (e *OpusEncoder) EncodeChunk(pcm []byte) ([]byte, error) {
+	packets, err := e.EncodeFrames(pcm)
+	if err != nil {
+		return nil, err
+	}
+
+	var payload []byte
+	for _, packet := range packets {
+		payload = appendPacket(payload, packet)
+	}
+	return payload, nil
+}
+
+// EncodeFrames encodes 16-bit little-endian PCM into one Opus packet per 20ms frame
+// Samples that do not fill a whole frame are kept and encoded with the next call
+func (e *OpusEncoder) EncodeFrames(pcm []byte) ([][]byte, error) {
+	e.pending = append(e.pending, bytesToSamples(pcm)...)
+
+	var packets [][]byte
+	buf := make([]byte, maxPacketSize)
+	for len(e.pending) >= FrameSize {
+		n, err := e.encoder.Encode(e.pending[:FrameSize], buf)
+		if err != nil {
+			return nil, fmt.Errorf("opus encode failed: %w", err)
+		}
+		packets = append(packets, append([]byte(nil), buf[:n]...))
+		e.pending = e.pending[FrameSize:]
+	}
+
+	// Keep the remainder in its own slice so the backing array does not grow forever
+	e.pending = append([]int16(nil), e.pending...)
+	return packets, nil
+}
+
+// OpusDecoder decodes Opus chunk payloads back to 16kHz mono PCM
+// It is stateful: chunks of one stream must go through the same decoder in order
+type OpusDecoder struct {
+	decoder *opus.Decoder
+}
+
+// NewOpusDecoder creates a decoder for one stream
+func NewOpusDecoder() (*OpusDecoder, error) {
+	decoder, err := opus.NewDecoder(SampleRate, Channels)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create opus decoder: %w", err)
+	}
+	return &OpusDecoder{decoder: decoder}, nil
+}
+
+// DecodeChunk decodes a chunk payload into 16-bit little-endian PCM
+func (d *OpusDecoder) DecodeChunk(payload []byte) ([]byte, error) {
+	packets, err := splitPackets(payload)
+	if err != nil {
+		return nil, err
+	}
+
+	var pcm []byte
+	for _, packet := range packets {
+		decoded, err := d.DecodePacket(packet)
+		if err != nil {
+			return nil, err
+		}
+		pcm = append(pcm, decoded...)
+	}
+	return pcm, nil
+}
+
+// DecodePacket decodes a single Opus packet into 16-bit little-endian PCM
+func (d *OpusDecoder) DecodePacket(packet []byte) ([]byte, error) {
+	// A packet holds at most 120ms of audio
+	frame := make([]int16, SampleRate*120/1000)
+	n, err := d.decoder.Decode(packet, frame)
+	if err != nil {
+		return nil, fmt.Errorf("opus decode failed: %w", err)
+	}
+	return samplesToBytes(frame[:n]), nil
+}
+
+// Conceal returns 16-bit little-endian PCM standing in for one lost 20ms frame
+func (d *OpusDecoder) Conceal() ([]byte, error) {
+	frame := make([]int16, FrameSize)
+	if err := d.decoder.DecodePLC(frame); err != nil {
+		return nil, fmt.Errorf("opus packet loss concealment failed: %w", err)
+	}
+	return samplesToBytes(frame), nil
+}
@@ -0,0 +1,58 @@
+//go:build !opus
+// +build !opus
+
+package audiocodec
+
+// This file is used when building WITHOUT the opus build tag
+// Opus is unavailable and the constructors fail with ErrOpusUnavailable
+
+// Available reports whether Opus support is compiled in
+const Available = false
+
+// OpusEncoder encodes 16kHz mono PCM chunks into Opus packets
+// THIS IS THE UNAVAILABLE VERSION (construction always fails)
+type OpusEncoder struct{}
+
+// NewOpusEncoder always fails without the opus build tag
+func NewOpusEncoder(bitrate int) (*OpusEncoder, error) {
+	return nil, ErrOpusUnavailable
+}
+
+// Reset always fails without the opus build tag
+func (e *OpusEncoder) Reset() error {
+	return ErrOpusUnavailable
+}
+
+// EncodeChunk always fails without the opus build tag
+func (e *OpusEncoder) EncodeChunk(pcm []byte) ([]byte, error) {
+	return nil, ErrOpusUnavailable
+}
+
+// EncodeFrames always fails without the opus build tag
+func (e *OpusEncoder) EncodeFrames(pcm []byte) ([][]byte, error) {
+	return nil, ErrOpusUnavailable
+}
+
+// OpusDecoder decodes Opus chunk payloads back to 16kHz mono PCM
+// THIS IS THE UNAVAILABLE VERSION (construction always fails)
+type OpusDecoder struct{}
+
+// NewOpusDecoder always fails without the opus build tag
+func NewOpusDecoder() (*OpusDecoder, error) {
+	return nil, ErrOpusUnavailable
+}
+
+// DecodeChunk always fails without the opus build tag
+func (d *OpusDecoder) DecodeChunk(payload []byte) ([]byte, error) {
+	return nil, ErrOpusUnavailable
+}
+
+// DecodePacket always fails without the opus build tag
+func (d *OpusDecoder) DecodePacket(packet []byte) ([]byte, error) {
+	return nil, ErrOpusUnavailable
+}
+
+// Conceal always fails without the opus build tag
+func (d *OpusDecoder) Conceal() ([]byte, error) {
+	return nil, ErrOpusUnavailable
+}
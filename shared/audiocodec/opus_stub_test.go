@@ -0,0 +1,21 @@
+//go:build !opus
+// +build !opus
+
+package audiocodec
+
+import (
+	"errors"
+	"testing"
+)
+
+func TestOpusUnavailableWithoutBuildTag(t *testing.T) {
+	if Available {
+		t.Error("Expected Available to be false without the opus build tag")
+	}
+	if _, err := NewOpusEncoder(0); !errors.Is(err, ErrOpusUnavailable) {
+		t.Errorf("Expected ErrOpusUnavailable from NewOpusEncoder, got %v", err)
+	}
+	if _, err := NewOpusDecoder(); !errors.Is(err, ErrOpusUnavailable) {
+		t.Errorf("Expected ErrOpusUnavailable from NewOpusDecoder, got %v", err)
+	}
+}
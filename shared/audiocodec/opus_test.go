@@ -0,0 +1,188 @@
+//go:build opus
+// +build opus
+
+package audiocodec
+
+import (
+	"math"
+	"testing"
+)
+
+// sine returns n samples of a tone at the given frequency and amplitude, starting at sample offset
+func sine(n, offset int, freq, amplitude float64) []int16 {
+	samples := make([]int16, n)
+	for i := range samples {
+		samples[i] = int16(amplitude * math.Sin(2*math.Pi*freq*float64(offset+i)/SampleRate))
+	}
+	return samples
+}
+
+// rms returns the root mean square of samples
+func rms(samples []int16) float64 {
+	var sum float64
+	for _, s := range samples {
+		sum += float64(s) * float64(s)
+	}
+	return math.Sqrt(sum / float64(len(samples)))
+}
+
+// tonePower returns the power of samples at freq (Goertzel algorithm)
+func tonePower(samples []int16, freq float64) float64 {
+	coeff := 2 * math.Cos(2*math.Pi*freq/SampleRate)
+	var s1, s2 float64
+	for _, s := range samples {
+		s0 := float64(s) + coeff*s1 - s2
+		s2, s1 = s1, s0
+	}
+	return s1*s1 + s2*s2 - coeff*s1*s2
+}
+
+// roundTrip encodes samples in chunks of chunkSize samples and decodes them again
+func roundTrip(t *testing.T, samples []int16, chunkSize int) (decoded []int16, encodedBytes int) {
+	t.Helper()
+	encoder, err := NewOpusEncoder(0)
+	if err != nil {
+		t.Fatalf("NewOpusEncoder failed: %v", err)
+	}
+	decoder, err := NewOpusDecoder()
+	if err != nil {
+		t.Fatalf("NewOpusDecoder failed: %v", err)
+	}
+
+	for start := 0; start < len(samples); start += chunkSize {
+		end := min(start+chunkSize, len(samples))
+		payload, err := encoder.EncodeChunk(samplesToBytes(samples[start:end]))
+		if err != nil {
+			t.Fatalf("EncodeChunk failed: %v", err)
+		}
+		encodedBytes += len(payload)
+
+		pcm, err := decoder.DecodeChunk(payload)
+		if err != nil {
+			t.Fatalf("DecodeChunk failed: %v", err)
+		}
+		decoded = append(decoded, bytesToSamples(pcm)...)
+	}
+	return decoded, encodedBytes
+}
+
+func TestOpusRoundTripPreservesSpeechBand(t *testing.T) {
+	// Two seconds of a 440Hz tone in the client's 200ms chunks
+	input := sine(2*SampleRate, 0, 440, 8000)
+	decoded, encodedBytes := roundTrip(t, input, SampleRate/5)
+
+	if len(decoded) != len(input) {
+		t.Fatalf("Expected %d decoded samples, got %d", len(input), len(decoded))
+	}
+
+	// Skip the first 100ms while the codec settles
+	settled := decoded[SampleRate/10:]
+	inLevel, outLevel := rms(input[SampleRate/10:]), rms(settled)
+	if ratio := 20 * math.Log10(outLevel/inLevel); math.Abs(ratio) > 3 {
+		t.Errorf("Expected level within 3dB of the input, got %.1fdB (%.0f vs %.0f RMS)", ratio, outLevel, inLevel)
+	}
+
+	// The tone must dominate: anything else is codec noise
+	signal, noise := tonePower(settled, 440), tonePower(settled, 1000)
+	if db := 10 * math.Log10(signal/noise); db < 30 {
+		t.Errorf("Expected the 440Hz tone 30dB above 1kHz, got %.1fdB", db)
+	}
+
+	if ratio := float64(len(input)*2) / float64(encodedBytes); ratio < 5 {
+		t.Errorf("Expected at least 5x compression, got %.1fx (%d bytes)", ratio, encodedBytes)
+	}
+}
+
+func TestOpusRoundTripKeepsSilenceSilent(t *testing.T) {
+	decoded, _ := roundTrip(t, make([]int16, SampleRate), SampleRate/5)
+	if level := rms(decoded); level > 10 {
+		t.Errorf("Expected silence to stay silent, got %.1f RMS", level)
+	}
+}
+
+func TestOpusEncoderCarriesPartialFrames(t *testing.T) {
+	encoder, err := NewOpusEncoder(0)
+	if err != nil {
+		t.Fatalf("NewOpusEncoder failed: %v", err)
+	}
+
+	// 2.5 frames, then 1.5 frames: the half frame goes out with the second chunk
+	first, err := encoder.EncodeChunk(samplesToBytes(sine(FrameSize*5/2, 0, 440, 8000)))
+	if err != nil {
+		t.Fatalf("EncodeChunk failed: %v", err)
+	}
+	second, err := encoder.EncodeChunk(samplesToBytes(sine(FrameSize*3/2, FrameSize*5/2, 440, 8000)))
+	if err != nil {
+		t.Fatalf("EncodeChunk failed: %v", err)
+	}
+
+	for i, payload := range [][]byte{first, second} {
+		packets, err := splitPackets(payload)
+		if err != nil {
+			t.Fatalf("splitPackets failed: %v", err)
+		}
+		if len(packets) != 2 {
+			t.Errorf("Chunk %d: expected 2 packets, got %d", i, len(packets))
+		}
+	}
+
+	// Reset drops the pending half frame
+	if _, err := encoder.EncodeChunk(samplesToBytes(make([]int16, FrameSize/2))); err != nil {
+		t.Fatalf("EncodeChunk failed: %v", err)
+	}
+	if err := encoder.Reset(); err != nil {
+		t.Fatalf("Reset failed: %v", err)
+	}
+	payload, err := encoder.EncodeChunk(samplesToBytes(make([]int16, FrameSize/2)))
+	if err != nil {
+		t.Fatalf("EncodeChunk failed: %v", err)
+	}
+	if len(payload) != 0 {
+		t.Errorf("Expected no packets after Reset, got %d bytes", len(payload))
+	}
+}
+
+func TestOpusDecoderRejectsGarbage(t *testing.T) {
+	decoder, err := NewOpusDecoder()
+	if err != nil {
+		t.Fatalf("NewOpusDecoder failed: %v", err)
+	}
+	if _, err := decoder.DecodeChunk([]byte{0x00, 0x05, 0x01}); err == nil {
+		t.Error("Expected an error for a truncated payload")
+	}
+}
+
+func TestOpusFramesAndConcealment(t *testing.T) {
+	encoder, err := NewOpusEncoder(0)
+	if err != nil {
+		t.Fatalf("NewOpusEncoder failed: %v", err)
+	}
+	decoder, err := NewOpusDecoder()
+	if err != nil {
+		t.Fatalf("NewOpusDecoder failed: %v", err)
+	}
+
+	packets, err := encoder.EncodeFrames(samplesToBytes(sine(FrameSize*3, 0, 440, 8000)))
+	if err != nil {
+		t.Fatalf("EncodeFrames failed: %v", err)
+	}
+	if len(packets) != 3 {
+		t.Fatalf("Expected 3 packets, got %d", len(packets))
+	}
+
+	// The second packet is lost in transit
+	for i, packet := range packets {
+		var pcm []byte
+		if i == 1 {
+			pcm, err = decoder.Conceal()
+		} else {
+			pcm, err = decoder.DecodePacket(packet)
+		}
+		if err != nil {
+			t.Fatalf("Frame %d: %v", i, err)
+		}
+		if len(pcm) != FrameSize*2 {
+			t.Errorf("Frame %d: expected %d bytes, got %d", i, FrameSize*2, len(pcm))
+		}
+	}
+}
@@ -1,3 +1,5 @@
 module github.com/lucianHymer/streaming-transcription/shared
 
 go 1.23
+
+require gopkg.in/hraban/opus.v2 v2.0.0-20230925203106-0188a62cb302
@@ -0,0 +1,92 @@
+package protocol
+
+import (
+	"encoding/binary"
+	"errors"
+	"fmt"
+)
+
+// Binary audio frames carry audio chunks over the DataChannel without the JSON and
+// base64 overhead of MessageTypeAudioChunk. Control and transcript messages stay JSON.
+//
+// Layout (integers big-endian):
+//
+//	offset  size  field
+//	0       1     frame type (AudioFrameTypePCM16 or AudioFrameTypeOpus)
+//	1       8     sequence ID
+//	9       8     timestamp (Unix milliseconds)
+//	17      4     sample rate (Hz)
+//	21      1     channels
+//	22      ...   payload (PCM: 16-bit little-endian samples, interleaved; Opus: length-prefixed packets)
+//
+// JSON messages always start with '{', so a receiver tells the two apart by the first byte.
+
+// AudioFrameType identifies the payload of a binary audio frame
+type AudioFrameType byte
+
+const (
+	AudioFrameTypePCM16 AudioFrameType = 0x01 // Raw 16-bit PCM
+	AudioFrameTypeOpus  AudioFrameType = 0x02 // Opus packets (see AudioEncodingOpus)
+)
+
+// AudioFrameHeaderSize is the size of the binary audio frame header in bytes
+const AudioFrameHeaderSize = 22
+
+// FeatureBinaryAudio is announced in the signaling answer by servers that accept binary audio frames
+const FeatureBinaryAudio = "binary_audio"
+
+// FeatureOpusAudio is announced in the signaling answer by servers that decode Opus audio
+const FeatureOpusAudio = "opus_audio"
+
+// ErrInvalidAudioFrame is returned for frames that cannot be decoded
+var ErrInvalidAudioFrame = errors.New("invalid audio frame")
+
+// IsAudioFrame returns true if a DataChannel message is a binary audio frame
+func IsAudioFrame(data []byte) bool {
+	if len(data) == 0 {
+		return false
+	}
+	frameType := AudioFrameType(data[0])
+	return frameType == AudioFrameTypePCM16 || frameType == AudioFrameTypeOpus
+}
+
+// EncodeAudioFrame builds a binary audio frame for a chunk
+func EncodeAudioFrame(chunk AudioChunkData, timestamp int64) []byte {
+	frame := make([]byte, AudioFrameHeaderSize+len(chunk.Data))
+	frame[0] = byte(AudioFrameTypePCM16)
+	if chunk.Encoding == AudioEncodingOpus {
+		frame[0] = byte(AudioFrameTypeOpus)
+	}
+	binary.BigEndian.PutUint64(frame[1:9], chunk.SequenceID)
+	binary.BigEndian.PutUint64(frame[9:17], uint64(timestamp))
+	binary.BigEndian.PutUint32(frame[17:21], uint32(chunk.SampleRate))
+	frame[21] = byte(chunk.Channels)
+	copy(frame[AudioFrameHeaderSize:], chunk.Data)
+	return frame
+}
+
+// DecodeAudioFrame parses a binary audio frame, returning the chunk and its timestamp
+// The chunk's Data aliases the frame, so callers must not modify the frame afterwards
+func DecodeAudioFrame(frame []byte) (AudioChunkData, int64, error) {
+	if len(frame) < AudioFrameHeaderSize {
+		return AudioChunkData{}, 0, fmt.Errorf("%w: %d bytes is shorter than the header", ErrInvalidAudioFrame, len(frame))
+	}
+	encoding := AudioEncodingPCM16
+	switch AudioFrameType(frame[0]) {
+	case AudioFrameTypePCM16:
+	case AudioFrameTypeOpus:
+		encoding = AudioEncodingOpus
+	default:
+		return AudioChunkData{}, 0, fmt.Errorf("%w: unknown frame type 0x%02x", ErrInvalidAudioFrame, frame[0])
+	}
+
+	chunk := AudioChunkData{
+		SequenceID: binary.BigEndian.Uint64(frame[1:9]),
+		SampleRate: int(binary.BigEndian.Uint32(frame[17:21])),
+		Channels:   int(frame[21]),
+		Data:       frame[AudioFrameHeaderSize:],
+		Encoding:   encoding,
+	}
+	timestamp := int64(binary.BigEndian.Uint64(frame[9:17]))
+	return chunk, timestamp, nil
+}
@@ -0,0 +1,81 @@
+package protocol
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"testing"
+)
+
+func TestAudioFrameRoundTrip(t *testing.T) {
+	chunk := AudioChunkData{
+		SampleRate: 16000,
+		Channels:   1,
+		Data:       []byte{0x01, 0x02, 0x03, 0x04},
+		SequenceID: 42,
+	}
+
+	frame := EncodeAudioFrame(chunk, 1700000000123)
+	if len(frame) != AudioFrameHeaderSize+len(chunk.Data) {
+		t.Fatalf("Expected %d bytes, got %d", AudioFrameHeaderSize+len(chunk.Data), len(frame))
+	}
+	if !IsAudioFrame(frame) {
+		t.Fatal("Expected frame to be recognised as audio")
+	}
+
+	decoded, timestamp, err := DecodeAudioFrame(frame)
+	if err != nil {
+		t.Fatalf("Decode failed: %v", err)
+	}
+	if timestamp != 1700000000123 {
+		t.Errorf("Expected timestamp 1700000000123, got %d", timestamp)
+	}
+	if decoded.SequenceID != 42 || decoded.SampleRate != 16000 || decoded.Channels != 1 {
+		t.Errorf("Unexpected header fields: %+v", decoded)
+	}
+	if !bytes.Equal(decoded.Data, chunk.Data) {
+		t.Errorf("Expected payload %v, got %v", chunk.Data, decoded.Data)
+	}
+}
+
+func TestAudioFrameCarriesEncoding(t *testing.T) {
+	frame := EncodeAudioFrame(AudioChunkData{SampleRate: 16000, Channels: 1, Data: []byte{0x00, 0x03}, Encoding: AudioEncodingOpus}, 0)
+	if AudioFrameType(frame[0]) != AudioFrameTypeOpus {
+		t.Fatalf("Expected Opus frame type, got 0x%02x", frame[0])
+	}
+	if !IsAudioFrame(frame) {
+		t.Fatal("Expected Opus frame to be recognised as audio")
+	}
+
+	decoded, _, err := DecodeAudioFrame(frame)
+	if err != nil {
+		t.Fatalf("Decode failed: %v", err)
+	}
+	if decoded.Encoding != AudioEncodingOpus {
+		t.Errorf("Expected encoding %q, got %q", AudioEncodingOpus, decoded.Encoding)
+	}
+
+	decoded, _, _ = DecodeAudioFrame(EncodeAudioFrame(AudioChunkData{SampleRate: 16000, Channels: 1}, 0))
+	if decoded.Encoding != AudioEncodingPCM16 {
+		t.Errorf("Expected encoding %q, got %q", AudioEncodingPCM16, decoded.Encoding)
+	}
+}
+
+func TestAudioFrameRejectsInvalidFrames(t *testing.T) {
+	if _, _, err := DecodeAudioFrame([]byte{byte(AudioFrameTypePCM16), 0, 0}); !errors.Is(err, ErrInvalidAudioFrame) {
+		t.Errorf("Expected ErrInvalidAudioFrame for a short frame, got %v", err)
+	}
+
+	frame := EncodeAudioFrame(AudioChunkData{SampleRate: 16000, Channels: 1}, 0)
+	frame[0] = 0x7f
+	if _, _, err := DecodeAudioFrame(frame); !errors.Is(err, ErrInvalidAudioFrame) {
+		t.Errorf("Expected ErrInvalidAudioFrame for an unknown type, got %v", err)
+	}
+}
+
+func TestJSONMessagesAreNotAudioFrames(t *testing.T) {
+	data, _ := json.Marshal(Message{Type: MessageTypeControlPing})
+	if IsAudioFrame(data) {
+		t.Error("JSON message mistaken for an audio frame")
+	}
+}
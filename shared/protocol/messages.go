@@ -12,12 +12,19 @@ const (
 	MessageTypeControlPing  MessageType = "control.ping"
 	MessageTypeControlPong  MessageType = "control.pong"
 
+	// Handshake (sent once the connection opens, if the server announces FeatureHello)
+	MessageTypeControlHello   MessageType = "control.hello"   // Client's protocol version and capabilities
+	MessageTypeControlWelcome MessageType = "control.welcome" // Server's version, model, limits and features
+	MessageTypeControlStarted MessageType = "control.started" // Server's effective settings for the new session
+
 	// Audio data
 	MessageTypeAudioChunk MessageType = "audio.chunk"
+	MessageTypeAudioStats MessageType = "audio.stats" // Server reports gaps and duplicates in the session's chunks
 
 	// Transcription results
 	MessageTypeTranscriptPartial MessageType = "transcript.partial"
 	MessageTypeTranscriptFinal   MessageType = "transcript.final"
+	MessageTypeTranscriptResend  MessageType = "transcript.resend" // Client asks for missed finals again
 
 	// Errors
 	MessageTypeError MessageType = "error"
@@ -32,38 +39,202 @@ type Message struct {
 
 // ControlStartData contains transcription settings sent by client
 type ControlStartData struct {
+	// VAD Settings (optional - zero values use server defaults, server limits apply)
+	VADEnergyThreshold     float64 `json:"vad_energy_threshold"`
+	SilenceThresholdMs     int     `json:"silence_threshold_ms"`
+	MinChunkDurationMs     int     `json:"min_chunk_duration_ms"`
+	MaxChunkDurationMs     int     `json:"max_chunk_duration_ms"`
+	SpeechDensityThreshold float64 `json:"speech_density_threshold"`
+
+	// Whisper Settings (optional - empty values use server defaults, server limits apply)
+	Language      string   `json:"language,omitempty"`       // ISO code (e.g. "en") or "auto" to detect
+	Translate     bool     `json:"translate,omitempty"`      // Translate speech to English
+	InitialPrompt string   `json:"initial_prompt,omitempty"` // Replaces the server's initial prompt
+	Vocabulary    []string `json:"vocabulary,omitempty"`     // Custom words appended to the prompt
+	Threads       int      `json:"threads,omitempty"`
+	Temperature   float64  `json:"temperature,omitempty"`
+
+	// Client-chosen ID echoed in this session's transcripts (0 = the server assigns one)
+	SessionID uint64 `json:"session_id,omitempty"`
+}
+
+// ControlStartedData echoes the settings a session started with, after the server
+// applied its defaults and limits to ControlStartData
+type ControlStartedData struct {
+	SessionID uint64 `json:"session_id"`
+
 	// VAD Settings
 	VADEnergyThreshold     float64 `json:"vad_energy_threshold"`
 	SilenceThresholdMs     int     `json:"silence_threshold_ms"`
 	MinChunkDurationMs     int     `json:"min_chunk_duration_ms"`
 	MaxChunkDurationMs     int     `json:"max_chunk_duration_ms"`
 	SpeechDensityThreshold float64 `json:"speech_density_threshold"`
+
+	// Whisper Settings
+	Language    string  `json:"language"`
+	Translate   bool    `json:"translate"`
+	Threads     int     `json:"threads"`
+	Temperature float64 `json:"temperature"`
+
+	// Settings the server clamped to its limits, by JSON field name
+	Adjusted []string `json:"adjusted,omitempty"`
+}
+
+// ProtocolVersion is the version of the message protocol spoken by this build
+// Peers use the lower of both versions; peers that skip the handshake are version 0
+const ProtocolVersion = 1
+
+// Features announced in control.hello and control.welcome
+// (FeatureBinaryAudio and FeatureOpusAudio are also announced in the signaling answer)
+const (
+	FeatureHello            = "hello"             // Server answers control.hello (signaling answer / upgrade response only)
+	FeaturePartials         = "partials"          // Server sends transcript.partial / client shows them
+	FeatureTimestamps       = "timestamps"        // Transcripts carry segment and token timings
+	FeatureNoiseSuppression = "noise_suppression" // Server denoises audio with RNNoise
+	FeatureSessionResume    = "session_resume"    // Sessions survive reconnects (resume_token)
+	FeatureAudioStats       = "audio_stats"       // Server reports audio gaps and duplicates / client reads audio.stats
+)
+
+// HelloData is the client's half of the handshake
+type HelloData struct {
+	ProtocolVersion int      `json:"protocol_version"`
+	AudioEncodings  []string `json:"audio_encodings"` // Encodings the client can send
+	Features        []string `json:"features,omitempty"`
+}
+
+// WelcomeData is the server's half of the handshake
+type WelcomeData struct {
+	ProtocolVersion int           `json:"protocol_version"` // Version both sides use: the lower of the two
+	Model           string        `json:"model"`
+	Multilingual    bool          `json:"multilingual"`
+	Languages       []string      `json:"languages,omitempty"` // Languages a session may request
+	AudioEncodings  []string      `json:"audio_encodings"`     // Encodings the server decodes
+	Features        []string      `json:"features,omitempty"`  // Server features, including ones the client did not announce
+	Limits          SessionLimits `json:"limits"`
+}
+
+// SessionLimits bounds the Whisper settings a client may request in control.start
+type SessionLimits struct {
+	MaxThreads     int     `json:"max_threads"`
+	MaxTemperature float64 `json:"max_temperature"`
+	MaxPromptChars int     `json:"max_prompt_chars"`
 }
 
-// AudioChunkData contains raw PCM audio data
+// HasFeature reports whether a feature list contains feature
+func HasFeature(features []string, feature string) bool {
+	for _, f := range features {
+		if f == feature {
+			return true
+		}
+	}
+	return false
+}
+
+// AudioChunkData contains raw PCM or encoded audio data
 type AudioChunkData struct {
-	SampleRate int    `json:"sample_rate"`
-	Channels   int    `json:"channels"`
-	Data       []byte `json:"data"` // Base64 encoded PCM data
+	SampleRate int    `json:"sample_rate"` // 8000-192000Hz; the server resamples to 16kHz
+	Channels   int    `json:"channels"`    // Interleaved channels; the server downmixes to mono
+	Data       []byte `json:"data"`        // Base64 encoded PCM data or Opus packets
 	SequenceID uint64 `json:"sequence_id"`
+	Encoding   string `json:"encoding,omitempty"` // AudioEncodingPCM16 (default) or AudioEncodingOpus
+}
+
+// Audio encodings of AudioChunkData
+const (
+	AudioEncodingPCM16 = "pcm16" // 16-bit little-endian PCM
+	AudioEncodingOpus  = "opus"  // Opus packets, each prefixed with its length (uint16 big-endian)
+)
+
+// AudioStatsData reports how a session's audio chunks arrived, by sequence ID
+// Sent when a chunk is missing, repeated or late, and when the session stops,
+// to clients that announce FeatureAudioStats in control.hello
+type AudioStatsData struct {
+	SessionID  uint64 `json:"session_id,omitempty"`
+	Received   uint64 `json:"received"`   // Chunks accepted
+	Missing    uint64 `json:"missing"`    // Chunks skipped (filled with silence, or the transcript chunk was cut)
+	Gaps       uint64 `json:"gaps"`       // Times the sequence skipped ahead
+	Duplicates uint64 `json:"duplicates"` // Chunks dropped as already received
+	Late       uint64 `json:"late"`       // Chunks dropped for arriving after their gap was handled
 }
 
 // TranscriptData contains transcription results
 type TranscriptData struct {
-	Text       string  `json:"text"`
-	IsFinal    bool    `json:"is_final"`
-	Confidence float64 `json:"confidence,omitempty"`
-	SequenceID uint64  `json:"sequence_id,omitempty"`
+	Text          string              `json:"text"`
+	IsFinal       bool                `json:"is_final"`
+	Confidence    float64             `json:"confidence,omitempty"`     // Mean token probability in [0, 1]
+	LowConfidence bool                `json:"low_confidence,omitempty"` // Confidence below the server threshold
+	SequenceID    uint64              `json:"sequence_id,omitempty"`    // Chunk index within the session; a final is sent for every chunk (text may be empty)
+	Redelivered   bool                `json:"redelivered,omitempty"`    // Re-sent in response to transcript.resend
+	SessionID     uint64              `json:"session_id,omitempty"`     // Session (control.start) the transcript belongs to; sequence IDs restart per session
+	Segments      []TranscriptSegment `json:"segments,omitempty"`
+}
+
+// TranscriptResendData asks the server to send finals again
+type TranscriptResendData struct {
+	SequenceIDs []uint64 `json:"sequence_ids"`
+	SessionID   uint64   `json:"session_id,omitempty"` // Session the sequence IDs belong to (0 = current session)
+}
+
+// TranscriptSegment is a timed span of transcribed text
+// Times are milliseconds of session audio since control.start
+type TranscriptSegment struct {
+	Text    string            `json:"text"`
+	StartMs int64             `json:"start_ms"`
+	EndMs   int64             `json:"end_ms"`
+	Tokens  []TranscriptToken `json:"tokens,omitempty"`
 }
 
+// TranscriptToken is a single Whisper token (word or sub-word) with timing and probability
+type TranscriptToken struct {
+	Text        string  `json:"text"`
+	StartMs     int64   `json:"start_ms"`
+	EndMs       int64   `json:"end_ms"`
+	Probability float64 `json:"probability"`
+}
+
+// Error codes sent in MessageTypeError
+const (
+	ErrorCodeInvalidMessage         = "invalid_message"          // Message could not be parsed or has an unknown type
+	ErrorCodeInvalidSettings        = "invalid_settings"         // control.start settings rejected (outside server limits, unsupported language)
+	ErrorCodePipelineFailed         = "pipeline_failed"          // Transcription pipeline could not be created, started or stopped
+	ErrorCodeNotStarted             = "not_started"              // Audio received without an active session (send control.start first)
+	ErrorCodeAudioFormatUnsupported = "audio_format_unsupported" // Audio encoding, sample rate, channel count or size not supported
+	ErrorCodeModelBusy              = "model_busy"               // Transcription queue is full; chunks listed in sequence_ids were not transcribed
+	ErrorCodeInferenceFailed        = "inference_failed"         // Whisper failed on the chunks listed in sequence_ids
+	ErrorCodeResultDropped          = "result_dropped"           // Transcripts were lost on the server (sequence_ids lists them)
+	ErrorCodeResendUnavailable      = "resend_unavailable"       // Requested transcripts are no longer held by the server
+	ErrorCodeSessionTerminated      = "session_terminated"       // An administrator stopped the session or disconnected the client
+)
+
 // ErrorData contains error information
 type ErrorData struct {
-	Code    string `json:"code"`
-	Message string `json:"message"`
+	Code        string   `json:"code"`
+	Message     string   `json:"message"`
+	SequenceIDs []uint64 `json:"sequence_ids,omitempty"` // Transcripts the error refers to
 }
 
 // SignalingMessage is used for WebRTC signaling over WebSocket
 type SignalingMessage struct {
-	Type string          `json:"type"` // "offer", "answer", "ice"
-	Data json.RawMessage `json:"data"`
+	Type      string          `json:"type"` // "offer", "answer", "ice"
+	Data      json.RawMessage `json:"data"`
+	Features  []string        `json:"features,omitempty"`  // Optional features the server supports (sent with the answer)
+	Transport string          `json:"transport,omitempty"` // Audio transport requested in the offer and chosen in the answer (empty = TransportDataChannel)
+
+	// Offer: token of the previous connection, to resume its session after a reconnect
+	// Answer: token to present on the next reconnect (unchanged if the session was resumed)
+	ResumeToken string `json:"resume_token,omitempty"`
 }
+
+// FeaturesHeader lists the server's features (comma-separated) in the upgrade
+// response of the WebSocket streaming endpoint, which has no signaling answer
+const FeaturesHeader = "X-Stream-Features"
+
+// ResumeTokenHeader carries the resume token of the WebSocket streaming endpoint:
+// in the upgrade request to resume a session, in the response for the next reconnect
+const ResumeTokenHeader = "X-Resume-Token"
+
+// Audio transports negotiated at signaling time
+const (
+	TransportDataChannel = "datachannel" // Audio chunks over the DataChannel
+	TransportTrack       = "track"       // Opus RTP media track; control and transcripts stay on the DataChannel
+)